@@ -0,0 +1,316 @@
+package main
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/IAmRadek/gorate/internal/rates"
+	"github.com/gin-gonic/gin"
+	"github.com/govalues/decimal"
+)
+
+// adminKey is the gin context key the name of the authenticated admin is stored under.
+const adminKey = "admin"
+
+// adminKeys maps the API keys of admins to their names.
+type adminKeys map[string]string
+
+// parseAdminKeys parses ADMIN_API_KEYS entries of the form "name:key".
+func parseAdminKeys(entries []string) (adminKeys, error) {
+	keys := make(adminKeys, len(entries))
+	for _, entry := range entries {
+		name, key, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		if !ok || name == "" || key == "" {
+			return nil, fmt.Errorf("admin api key %q: not of the form name:key", entry)
+		}
+		if _, ok := keys[key]; ok {
+			return nil, fmt.Errorf("admin api key of %q: used by another admin", name)
+		}
+
+		keys[key] = name
+	}
+
+	return keys, nil
+}
+
+// name returns the name of the admin with the key. Every key is compared in constant time.
+func (k adminKeys) name(key string) (string, bool) {
+	var found string
+	for candidate, name := range k {
+		if subtle.ConstantTimeCompare([]byte(candidate), []byte(key)) == 1 {
+			found = name
+		}
+	}
+
+	return found, found != ""
+}
+
+// names returns the names of the admins, sorted.
+func (k adminKeys) names() []string {
+	return slices.Sorted(maps.Values(k))
+}
+
+// RequireAdmin lets through requests with the API key of an admin in the "Authorization: Bearer" header,
+// noting the admin's name for the audit of the changes made.
+func RequireAdmin(keys adminKeys) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok {
+			c.Header("WWW-Authenticate", "Bearer")
+			c.AbortWithStatusJSON(http.StatusUnauthorized, map[string]string{
+				"error": "missing admin api key",
+			})
+			return
+		}
+
+		name, ok := keys.name(key)
+		if !ok {
+			c.Header("WWW-Authenticate", "Bearer")
+			c.AbortWithStatusJSON(http.StatusUnauthorized, map[string]string{
+				"error": "invalid admin api key",
+			})
+			return
+		}
+
+		c.Set(adminKey, name)
+		c.Next()
+	}
+}
+
+type cryptoTokenResponse struct {
+	Symbol   string `json:"symbol"`
+	Decimals int    `json:"decimals"`
+	USD      string `json:"usd"`
+}
+
+type cryptoVersionResponse struct {
+	Version      int                            `json:"version"`
+	Action       string                         `json:"action"`
+	Code         string                         `json:"code,omitempty"`
+	RolledBackTo int                            `json:"rolled_back_to,omitempty"`
+	Author       string                         `json:"author"`
+	Reason       string                         `json:"reason"`
+	At           string                         `json:"at"`
+	Tokens       map[string]cryptoTokenResponse `json:"tokens"`
+}
+
+func newCryptoVersionResponse(v rates.CryptoVersion) cryptoVersionResponse {
+	tokens := make(map[string]cryptoTokenResponse, len(v.Config.Tokens))
+	for code, token := range v.Config.Tokens {
+		symbol := token.Symbol
+		if symbol == "" {
+			symbol = code
+		}
+
+		tokens[code] = cryptoTokenResponse{Symbol: symbol, Decimals: token.Decimals, USD: token.USD.String()}
+	}
+
+	return cryptoVersionResponse{
+		Version:      v.Version,
+		Action:       string(v.Action),
+		Code:         v.Code,
+		RolledBackTo: v.RolledBackTo,
+		Author:       v.Author,
+		Reason:       v.Reason,
+		At:           v.At.Format(time.RFC3339),
+		Tokens:       tokens,
+	}
+}
+
+// cryptoChange returns the change made by the authenticated admin for the reason.
+func cryptoChange(c *gin.Context, reason string) rates.CryptoChange {
+	return rates.CryptoChange{Author: c.GetString(adminKey), Reason: reason}
+}
+
+// HandleCryptoRates returns the current version of the crypto rates.
+func HandleCryptoRates(provider *rates.MutableCryptoRatesProvider) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, newCryptoVersionResponse(provider.Current()))
+	}
+}
+
+// HandleCryptoRatesVersions returns every version of the crypto rates, oldest first.
+func HandleCryptoRatesVersions(provider *rates.MutableCryptoRatesProvider) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		versions := provider.Versions()
+
+		resp := make([]cryptoVersionResponse, 0, len(versions))
+		for _, v := range versions {
+			resp = append(resp, newCryptoVersionResponse(v))
+		}
+
+		c.JSON(http.StatusOK, resp)
+	}
+}
+
+// HandleCryptoRatesVersion returns the version of the crypto rates with the number given in the path.
+func HandleCryptoRatesVersion(provider *rates.MutableCryptoRatesProvider) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		n, err := strconv.Atoi(c.Param("version"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, map[string]string{
+				"error": fmt.Sprintf("invalid version %q", c.Param("version")),
+			})
+			return
+		}
+
+		v, ok := provider.Version(n)
+		if !ok {
+			c.JSON(http.StatusNotFound, map[string]string{
+				"error": fmt.Sprintf("version %d: %v", n, rates.ErrVersionNotFound),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, newCryptoVersionResponse(v))
+	}
+}
+
+// HandleListCryptoToken lists a new token with its decimals and USD price.
+func HandleListCryptoToken(provider *rates.MutableCryptoRatesProvider) gin.HandlerFunc {
+	type request struct {
+		Code     string          `json:"code"`
+		Symbol   string          `json:"symbol"`
+		Decimals *int            `json:"decimals"`
+		USD      decimal.Decimal `json:"usd"`
+		Reason   string          `json:"reason"`
+	}
+
+	return func(c *gin.Context) {
+		var req request
+
+		if !bindCryptoChange(c, &req) {
+			return
+		}
+
+		if req.Decimals == nil {
+			c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "decimals are required",
+			})
+			return
+		}
+
+		token := rates.CryptoToken{Symbol: req.Symbol, Decimals: *req.Decimals, USD: req.USD}
+
+		v, err := provider.List(strings.ToUpper(req.Code), token, cryptoChange(c, req.Reason))
+		if err != nil {
+			writeCryptoChangeError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusCreated, newCryptoVersionResponse(v))
+	}
+}
+
+// HandleSetCryptoPrice sets the USD price of the token with the code given in the path.
+func HandleSetCryptoPrice(provider *rates.MutableCryptoRatesProvider) gin.HandlerFunc {
+	type request struct {
+		USD    decimal.Decimal `json:"usd"`
+		Reason string          `json:"reason"`
+	}
+
+	return func(c *gin.Context) {
+		var req request
+
+		if !bindCryptoChange(c, &req) {
+			return
+		}
+
+		v, err := provider.SetPrice(strings.ToUpper(c.Param("code")), req.USD, cryptoChange(c, req.Reason))
+		if err != nil {
+			writeCryptoChangeError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, newCryptoVersionResponse(v))
+	}
+}
+
+// HandleDelistCryptoToken delists the token with the code given in the path.
+func HandleDelistCryptoToken(provider *rates.MutableCryptoRatesProvider) gin.HandlerFunc {
+	type request struct {
+		Reason string `json:"reason"`
+	}
+
+	return func(c *gin.Context) {
+		var req request
+
+		if !bindCryptoChange(c, &req) {
+			return
+		}
+
+		v, err := provider.Delist(strings.ToUpper(c.Param("code")), cryptoChange(c, req.Reason))
+		if err != nil {
+			writeCryptoChangeError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, newCryptoVersionResponse(v))
+	}
+}
+
+// HandleRollbackCryptoRates restores the tokens of an earlier version as a new version.
+func HandleRollbackCryptoRates(provider *rates.MutableCryptoRatesProvider) gin.HandlerFunc {
+	type request struct {
+		Version int    `json:"version"`
+		Reason  string `json:"reason"`
+	}
+
+	return func(c *gin.Context) {
+		var req request
+
+		if !bindCryptoChange(c, &req) {
+			return
+		}
+
+		v, err := provider.Rollback(req.Version, cryptoChange(c, req.Reason))
+		if err != nil {
+			writeCryptoChangeError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, newCryptoVersionResponse(v))
+	}
+}
+
+// bindCryptoChange parses the JSON body of a change into req, writing the error response when it fails.
+func bindCryptoChange(c *gin.Context, req any) bool {
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, map[string]string{
+			"error": fmt.Sprintf("cannot parse request: %v", err),
+		})
+		return false
+	}
+
+	return true
+}
+
+func writeCryptoChangeError(c *gin.Context, err error) {
+	status := errorStatus(err, http.StatusInternalServerError)
+	if status == http.StatusInternalServerError {
+		logError(c, "changing crypto rates failed", err)
+	}
+
+	c.JSON(status, map[string]string{
+		"error": fmt.Sprintf("changing crypto rates failed: %v", err),
+	})
+}
+
+// registerAdminRoutes registers the routes changing the crypto rates at runtime, for the admins with keys.
+func registerAdminRoutes(router *gin.Engine, keys adminKeys, crypto *rates.MutableCryptoRatesProvider) {
+	admin := router.Group("/admin", RequireAdmin(keys))
+
+	admin.GET("/crypto-rates", HandleCryptoRates(crypto))
+	admin.POST("/crypto-rates", HandleListCryptoToken(crypto))
+	admin.PUT("/crypto-rates/:code", HandleSetCryptoPrice(crypto))
+	admin.DELETE("/crypto-rates/:code", HandleDelistCryptoToken(crypto))
+	admin.GET("/crypto-rates/versions", HandleCryptoRatesVersions(crypto))
+	admin.GET("/crypto-rates/versions/:version", HandleCryptoRatesVersion(crypto))
+	admin.POST("/crypto-rates/rollback", HandleRollbackCryptoRates(crypto))
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/IAmRadek/gorate/internal/rates"
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequireAdmin(t *testing.T) {
+	router := gin.New()
+	router.GET("/admin", RequireAdmin(adminKeys{"s3cret": "alice"}), func(c *gin.Context) {
+		c.String(http.StatusOK, c.GetString(adminKey))
+	})
+
+	tests := []struct {
+		name          string
+		authorization string
+		expectedCode  int
+		expectedBody  string
+	}{
+		{name: "missing", expectedCode: http.StatusUnauthorized, expectedBody: "missing admin api key"},
+		{name: "not_bearer", authorization: "Basic s3cret", expectedCode: http.StatusUnauthorized, expectedBody: "missing admin api key"},
+		{name: "lowercase_scheme", authorization: "bearer s3cret", expectedCode: http.StatusUnauthorized, expectedBody: "missing admin api key"},
+		{name: "no_key", authorization: "Bearer", expectedCode: http.StatusUnauthorized, expectedBody: "missing admin api key"},
+		{name: "empty_key", authorization: "Bearer ", expectedCode: http.StatusUnauthorized, expectedBody: "invalid admin api key"},
+		{name: "wrong_key", authorization: "Bearer s3cre", expectedCode: http.StatusUnauthorized, expectedBody: "invalid admin api key"},
+		{name: "valid_key", authorization: "Bearer s3cret", expectedCode: http.StatusOK, expectedBody: "alice"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+			if tc.authorization != "" {
+				req.Header.Set("Authorization", tc.authorization)
+			}
+
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tc.expectedCode {
+				t.Fatalf("expected: %d status got: %d", tc.expectedCode, rec.Code)
+			}
+			if !strings.Contains(rec.Body.String(), tc.expectedBody) {
+				t.Fatalf("expected body with %q got: %s", tc.expectedBody, rec.Body)
+			}
+			if challenge := rec.Header().Get("WWW-Authenticate"); (challenge == "Bearer") != (tc.expectedCode == http.StatusUnauthorized) {
+				t.Fatalf("expected a Bearer challenge only when unauthorized got: %q", challenge)
+			}
+		})
+	}
+}
+
+func TestCryptoChangeErrors(t *testing.T) {
+	newRouter := func(t *testing.T, provider *rates.MutableCryptoRatesProvider) *gin.Engine {
+		t.Helper()
+
+		router := gin.New()
+		registerAdminRoutes(router, adminKeys{"s3cret": "alice"}, provider)
+
+		return router
+	}
+
+	do := func(t *testing.T, router *gin.Engine, method, path, body string) *httptest.ResponseRecorder {
+		t.Helper()
+
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer s3cret")
+		req.Header.Set("Content-Type", "application/json")
+
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		return rec
+	}
+
+	t.Run("mutable", func(t *testing.T) {
+		provider, err := rates.NewMutableCryptoRatesProvider(rates.FixedCryptoConfig(), rates.CryptoChange{Author: "test", Reason: "test"})
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		router := newRouter(t, provider)
+
+		tests := []struct {
+			name         string
+			method       string
+			path         string
+			body         string
+			expectedCode int
+		}{
+			{name: "unparsable", method: http.MethodPut, path: "/admin/crypto-rates/WBTC", body: `{"usd":`, expectedCode: http.StatusBadRequest},
+			{name: "no_reason", method: http.MethodPut, path: "/admin/crypto-rates/WBTC", body: `{"usd":"1"}`, expectedCode: http.StatusBadRequest},
+			{name: "negative_price", method: http.MethodPut, path: "/admin/crypto-rates/WBTC", body: `{"usd":"-1","reason":"test"}`, expectedCode: http.StatusBadRequest},
+			{name: "no_decimals", method: http.MethodPost, path: "/admin/crypto-rates", body: `{"code":"ADMTOK","usd":"1","reason":"test"}`, expectedCode: http.StatusBadRequest},
+			{name: "unknown_token", method: http.MethodPut, path: "/admin/crypto-rates/NOPE", body: `{"usd":"1","reason":"test"}`, expectedCode: http.StatusNotFound},
+			{name: "unknown_version", method: http.MethodPost, path: "/admin/crypto-rates/rollback", body: `{"version":99,"reason":"test"}`, expectedCode: http.StatusNotFound},
+			{name: "listed_again", method: http.MethodPost, path: "/admin/crypto-rates", body: `{"code":"wbtc","decimals":8,"usd":"1","reason":"test"}`, expectedCode: http.StatusConflict},
+		}
+
+		for _, tc := range tests {
+			t.Run(tc.name, func(t *testing.T) {
+				if rec := do(t, router, tc.method, tc.path, tc.body); rec.Code != tc.expectedCode {
+					t.Fatalf("expected: %d status got: %d %s", tc.expectedCode, rec.Code, rec.Body)
+				}
+			})
+		}
+
+		if v := provider.Current(); v.Version != 1 {
+			t.Fatalf("expected failed changes to keep version 1 got %d", v.Version)
+		}
+
+		rec := do(t, router, http.MethodPut, "/admin/crypto-rates/wbtc", `{"usd":"60000","reason":"test"}`)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected: %d status got: %d %s", http.StatusOK, rec.Code, rec.Body)
+		}
+
+		var body cryptoVersionResponse
+		if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+			t.Fatalf("decoding response: %v", err)
+		}
+		if body.Version != 2 || body.Author != "alice" || body.Tokens["WBTC"].USD != "60000" {
+			t.Fatalf("expected version 2 by alice pricing WBTC at 60000 got %+v", body)
+		}
+	})
+
+	t.Run("file_backed", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "crypto.json")
+		if err := os.WriteFile(path, []byte(`{"tokens": {"WBTC": {"decimals": 8, "usd": "57037.22"}}}`), 0o644); err != nil {
+			t.Fatalf("writing config: %v", err)
+		}
+
+		provider, err := rates.NewCryptoFileProvider(path)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		router := newRouter(t, provider.MutableCryptoRatesProvider)
+
+		tests := []struct {
+			name         string
+			method       string
+			path         string
+			body         string
+			expectedCode int
+		}{
+			{name: "set_price", method: http.MethodPut, path: "/admin/crypto-rates/WBTC", body: `{"usd":"1","reason":"test"}`, expectedCode: http.StatusConflict},
+			{name: "list", method: http.MethodPost, path: "/admin/crypto-rates", body: `{"code":"ADMTOK","decimals":2,"usd":"1","reason":"test"}`, expectedCode: http.StatusConflict},
+			{name: "delist", method: http.MethodDelete, path: "/admin/crypto-rates/WBTC", body: `{"reason":"test"}`, expectedCode: http.StatusConflict},
+			{name: "rollback", method: http.MethodPost, path: "/admin/crypto-rates/rollback", body: `{"version":1,"reason":"test"}`, expectedCode: http.StatusConflict},
+			// Changes without a reason are invalid before the file is even considered.
+			{name: "no_reason", method: http.MethodPut, path: "/admin/crypto-rates/WBTC", body: `{"usd":"1"}`, expectedCode: http.StatusBadRequest},
+		}
+
+		for _, tc := range tests {
+			t.Run(tc.name, func(t *testing.T) {
+				if rec := do(t, router, tc.method, tc.path, tc.body); rec.Code != tc.expectedCode {
+					t.Fatalf("expected: %d status got: %d %s", tc.expectedCode, rec.Code, rec.Body)
+				}
+			})
+		}
+	})
+
+	t.Run("unexpected", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rec)
+		c.Request = httptest.NewRequest(http.MethodPut, "/admin/crypto-rates/WBTC", nil)
+
+		writeCryptoChangeError(c, errors.New("disk full"))
+
+		if rec.Code != http.StatusInternalServerError {
+			t.Fatalf("expected: %d status got: %d", http.StatusInternalServerError, rec.Code)
+		}
+		if !strings.Contains(rec.Body.String(), "changing crypto rates failed: disk full") {
+			t.Fatalf("expected the error in the body got: %s", rec.Body)
+		}
+	})
+}
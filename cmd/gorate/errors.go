@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/IAmRadek/gorate/internal/exchanges"
+	"github.com/IAmRadek/gorate/internal/quotes"
+	"github.com/IAmRadek/gorate/internal/rates"
+	"github.com/gin-gonic/gin"
+)
+
+// errorStatus maps errors returned by rate providers, exchanges and quotes to the HTTP status reported to the client.
+// Upstream failures take precedence over unsupported currencies, which a failover chain reports for skipped providers.
+// Errors it does not recognize are reported with fallback.
+func errorStatus(err error, fallback int) int {
+	switch {
+	case errors.Is(err, rates.ErrQuotaExceeded):
+		return http.StatusTooManyRequests
+	case errors.Is(err, rates.ErrUnavailable),
+		errors.Is(err, rates.ErrNoConsensus):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout
+	case errors.Is(err, rates.ErrMissingAppID),
+		errors.Is(err, rates.ErrInvalidAppID),
+		errors.Is(err, rates.ErrNotAllowed),
+		errors.Is(err, rates.ErrInvalidBase),
+		errors.Is(err, rates.ErrRateRejected):
+		return http.StatusBadGateway
+	case errors.Is(err, rates.ErrNoHistory):
+		return http.StatusNotImplemented
+	case errors.Is(err, rates.ErrNoData),
+		errors.Is(err, rates.ErrDateOutOfRange),
+		errors.Is(err, rates.ErrTokenNotFound),
+		errors.Is(err, rates.ErrVersionNotFound),
+		errors.Is(err, quotes.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, quotes.ErrExpired):
+		return http.StatusGone
+	case errors.Is(err, quotes.ErrAlreadyExecuted),
+		errors.Is(err, rates.ErrTokenExists),
+		errors.Is(err, rates.ErrCryptoRatesManaged):
+		return http.StatusConflict
+	case errors.Is(err, rates.ErrUnsupportedCurrency),
+		errors.Is(err, rates.ErrFutureDate),
+		errors.Is(err, rates.ErrInvalidRange),
+		errors.Is(err, rates.ErrRangeTooLong),
+		errors.Is(err, rates.ErrInvalidCryptoRates),
+		errors.Is(err, exchanges.ErrAmountTooSmall):
+		return http.StatusBadRequest
+	default:
+		return fallback
+	}
+}
+
+// logError logs a failed request, including the upstream response details when there are any.
+func logError(c *gin.Context, msg string, err error) {
+	attrs := []any{"err", err, "path", c.Request.URL.Path}
+
+	var upErr *rates.UpstreamError
+	if errors.As(err, &upErr) {
+		attrs = append(attrs, "upstream", upErr)
+	}
+
+	slog.ErrorContext(c, msg, attrs...)
+}
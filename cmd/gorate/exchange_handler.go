@@ -3,24 +3,46 @@ package main
 import (
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/IAmRadek/gorate/internal/exchanges"
-	"github.com/Rhymond/go-money"
+	"github.com/IAmRadek/gorate/internal/rates"
 	"github.com/gin-gonic/gin"
 	"github.com/govalues/decimal"
 )
 
 func HandleExchange(exchange *exchanges.Exchange) gin.HandlerFunc {
 	type request struct {
-		From   string          `form:"from"`
-		To     string          `form:"to"`
-		Amount decimal.Decimal `form:"amount"`
+		From       string          `form:"from"`
+		To         string          `form:"to"`
+		Amount     decimal.Decimal `form:"amount"`
+		Receive    decimal.Decimal `form:"receive"`
+		Date       string          `form:"date"`
+		Rounding   string          `form:"rounding"`
+		Provenance bool            `form:"provenance"`
+	}
+
+	type fee struct {
+		Type   string      `json:"type"`
+		Amount jsonDecimal `json:"amount"`
 	}
 
 	type response struct {
-		From   string  `json:"from"`
-		To     string  `json:"to"`
-		Amount float64 `json:"amount"`
+		From            string      `json:"from"`
+		To              string      `json:"to"`
+		SourceAmount    jsonDecimal `json:"source_amount"`
+		Amount          jsonDecimal `json:"amount"`
+		UnroundedAmount jsonDecimal `json:"unrounded_amount"`
+		Rounding        string      `json:"rounding"`
+		Rate            jsonDecimal `json:"rate"`
+		RateSource      string      `json:"rate_source,omitempty"`
+		RateLegs        []rateLeg   `json:"rate_legs,omitempty"`
+		RateProvenance  *provenance `json:"rate_provenance,omitempty"`
+		GrossAmount     jsonDecimal `json:"gross_amount"`
+		Spread          jsonDecimal `json:"spread"`
+		Fees            []fee       `json:"fees"`
+		TotalFees       jsonDecimal `json:"total_fees"`
+		NetAmount       jsonDecimal `json:"net_amount"`
 	}
 
 	return func(c *gin.Context) {
@@ -33,39 +55,122 @@ func HandleExchange(exchange *exchanges.Exchange) gin.HandlerFunc {
 			return
 		}
 
-		from := money.GetCurrency(req.From)
+		exact, err := exactNumbers(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, map[string]string{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		rounding, err := exchanges.ParseRounding(req.Rounding)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, map[string]string{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		from := rates.GetCurrency(req.From)
 		if from == nil {
 			c.JSON(http.StatusBadRequest, nil)
 			return
 		}
 
-		to := money.GetCurrency(req.To)
+		to := rates.GetCurrency(req.To)
 		if to == nil {
 			c.JSON(http.StatusBadRequest, nil)
 			return
 		}
 
-		if req.Amount.Sign() <= 0 {
+		// With receive, the amount of from to send is computed from the amount of to to receive.
+		reverse := !req.Receive.IsZero()
+		if reverse {
+			if !req.Amount.IsZero() {
+				c.JSON(http.StatusBadRequest, map[string]string{
+					"error": "amount and receive are mutually exclusive",
+				})
+				return
+			}
+			if req.Receive.Sign() < 0 {
+				c.JSON(http.StatusBadRequest, nil)
+				return
+			}
+		} else if req.Amount.Sign() <= 0 {
 			c.JSON(http.StatusBadRequest, nil)
 			return
 		}
 
-		m, err := exchange.Exchange(c.Copy(), from, to, req.Amount)
+		var res *exchanges.Result
+
+		switch {
+		case req.Date == "" && !reverse:
+			res, err = exchange.Exchange(c.Copy(), from, to, req.Amount, exchanges.WithRounding(rounding))
+		case req.Date == "":
+			res, err = exchange.ExchangeFor(c.Copy(), from, to, req.Receive, exchanges.WithRounding(rounding))
+		default:
+			var date time.Time
+			date, err = time.Parse(time.DateOnly, req.Date)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, map[string]string{
+					"error": fmt.Sprintf("cannot parse date: %v", err),
+				})
+				return
+			}
+
+			if reverse {
+				res, err = exchange.ExchangeForAt(c.Copy(), date, from, to, req.Receive, exchanges.WithRounding(rounding))
+			} else {
+				res, err = exchange.ExchangeAt(c.Copy(), date, from, to, req.Amount, exchanges.WithRounding(rounding))
+			}
+		}
 		if err != nil {
+			logError(c, "exchange failed", err)
+
 			resp := map[string]any{
 				"error": fmt.Sprintf("exchange failed: %v", err),
 			}
 
-			c.JSON(http.StatusInternalServerError, resp)
+			c.JSON(errorStatus(err, http.StatusInternalServerError), resp)
+			return
+		}
+
+		totalFees, err := res.TotalFees()
+		if err != nil {
+			logError(c, "exchange failed", err)
+			c.JSON(http.StatusInternalServerError, map[string]any{
+				"error": fmt.Sprintf("exchange failed: %v", err),
+			})
 			return
 		}
 
+		fees := make([]fee, 0, len(res.Fees))
+		for _, f := range res.Fees {
+			fees = append(fees, fee{Type: string(f.Kind), Amount: jsonDecimal{value: f.Amount, exact: exact}})
+		}
+
+		if m, err := rates.NewMatrix(res.Rate); err == nil {
+			setProvenanceHeaders(c, m)
+		}
+
+		setNumbersContentType(c, exact)
 		c.JSON(http.StatusOK, response{
-			From:   from.Code,
-			To:     to.Code,
-			Amount: m.AsMajorUnits(),
+			From:            from.Code,
+			To:              to.Code,
+			SourceAmount:    jsonDecimal{value: res.Amount, exact: exact},
+			Amount:          jsonDecimal{value: res.Converted, exact: exact},
+			UnroundedAmount: jsonDecimal{value: res.Unrounded, exact: exact},
+			Rounding:        string(res.Rounding),
+			Rate:            jsonDecimal{value: res.Rate.SellRate(), exact: exact},
+			RateSource:      res.Rate.Source,
+			RateLegs:        newRateLegs(res.Rate.Legs, exact, false, req.Provenance),
+			RateProvenance:  newProvenance(res.Rate, exact, req.Provenance),
+			GrossAmount:     jsonDecimal{value: res.Gross, exact: exact},
+			Spread:          jsonDecimal{value: res.Spread, exact: exact},
+			Fees:            fees,
+			TotalFees:       jsonDecimal{value: totalFees, exact: exact},
+			NetAmount:       jsonDecimal{value: res.Converted, exact: exact},
 		})
-
 	}
 }
 
@@ -9,12 +9,16 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"slices"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/IAmRadek/go-kit/envconfig"
 	"github.com/IAmRadek/gorate/internal/exchanges"
+	"github.com/IAmRadek/gorate/internal/quotes"
 	"github.com/IAmRadek/gorate/internal/rates"
+	"github.com/IAmRadek/gorate/internal/snapshots"
 	"github.com/gin-gonic/gin"
 )
 
@@ -28,7 +32,47 @@ type Config struct {
 	MaxHeaderBytes           int           `env:"MAX_HEADER_BYTES" default:"1024"`
 	GracefulShutdownDuration time.Duration `env:"GRACEFUL_SHUTDOWN_DURATION" default:"5s"`
 
-	OpenExchangeRatesProviderAppID string `env:"OPEN_EXCHANGE_RATES_PROVIDER_APP_ID" required:"true"`
+	RatesProviders       []string      `env:"RATES_PROVIDERS" default:"openexchangerates"`
+	RatesProviderTimeout time.Duration `env:"RATES_PROVIDER_TIMEOUT" default:"5s"`
+	RatesPivotCurrency   string        `env:"RATES_PIVOT_CURRENCY" default:"USD"`
+	TimeSeriesMaxDays    int           `env:"TIME_SERIES_MAX_DAYS" default:"366"`
+
+	ConsensusProviders []string `env:"CONSENSUS_PROVIDERS"`
+	ConsensusMethod    string   `env:"CONSENSUS_METHOD" default:"median"`
+	ConsensusTolerance string   `env:"CONSENSUS_TOLERANCE" default:"0.02"`
+	ConsensusQuorum    int      `env:"CONSENSUS_QUORUM" default:"0"`
+	ConsensusWeights   []string `env:"CONSENSUS_WEIGHTS"`
+
+	RatesRefreshInterval   time.Duration `env:"RATES_REFRESH_INTERVAL" default:"1h"`
+	RatesRefreshIntervals  []string      `env:"RATES_REFRESH_INTERVALS"`
+	RatesRefreshBackoff    time.Duration `env:"RATES_REFRESH_BACKOFF" default:"1s"`
+	RatesRefreshMaxBackoff time.Duration `env:"RATES_REFRESH_MAX_BACKOFF" default:"5m"`
+
+	RatesGuardAction   string   `env:"RATES_GUARD_ACTION" default:"reject"`
+	RatesGuardMaxMove  string   `env:"RATES_GUARD_MAX_MOVE" default:"0.1"`
+	RatesGuardMaxMoves []string `env:"RATES_GUARD_MAX_MOVES"`
+	RatesGuardMinRate  string   `env:"RATES_GUARD_MIN_RATE" default:"0.000000000001"`
+	RatesGuardMaxRate  string   `env:"RATES_GUARD_MAX_RATE" default:"1000000000000"`
+
+	OpenExchangeRatesProviderAppID   string `env:"OPEN_EXCHANGE_RATES_PROVIDER_APP_ID" required:"true"`
+	OpenExchangeRatesProviderBaseURL string `env:"OPEN_EXCHANGE_RATES_PROVIDER_BASE_URL" default:"https://openexchangerates.org/api"`
+
+	ECBProviderURL string `env:"ECB_PROVIDER_URL" default:"https://www.ecb.europa.eu/stats/eurofxref/eurofxref-daily.xml"`
+
+	NBPProviderBaseURL string `env:"NBP_PROVIDER_BASE_URL" default:"https://api.nbp.pl/api"`
+	NBPProviderTable   string `env:"NBP_PROVIDER_TABLE" default:"A"`
+
+	CryptoRatesFile           string        `env:"CRYPTO_RATES_FILE"`
+	CryptoRatesReloadInterval time.Duration `env:"CRYPTO_RATES_RELOAD_INTERVAL" default:"10s"`
+
+	SnapshotsFile string `env:"SNAPSHOTS_FILE"`
+
+	FeesFile string `env:"FEES_FILE"`
+
+	QuoteTTL   time.Duration `env:"QUOTE_TTL" default:"30s"`
+	QuotesFile string        `env:"QUOTES_FILE"`
+
+	AdminAPIKeys []string `env:"ADMIN_API_KEYS"`
 }
 
 func main() {
@@ -45,16 +89,139 @@ func main() {
 
 	httpClient := http.DefaultClient
 
-	ratesProvider := rates.NewOpenExchangeRatesProvider(httpClient, cfg.OpenExchangeRatesProviderAppID)
+	snapshotStore, closeSnapshots, err := newSnapshotStore(cfg.SnapshotsFile)
+	if err != nil {
+		fatal("opening snapshots store: %v", err)
+	}
+	defer closeSnapshots()
+
+	recorder := snapshots.Recorder(snapshotStore)
+
+	openExchangeRates := rates.NewOpenExchangeRatesProvider(httpClient, cfg.OpenExchangeRatesProviderAppID,
+		rates.WithBaseURL(cfg.OpenExchangeRatesProviderBaseURL),
+	)
 
-	fixedCryptoRates := rates.NewFixedCryptoRatesProvider()
-	exchange := exchanges.NewExchange(fixedCryptoRates)
+	ecbRates := rates.NewECBProvider(httpClient, cfg.ECBProviderURL)
+	nbpRates := rates.NewNBPProvider(httpClient, cfg.NBPProviderBaseURL, cfg.NBPProviderTable)
+
+	cryptoRates, err := newCryptoProvider(ctx, cfg.CryptoRatesFile, cfg.CryptoRatesReloadInterval, recorder)
+	if err != nil {
+		fatal("loading crypto rates: %v", err)
+	}
+
+	var fees exchanges.FeePolicy = exchanges.NoFees{}
+	if cfg.FeesFile != "" {
+		if fees, err = exchanges.LoadFeeRules(cfg.FeesFile); err != nil {
+			fatal("loading fee rules: %v", err)
+		}
+	}
+
+	available := map[string]rates.Provider{
+		providerFixedCrypto: cryptoRates,
+	}
+
+	fiat := map[string]rates.Provider{
+		providerOpenExchangeRates: openExchangeRates,
+		providerECB:               ecbRates,
+		providerNBP:               nbpRates,
+	}
+	for name, provider := range fiat {
+		guarded, err := newGuardedProvider(name, provider, guardConfig{
+			action:   cfg.RatesGuardAction,
+			maxMove:  cfg.RatesGuardMaxMove,
+			maxMoves: cfg.RatesGuardMaxMoves,
+			minRate:  cfg.RatesGuardMinRate,
+			maxRate:  cfg.RatesGuardMaxRate,
+			recorder: recorder,
+		})
+		if err != nil {
+			fatal("configuring %s rates guardrails: %v", name, err)
+		}
+
+		available[name] = guarded
+	}
+
+	refresher, err := newRefresher(refreshConfig{
+		interval:   cfg.RatesRefreshInterval,
+		intervals:  cfg.RatesRefreshIntervals,
+		backoff:    cfg.RatesRefreshBackoff,
+		maxBackoff: cfg.RatesRefreshMaxBackoff,
+		timeout:    cfg.RatesProviderTimeout,
+	})
+	if err != nil {
+		fatal("configuring rates refresher: %v", err)
+	}
+
+	// The fiat providers rates are served from are refreshed in the background, requests only read their warm rates.
+	for _, name := range slices.Concat(cfg.RatesProviders, cfg.ConsensusProviders) {
+		name = strings.TrimSpace(name)
+		if _, ok := fiat[name]; !ok {
+			continue
+		}
+		if _, ok := available[name].(*warmProvider); ok {
+			continue
+		}
+
+		available[name] = refresher.keepWarm(name, available[name])
+	}
+
+	if len(cfg.ConsensusProviders) > 0 {
+		consensus, err := newConsensusProvider(cfg.ConsensusProviders, consensusConfig{
+			method:    cfg.ConsensusMethod,
+			tolerance: cfg.ConsensusTolerance,
+			quorum:    cfg.ConsensusQuorum,
+			weights:   cfg.ConsensusWeights,
+			timeout:   cfg.RatesProviderTimeout,
+		}, available)
+		if err != nil {
+			fatal("configuring consensus rates provider: %v", err)
+		}
+
+		available[providerConsensus] = consensus
+	}
+
+	failover, err := newFailoverProvider(cfg.RatesProviders, cfg.RatesProviderTimeout, available)
+	if err != nil {
+		fatal("configuring rates providers: %v", err)
+	}
+
+	pivot := rates.GetCurrency(cfg.RatesPivotCurrency)
+	if pivot == nil {
+		fatal("unknown rates pivot currency %q", cfg.RatesPivotCurrency)
+	}
+
+	// Fiat rates come from the configured providers and crypto rates from the crypto one, crossed through the pivot.
+	ratesProvider := rates.NewCompositeProvider(pivot,
+		rates.NamedProvider{Name: providerFiat, Provider: failover},
+		rates.NamedProvider{Name: providerFixedCrypto, Provider: cryptoRates},
+	)
+
+	exchange := exchanges.NewExchange(ratesProvider, fees)
+
+	quoteStore, closeQuotes, err := newQuoteStore(cfg.QuotesFile)
+	if err != nil {
+		fatal("opening quotes store: %v", err)
+	}
+	defer closeQuotes()
+
+	quoteService := quotes.NewService(exchange, quoteStore, cfg.QuoteTTL)
 
 	router := gin.New()
 	router.Use(gin.Logger())
 	router.Use(gin.Recovery())
 
-	registerRoutes(router, ratesProvider, exchange)
+	timeSeries := rates.NewTimeSeries(ratesProvider, cfg.TimeSeriesMaxDays)
+
+	registerRoutes(router, refresher, ratesProvider, timeSeries, exchange, quoteService, snapshotStore)
+
+	admins, err := parseAdminKeys(cfg.AdminAPIKeys)
+	if err != nil {
+		fatal("reading admin api keys: %v", err)
+	}
+	if len(admins) > 0 {
+		registerAdminRoutes(router, admins, cryptoRates)
+		log.Info("Admin API enabled", "admins", admins.names())
+	}
 
 	httpSrv := &http.Server{
 		Addr:              cfg.Addr,
@@ -69,6 +236,12 @@ func main() {
 		},
 	}
 
+	refreshed := make(chan struct{})
+	go func() {
+		defer close(refreshed)
+		refresher.Run(ctx)
+	}()
+
 	log.Info("Starting Server", "addr", cfg.Addr)
 
 	go func() {
@@ -89,16 +262,68 @@ func main() {
 		log.Error("Server Failed to Shutdown", "err", err)
 	}
 
+	select {
+	case <-refreshed:
+	case <-teardownCtx.Done():
+		log.Error("Rates Refresher Failed to Stop", "err", teardownCtx.Err())
+	}
+
 	log.Info("Server Stopped")
 }
 
 func registerRoutes(
 	router *gin.Engine,
-	provider *rates.OpenExchangeRatesProvider,
+	refresher *refresher,
+	provider rates.Provider,
+	timeSeries *rates.TimeSeries,
 	exchange *exchanges.Exchange,
+	quoteService *quotes.Service,
+	snapshotStore snapshots.Store,
 ) {
+	router.GET("/ready", HandleReady(refresher))
 	router.GET("/rates", HandleRates(provider))
+	router.GET("/rates/timeseries", HandleTimeSeries(timeSeries))
 	router.GET("/exchange", HandleExchange(exchange))
+	router.POST("/quotes", HandleCreateQuote(quoteService))
+	router.POST("/quotes/:id/execute", HandleExecuteQuote(quoteService))
+	router.GET("/snapshots", HandleSnapshotAt(snapshotStore))
+	router.GET("/snapshots/:id", HandleSnapshot(snapshotStore))
+}
+
+// newSnapshotStore opens the snapshots file at path, or keeps snapshots in memory when path is empty.
+func newSnapshotStore(path string) (snapshots.Store, func(), error) {
+	if path == "" {
+		return snapshots.NewMemoryStore(), func() {}, nil
+	}
+
+	store, err := snapshots.OpenFileStore(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return store, func() {
+		if err := store.Close(); err != nil {
+			slog.Error("Closing snapshots store failed", "err", err)
+		}
+	}, nil
+}
+
+// newQuoteStore opens the quotes file at path, or keeps quotes in memory when path is empty.
+func newQuoteStore(path string) (quotes.Store, func(), error) {
+	if path == "" {
+		return quotes.NewMemoryStore(), func() {}, nil
+	}
+
+	store, err := quotes.OpenFileStore(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return store, func() {
+		if err := store.Close(); err != nil {
+			slog.Error("Closing quotes store failed", "err", err)
+		}
+	}, nil
 }
 
 func fatal(msg string, a ...any) {
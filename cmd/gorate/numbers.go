@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/govalues/decimal"
+)
+
+// numbersParam selects how rates and amounts are encoded, either as a query parameter
+// or as a parameter of the requested media type, e.g. "Accept: application/json; numbers=string".
+const numbersParam = "numbers"
+
+const (
+	// numbersNumber encodes decimals as JSON numbers, which clients usually decode to floats. It is the default.
+	numbersNumber = "number"
+
+	// numbersString encodes decimals as JSON strings holding their exact value.
+	numbersString = "string"
+)
+
+// jsonDecimal is a decimal encoded as a JSON number, or as a string holding its exact value when exact is set.
+type jsonDecimal struct {
+	value decimal.Decimal
+	exact bool
+}
+
+func (d jsonDecimal) IsZero() bool {
+	return d.value.IsZero()
+}
+
+func (d jsonDecimal) MarshalJSON() ([]byte, error) {
+	if d.exact {
+		return json.Marshal(d.value.String())
+	}
+
+	f, _ := d.value.Float64()
+	return json.Marshal(f)
+}
+
+// exactNumbers reports whether the client asked for decimals encoded as exact strings.
+// The query parameter takes precedence over the Accept header.
+func exactNumbers(c *gin.Context) (bool, error) {
+	if format, ok := c.GetQuery(numbersParam); ok {
+		return parseNumbers(format)
+	}
+
+	for _, accepted := range strings.Split(c.GetHeader("Accept"), ",") {
+		_, params, err := mime.ParseMediaType(strings.TrimSpace(accepted))
+		if err != nil {
+			continue
+		}
+
+		if format, ok := params[numbersParam]; ok {
+			return parseNumbers(format)
+		}
+	}
+
+	return false, nil
+}
+
+func parseNumbers(format string) (bool, error) {
+	switch format {
+	case numbersNumber:
+		return false, nil
+	case numbersString:
+		return true, nil
+	default:
+		return false, fmt.Errorf("unknown %s format %q, expected %q or %q", numbersParam, format, numbersNumber, numbersString)
+	}
+}
+
+// setNumbersContentType announces exact numbers in the response media type, so they are not mistaken for the default format.
+func setNumbersContentType(c *gin.Context, exact bool) {
+	c.Header("Vary", "Accept")
+
+	if exact {
+		c.Header("Content-Type", "application/json; charset=utf-8; "+numbersParam+"="+numbersString)
+	}
+}
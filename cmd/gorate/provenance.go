@@ -0,0 +1,75 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/IAmRadek/gorate/internal/rates"
+	"github.com/gin-gonic/gin"
+)
+
+// provenance tells where a rate came from, returned along with it when asked for with provenance=true.
+type provenance struct {
+	Source    string   `json:"source,omitempty"`
+	AsOf      string   `json:"as_of,omitempty"`
+	FetchedAt string   `json:"fetched_at,omitempty"`
+	Cached    bool     `json:"cached"`
+	Derived   bool     `json:"derived"`
+	Path      []string `json:"path"`
+
+	Consensus *consensus `json:"consensus,omitempty"`
+}
+
+// consensus is how a rate was agreed on by several providers.
+type consensus struct {
+	Method     string      `json:"method"`
+	Sources    []string    `json:"sources"`
+	Rejected   []string    `json:"rejected,omitempty"`
+	Dispersion jsonDecimal `json:"dispersion"`
+}
+
+// newProvenance returns the provenance of rate, or nil when it wasn't asked for.
+func newProvenance(rate rates.ExchangeRate, exact, asked bool) *provenance {
+	if !asked {
+		return nil
+	}
+
+	out := &provenance{
+		Source:  rate.Source,
+		Cached:  rate.Cached,
+		Derived: rate.Derived(),
+		Path:    rate.Path(),
+	}
+	if !rate.AsOf.IsZero() {
+		out.AsOf = rate.AsOf.UTC().Format(time.RFC3339)
+	}
+	if !rate.FetchedAt.IsZero() {
+		out.FetchedAt = rate.FetchedAt.UTC().Format(time.RFC3339)
+	}
+	if c := rate.Consensus; c != nil {
+		out.Consensus = &consensus{
+			Method:     string(c.Method),
+			Sources:    c.Sources,
+			Rejected:   c.Rejected,
+			Dispersion: jsonDecimal{value: c.Dispersion, exact: exact},
+		}
+	}
+
+	return out
+}
+
+// setProvenanceHeaders tells where the rates of m came from in the response headers: the age of the oldest fetched
+// rate in seconds, the providers that answered, the as-of time of the oldest rate and whether any was cached.
+func setProvenanceHeaders(c *gin.Context, m *rates.Matrix) {
+	if fetchedAt, ok := m.FetchedAt(); ok {
+		c.Header("Age", strconv.Itoa(int(time.Since(fetchedAt).Seconds())))
+	}
+	if sources := m.Sources(); len(sources) > 0 {
+		c.Header("X-Rates-Source", strings.Join(sources, ","))
+	}
+	if asOf, ok := m.AsOf(); ok {
+		c.Header("X-Rates-As-Of", asOf.UTC().Format(time.RFC3339))
+	}
+	c.Header("X-Rates-Cached", strconv.FormatBool(m.Cached()))
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/IAmRadek/gorate/internal/rates"
+	"github.com/govalues/decimal"
+)
+
+// Names under which rate providers can be listed in RATES_PROVIDERS.
+const (
+	providerOpenExchangeRates = "openexchangerates"
+	providerECB               = "ecb"
+	providerNBP               = "nbp"
+	providerFixedCrypto       = "fixed_crypto"
+	providerConsensus         = "consensus"
+)
+
+// providerFiat is the name the chain of RATES_PROVIDERS is merged with the fixed crypto rates under.
+const providerFiat = "fiat"
+
+// newFailoverProvider chains the providers listed in names, in that order.
+func newFailoverProvider(names []string, timeout time.Duration, available map[string]rates.Provider) (*rates.FailoverProvider, error) {
+	chain := make([]rates.NamedProvider, 0, len(names))
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+
+		provider, ok := available[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown rates provider %q", name)
+		}
+
+		chain = append(chain, rates.NamedProvider{Name: name, Provider: provider})
+	}
+
+	if len(chain) == 0 {
+		return nil, fmt.Errorf("no rates providers configured")
+	}
+
+	return rates.NewFailoverProvider(timeout, chain...), nil
+}
+
+// consensusConfig configures the consensus of the providers listed in CONSENSUS_PROVIDERS.
+type consensusConfig struct {
+	method    string
+	tolerance string
+	quorum    int
+	weights   []string
+	timeout   time.Duration
+}
+
+// newConsensusProvider takes the consensus of the providers listed in names. Weights are "name:weight" entries and a
+// non-positive quorum leaves it to a majority of the providers.
+func newConsensusProvider(names []string, cfg consensusConfig, available map[string]rates.Provider) (*rates.ConsensusProvider, error) {
+	providers := make([]rates.NamedProvider, 0, len(names))
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+
+		provider, ok := available[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown consensus provider %q", name)
+		}
+
+		providers = append(providers, rates.NamedProvider{Name: name, Provider: provider})
+	}
+
+	method, err := rates.ParseConsensusMethod(cfg.method)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []rates.ConsensusOption{rates.WithConsensusMethod(method), rates.WithConsensusTimeout(cfg.timeout)}
+
+	if cfg.tolerance != "" {
+		tolerance, err := decimal.Parse(cfg.tolerance)
+		if err != nil {
+			return nil, fmt.Errorf("consensus tolerance %q: %w", cfg.tolerance, err)
+		}
+
+		opts = append(opts, rates.WithTolerance(tolerance))
+	}
+
+	if cfg.quorum > 0 {
+		opts = append(opts, rates.WithQuorum(cfg.quorum))
+	}
+
+	if len(cfg.weights) > 0 {
+		weights := make(map[string]decimal.Decimal, len(cfg.weights))
+		for _, entry := range cfg.weights {
+			name, raw, ok := strings.Cut(strings.TrimSpace(entry), ":")
+			if !ok {
+				return nil, fmt.Errorf("consensus weight %q: not of the form name:weight", entry)
+			}
+
+			weight, err := decimal.Parse(raw)
+			if err != nil {
+				return nil, fmt.Errorf("consensus weight of %q: %w", name, err)
+			}
+
+			weights[name] = weight
+		}
+
+		opts = append(opts, rates.WithWeights(weights))
+	}
+
+	return rates.NewConsensusProvider(providers, opts...)
+}
+
+// guardConfig configures the guardrails of the fiat providers.
+type guardConfig struct {
+	action   string
+	maxMove  string
+	maxMoves []string
+	minRate  string
+	maxRate  string
+	recorder rates.Recorder
+}
+
+// newGuardedProvider guards provider, reported under name, against anomalous rates and records the tables it serves.
+// Per-currency thresholds are "CODE:threshold" entries.
+func newGuardedProvider(name string, provider rates.Provider, cfg guardConfig) (*rates.GuardedProvider, error) {
+	action, err := rates.ParseGuardAction(cfg.action)
+	if err != nil {
+		return nil, err
+	}
+
+	maxMove, err := decimal.Parse(cfg.maxMove)
+	if err != nil {
+		return nil, fmt.Errorf("guard max move %q: %w", cfg.maxMove, err)
+	}
+
+	opts := []rates.GuardOption{rates.WithGuardAction(action), rates.WithMaxMove(maxMove)}
+
+	for _, entry := range cfg.maxMoves {
+		code, raw, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		if !ok {
+			return nil, fmt.Errorf("guard max move %q: not of the form CODE:threshold", entry)
+		}
+
+		threshold, err := decimal.Parse(raw)
+		if err != nil {
+			return nil, fmt.Errorf("guard max move of %q: %w", code, err)
+		}
+
+		opts = append(opts, rates.WithCurrencyMaxMove(strings.ToUpper(code), threshold))
+	}
+
+	minRate, err := decimal.Parse(cfg.minRate)
+	if err != nil {
+		return nil, fmt.Errorf("guard min rate %q: %w", cfg.minRate, err)
+	}
+
+	maxRate, err := decimal.Parse(cfg.maxRate)
+	if err != nil {
+		return nil, fmt.Errorf("guard max rate %q: %w", cfg.maxRate, err)
+	}
+
+	opts = append(opts, rates.WithRateBounds(minRate, maxRate), rates.WithGuardRecorder(cfg.recorder))
+
+	return rates.NewGuardedProvider(name, provider, opts...)
+}
+
+// newCryptoProvider returns the crypto rates declared in the file at path, reloaded every interval when it changed
+// and on SIGHUP until ctx is done, or the fixed crypto rates when path is empty. Either can be changed at runtime.
+func newCryptoProvider(ctx context.Context, path string, interval time.Duration, recorder rates.Recorder) (*rates.MutableCryptoRatesProvider, error) {
+	if path == "" {
+		return rates.NewMutableCryptoRatesProvider(rates.FixedCryptoConfig(),
+			rates.CryptoChange{Author: "gorate", Reason: "fixed crypto rates"},
+			rates.WithCryptoRecorder(providerFixedCrypto, recorder),
+		)
+	}
+
+	provider, err := rates.NewCryptoFileProvider(path, rates.WithCryptoRecorder(providerFixedCrypto, recorder))
+	if err != nil {
+		return nil, err
+	}
+
+	go provider.Watch(ctx, interval)
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	go func() {
+		defer signal.Stop(hup)
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hup:
+			}
+
+			if err := provider.Reload(); err != nil {
+				slog.Error("Reloading crypto rates failed, keeping the last good config", "path", path, "err", err)
+				continue
+			}
+
+			slog.Info("Crypto rates reloaded", "path", path)
+		}
+	}()
+
+	return provider.MutableCryptoRatesProvider, nil
+}
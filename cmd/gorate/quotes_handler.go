@@ -0,0 +1,174 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/IAmRadek/gorate/internal/exchanges"
+	"github.com/IAmRadek/gorate/internal/quotes"
+	"github.com/IAmRadek/gorate/internal/rates"
+	"github.com/gin-gonic/gin"
+	"github.com/govalues/decimal"
+)
+
+type quoteFee struct {
+	Type   string      `json:"type"`
+	Amount jsonDecimal `json:"amount"`
+}
+
+type quoteResponse struct {
+	ID              string      `json:"id"`
+	From            string      `json:"from"`
+	To              string      `json:"to"`
+	Amount          jsonDecimal `json:"amount"`
+	Rate            jsonDecimal `json:"rate"`
+	RateAsOf        string      `json:"rate_as_of,omitempty"`
+	RateFetchedAt   string      `json:"rate_fetched_at,omitempty"`
+	RateSource      string      `json:"rate_source,omitempty"`
+	Rounding        string      `json:"rounding"`
+	GrossAmount     jsonDecimal `json:"gross_amount"`
+	Spread          jsonDecimal `json:"spread"`
+	Fees            []quoteFee  `json:"fees"`
+	UnroundedAmount jsonDecimal `json:"unrounded_amount"`
+	NetAmount       jsonDecimal `json:"net_amount"`
+	CreatedAt       string      `json:"created_at"`
+	ExpiresAt       string      `json:"expires_at"`
+	ExecutedAt      string      `json:"executed_at,omitempty"`
+}
+
+func newQuoteResponse(q *quotes.Quote, exact bool) quoteResponse {
+	fees := make([]quoteFee, 0, len(q.Fees))
+	for _, f := range q.Fees {
+		fees = append(fees, quoteFee{Type: string(f.Kind), Amount: jsonDecimal{value: f.Amount, exact: exact}})
+	}
+
+	resp := quoteResponse{
+		ID:              q.ID,
+		From:            q.From,
+		To:              q.To,
+		Amount:          jsonDecimal{value: q.Amount, exact: exact},
+		Rate:            jsonDecimal{value: q.Rate, exact: exact},
+		RateSource:      q.RateSource,
+		Rounding:        string(q.Rounding),
+		GrossAmount:     jsonDecimal{value: q.Gross, exact: exact},
+		Spread:          jsonDecimal{value: q.Spread, exact: exact},
+		Fees:            fees,
+		UnroundedAmount: jsonDecimal{value: q.Unrounded, exact: exact},
+		NetAmount:       jsonDecimal{value: q.Net, exact: exact},
+		CreatedAt:       q.CreatedAt.Format(time.RFC3339),
+		ExpiresAt:       q.ExpiresAt.Format(time.RFC3339),
+	}
+	if !q.RateAsOf.IsZero() {
+		resp.RateAsOf = q.RateAsOf.Format(time.RFC3339)
+	}
+	if !q.RateFetchedAt.IsZero() {
+		resp.RateFetchedAt = q.RateFetchedAt.Format(time.RFC3339)
+	}
+	if q.Executed() {
+		resp.ExecutedAt = q.ExecutedAt.Format(time.RFC3339)
+	}
+
+	return resp
+}
+
+// HandleCreateQuote quotes a conversion, locking its rate and amounts until the quote expires.
+func HandleCreateQuote(service *quotes.Service) gin.HandlerFunc {
+	type request struct {
+		From     string          `json:"from"`
+		To       string          `json:"to"`
+		Amount   decimal.Decimal `json:"amount"`
+		Rounding string          `json:"rounding"`
+	}
+
+	return func(c *gin.Context) {
+		var req request
+
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, map[string]string{
+				"error": fmt.Sprintf("cannot parse request: %v", err),
+			})
+			return
+		}
+
+		exact, err := exactNumbers(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, map[string]string{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		rounding, err := exchanges.ParseRounding(req.Rounding)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, map[string]string{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		from := rates.GetCurrency(req.From)
+		if from == nil {
+			c.JSON(http.StatusBadRequest, map[string]string{
+				"error": fmt.Sprintf("unknown currency %q", req.From),
+			})
+			return
+		}
+
+		to := rates.GetCurrency(req.To)
+		if to == nil {
+			c.JSON(http.StatusBadRequest, map[string]string{
+				"error": fmt.Sprintf("unknown currency %q", req.To),
+			})
+			return
+		}
+
+		if req.Amount.Sign() <= 0 {
+			c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "amount must be positive",
+			})
+			return
+		}
+
+		q, err := service.Quote(c.Copy(), from, to, req.Amount, exchanges.WithRounding(rounding))
+		if err != nil {
+			logError(c, "quote failed", err)
+			c.JSON(errorStatus(err, http.StatusInternalServerError), map[string]string{
+				"error": fmt.Sprintf("quote failed: %v", err),
+			})
+			return
+		}
+
+		setNumbersContentType(c, exact)
+		c.JSON(http.StatusCreated, newQuoteResponse(q, exact))
+	}
+}
+
+// HandleExecuteQuote executes the quote with the ID given in the path, once and only before it expires.
+func HandleExecuteQuote(service *quotes.Service) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		exact, err := exactNumbers(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, map[string]string{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		q, err := service.Execute(c.Copy(), c.Param("id"))
+		if err != nil {
+			status := errorStatus(err, http.StatusInternalServerError)
+			if status == http.StatusInternalServerError {
+				logError(c, "executing quote failed", err)
+			}
+
+			c.JSON(status, map[string]string{
+				"error": fmt.Sprintf("executing quote failed: %v", err),
+			})
+			return
+		}
+
+		setNumbersContentType(c, exact)
+		c.JSON(http.StatusOK, newQuoteResponse(q, exact))
+	}
+}
@@ -1,23 +1,30 @@
 package main
 
 import (
+	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/IAmRadek/gorate/internal/rates"
 	"github.com/Rhymond/go-money"
 	"github.com/gin-gonic/gin"
 )
 
-func HandleRates(rates rates.Provider) gin.HandlerFunc {
+func HandleRates(provider rates.Provider) gin.HandlerFunc {
 	type request struct {
 		Currencies string `form:"currencies"`
+		Date       string `form:"date"`
+		BidAsk     bool   `form:"bid_ask"`
+		Provenance bool   `form:"provenance"`
 	}
 
 	type response struct {
-		From string  `json:"from,omitempty"`
-		To   string  `json:"to,omitempty"`
-		Rate float64 `json:"rate,omitempty"`
+		From string `json:"from,omitempty"`
+		To   string `json:"to,omitempty"`
+		rateSides
+		Legs       []rateLeg   `json:"legs,omitempty"`
+		Provenance *provenance `json:"provenance,omitempty"`
 	}
 
 	return func(c *gin.Context) {
@@ -28,6 +35,13 @@ func HandleRates(rates rates.Provider) gin.HandlerFunc {
 			return
 		}
 
+		exact, err := exactNumbers(c)
+		if err != nil {
+			logError(c, "invalid numbers format", err)
+			c.Status(http.StatusBadRequest)
+			return
+		}
+
 		rawCurrencies := strings.Split(req.Currencies, ",")
 
 		if len(rawCurrencies) < 2 {
@@ -37,7 +51,7 @@ func HandleRates(rates rates.Provider) gin.HandlerFunc {
 
 		currencies := make([]*money.Currency, 0, len(req.Currencies))
 		for _, cur := range rawCurrencies {
-			currency := money.GetCurrency(cur)
+			currency := rates.GetCurrency(cur)
 			if currency == nil {
 				c.Status(http.StatusBadRequest)
 				return
@@ -46,24 +60,108 @@ func HandleRates(rates rates.Provider) gin.HandlerFunc {
 			currencies = append(currencies, currency)
 		}
 
-		exchangeRates, err := rates.Rates(c.Copy(), currencies[0], currencies[1], currencies[1:]...)
+		var exchangeRates *rates.Matrix
+
+		if req.Date == "" {
+			exchangeRates, err = provider.Rates(c.Copy(), currencies[0], currencies[1], currencies[1:]...)
+		} else {
+			var date time.Time
+			date, err = parseDate(provider, req.Date)
+			if err != nil {
+				logError(c, "invalid date", err)
+				c.Status(errorStatus(err, http.StatusBadRequest))
+				return
+			}
+
+			exchangeRates, err = provider.(rates.HistoricalProvider).RatesAt(c.Copy(), date, currencies[0], currencies[1], currencies[1:]...)
+		}
 		if err != nil {
-			c.Status(http.StatusBadRequest)
+			logError(c, "getting rates failed", err)
+			c.Status(errorStatus(err, http.StatusBadRequest))
 			return
 		}
 
-		out := make([]response, 0, len(exchangeRates))
+		setProvenanceHeaders(c, exchangeRates)
 
-		for _, rate := range exchangeRates {
-			r, _ := rate.Rate.Float64()
+		out := make([]response, 0, exchangeRates.Len())
 
+		for _, rate := range exchangeRates.Rates() {
 			out = append(out, response{
-				From: rate.From.Code,
-				To:   rate.To.Code,
-				Rate: r,
+				From:       rate.From.Code,
+				To:         rate.To.Code,
+				rateSides:  newRateSides(rate, exact, req.BidAsk),
+				Legs:       newRateLegs(rate.Legs, exact, req.BidAsk, req.Provenance),
+				Provenance: newProvenance(rate, exact, req.Provenance),
 			})
 		}
 
+		setNumbersContentType(c, exact)
 		c.JSON(http.StatusOK, out)
 	}
 }
+
+// rateSides is the rate of a pair and, when asked for, its bid, ask and mid. Rates quoted at a single rate have it on
+// every side.
+type rateSides struct {
+	Rate jsonDecimal `json:"rate,omitzero"`
+	Bid  jsonDecimal `json:"bid,omitzero"`
+	Ask  jsonDecimal `json:"ask,omitzero"`
+	Mid  jsonDecimal `json:"mid,omitzero"`
+}
+
+func newRateSides(rate rates.ExchangeRate, exact, bidAsk bool) rateSides {
+	out := rateSides{Rate: jsonDecimal{value: rate.Rate, exact: exact}}
+	if bidAsk {
+		out.Bid = jsonDecimal{value: rate.SellRate(), exact: exact}
+		out.Ask = jsonDecimal{value: rate.BuyRate(), exact: exact}
+		out.Mid = out.Rate
+	}
+
+	return out
+}
+
+// rateLeg is one of the rates a cross rate was derived from, with the provider it came from.
+type rateLeg struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	rateSides
+	Source string `json:"source,omitempty"`
+}
+
+// newRateLegs returns the legs of a derived rate, or nil when its provenance wasn't asked for.
+func newRateLegs(legs []rates.ExchangeRate, exact, bidAsk, asked bool) []rateLeg {
+	if !asked || len(legs) == 0 {
+		return nil
+	}
+
+	out := make([]rateLeg, 0, len(legs))
+	for _, leg := range legs {
+		out = append(out, rateLeg{
+			From:      leg.From.Code,
+			To:        leg.To.Code,
+			rateSides: newRateSides(leg, exact, bidAsk),
+			Source:    leg.Source,
+		})
+	}
+
+	return out
+}
+
+// parseDate parses a YYYY-MM-DD date and checks provider has rates for it.
+func parseDate(provider rates.Provider, raw string) (time.Time, error) {
+	date, err := time.Parse(time.DateOnly, raw)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("parsing date: %w", err)
+	}
+
+	hp, ok := provider.(rates.HistoricalProvider)
+	if !ok {
+		return time.Time{}, rates.ErrNoHistory
+	}
+
+	if err := rates.CheckDate(hp, date, time.Now()); err != nil {
+		return time.Time{}, err
+	}
+
+	return date, nil
+}
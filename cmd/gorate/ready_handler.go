@@ -0,0 +1,42 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HandleReady reports whether the rates have been warmed up and the server is ready to serve them, along with when
+// every provider was last refreshed.
+func HandleReady(r *refresher) gin.HandlerFunc {
+	type provider struct {
+		Name        string `json:"name"`
+		Warm        bool   `json:"warm"`
+		RefreshedAt string `json:"refreshed_at,omitempty"`
+	}
+
+	type response struct {
+		Ready     bool       `json:"ready"`
+		Providers []provider `json:"providers"`
+	}
+
+	return func(c *gin.Context) {
+		res := response{Ready: r.ready.Load(), Providers: make([]provider, 0, len(r.providers))}
+		for _, w := range r.providers {
+			p := provider{Name: w.name}
+			if refreshedAt := w.refreshedAt(); !refreshedAt.IsZero() {
+				p.Warm, p.RefreshedAt = true, refreshedAt.UTC().Format(time.RFC3339)
+			}
+
+			res.Providers = append(res.Providers, p)
+		}
+
+		status := http.StatusOK
+		if !res.Ready {
+			status = http.StatusServiceUnavailable
+		}
+
+		c.JSON(status, res)
+	}
+}
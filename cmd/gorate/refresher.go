@@ -0,0 +1,300 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/IAmRadek/gorate/internal/rates"
+	"github.com/Rhymond/go-money"
+)
+
+// refreshJitter is the share of every wait between refreshes it is randomly shortened or lengthened by, so the
+// refreshes of several instances don't line up.
+const refreshJitter = 0.1
+
+// refreshConfig configures how often the providers are refreshed.
+type refreshConfig struct {
+	interval   time.Duration
+	intervals  []string
+	backoff    time.Duration
+	maxBackoff time.Duration
+	timeout    time.Duration
+}
+
+// refresher keeps the latest rates of providers warm in the background, each refreshed on its own cadence and
+// retried with exponential backoff when it fails.
+type refresher struct {
+	cfg       refreshConfig
+	intervals map[string]time.Duration
+	providers []*warmProvider
+
+	now   func() time.Time
+	after func(time.Duration) <-chan time.Time
+
+	// ready is set once every provider was refreshed or failed to be at least once, and at least one has rates.
+	ready atomic.Bool
+}
+
+// newRefresher returns a refresher refreshing providers every cfg.interval, or as often as set for them in
+// cfg.intervals as "name:interval" entries.
+func newRefresher(cfg refreshConfig) (*refresher, error) {
+	if cfg.interval <= 0 {
+		return nil, fmt.Errorf("refresh interval %s: must be positive", cfg.interval)
+	}
+	if cfg.backoff <= 0 || cfg.maxBackoff < cfg.backoff {
+		return nil, fmt.Errorf("refresh backoff %s up to %s: must be positive and not above its maximum", cfg.backoff, cfg.maxBackoff)
+	}
+
+	intervals := make(map[string]time.Duration, len(cfg.intervals))
+	for _, entry := range cfg.intervals {
+		name, raw, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		if !ok {
+			return nil, fmt.Errorf("refresh interval %q: not of the form name:interval", entry)
+		}
+
+		interval, err := time.ParseDuration(raw)
+		if err != nil {
+			return nil, fmt.Errorf("refresh interval of %q: %w", name, err)
+		}
+		if interval <= 0 {
+			return nil, fmt.Errorf("refresh interval of %q: must be positive", name)
+		}
+
+		intervals[name] = interval
+	}
+
+	return &refresher{cfg: cfg, intervals: intervals, now: time.Now, after: time.After}, nil
+}
+
+// keepWarm returns provider, named name, served from the rates the refresher keeps warm.
+func (r *refresher) keepWarm(name string, provider rates.Provider) *warmProvider {
+	interval, ok := r.intervals[name]
+	if !ok {
+		interval = r.cfg.interval
+	}
+
+	w := &warmProvider{name: name, provider: provider, interval: interval, now: r.now}
+	r.providers = append(r.providers, w)
+
+	return w
+}
+
+// Run warms up every provider and then keeps refreshing them until ctx is done, returning once all of them stopped.
+func (r *refresher) Run(ctx context.Context) {
+	start := r.now()
+
+	var warmup, wg sync.WaitGroup
+	for _, w := range r.providers {
+		warmup.Add(1)
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+			r.keepRefreshing(ctx, w, warmup.Done)
+		}()
+	}
+
+	warmup.Wait()
+	r.warmedUp(ctx, start)
+
+	wg.Wait()
+}
+
+// warmedUp marks the refresher ready after the warm-up, as soon as any provider has rates.
+func (r *refresher) warmedUp(ctx context.Context, start time.Time) {
+	for {
+		var warm []string
+		for _, w := range r.providers {
+			if !w.refreshedAt().IsZero() {
+				warm = append(warm, w.name)
+			}
+		}
+
+		if len(warm) > 0 || len(r.providers) == 0 {
+			r.ready.Store(true)
+			slog.InfoContext(ctx, "Rates warmed up", "providers", warm, "took", r.now().Sub(start))
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-r.after(r.cfg.backoff):
+		}
+	}
+}
+
+// keepRefreshing refreshes w right away, calling warmedUp after the first attempt, and then every interval until
+// ctx is done. Failed refreshes are retried after a backoff doubling with every failure in a row, up to maxBackoff.
+func (r *refresher) keepRefreshing(ctx context.Context, w *warmProvider, warmedUp func()) {
+	failures := 0
+
+	for {
+		err := r.refresh(ctx, w)
+		if warmedUp != nil {
+			warmedUp()
+			warmedUp = nil
+		}
+
+		wait := w.interval
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+
+			failures++
+			wait = backoff(r.cfg.backoff, r.cfg.maxBackoff, failures)
+			slog.WarnContext(ctx, "Refreshing rates failed", "provider", w.name, "warm", !w.refreshedAt().IsZero(),
+				"failures", failures, "retry_in", wait, "err", err)
+		} else {
+			failures = 0
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-r.after(jitter(wait)):
+		}
+	}
+}
+
+// refresh refreshes the rates of w within the refresh timeout.
+func (r *refresher) refresh(ctx context.Context, w *warmProvider) error {
+	ctx, cancel := context.WithTimeout(ctx, r.cfg.timeout)
+	defer cancel()
+
+	return w.refresh(ctx)
+}
+
+// backoff returns how long to wait after failures in a row, base doubled with every failure after the first, up to max.
+func backoff(base, maxBackoff time.Duration, failures int) time.Duration {
+	wait := base
+	for i := 1; i < failures && wait < maxBackoff; i++ {
+		wait *= 2
+	}
+
+	return min(wait, maxBackoff)
+}
+
+// jitter returns d randomly shortened or lengthened by up to refreshJitter of it.
+func jitter(d time.Duration) time.Duration {
+	return time.Duration(float64(d) * (1 + refreshJitter*(2*rand.Float64()-1)))
+}
+
+// warmProvider serves the latest rates of a provider derived from the quotes it was last refreshed with in the
+// background, so no request waits on the provider. Only the quotes are kept, the rates between the currencies of a
+// request are derived from them as it is served. Historical rates are still asked for on demand.
+type warmProvider struct {
+	name     string
+	provider rates.Provider
+	interval time.Duration
+	now      func() time.Time
+
+	mu        sync.RWMutex
+	quotes    *rates.Graph
+	supported []*money.Currency // ordered by code
+	updatedAt time.Time
+}
+
+// refresh replaces the warm quotes with the latest ones of the provider.
+func (w *warmProvider) refresh(ctx context.Context) error {
+	qp, ok := w.provider.(rates.QuoteProvider)
+	if !ok {
+		return fmt.Errorf("%s has no quotes to keep warm", w.name)
+	}
+
+	fresh, err := qp.Quotes(ctx)
+	if err != nil {
+		return fmt.Errorf("getting quotes: %w", err)
+	}
+	if fresh.Len() == 0 {
+		return fmt.Errorf("no quotes")
+	}
+
+	// The quotes are fetched before the rates derived from them are asked for, so those are served as cached.
+	cached := fresh.Rates()
+	for i := range cached {
+		cached[i].Cached = true
+	}
+
+	quotes := rates.NewGraph()
+	if err := quotes.Add(cached...); err != nil {
+		return fmt.Errorf("adding quotes: %w", err)
+	}
+
+	w.mu.Lock()
+	w.quotes, w.supported, w.updatedAt = quotes, fresh.Currencies(), w.now()
+	w.mu.Unlock()
+
+	return nil
+}
+
+// SupportedCurrencies returns the currencies the warm quotes are for.
+func (w *warmProvider) SupportedCurrencies(ctx context.Context) ([]*money.Currency, error) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	if w.quotes == nil {
+		return nil, fmt.Errorf("%s rates not warmed up yet: %w", w.name, rates.ErrUnavailable)
+	}
+
+	return slices.Clone(w.supported), nil
+}
+
+// Rates returns the rates between the currencies derived from the warm quotes, marked as cached.
+func (w *warmProvider) Rates(ctx context.Context, c1, c2 *money.Currency, c ...*money.Currency) (*rates.Matrix, error) {
+	w.mu.RLock()
+	quotes, supported := w.quotes, w.supported
+	w.mu.RUnlock()
+
+	if quotes == nil {
+		return nil, fmt.Errorf("%s rates not warmed up yet: %w", w.name, rates.ErrUnavailable)
+	}
+
+	currencies := append([]*money.Currency{c1, c2}, c...)
+	for _, currency := range currencies {
+		_, ok := slices.BinarySearchFunc(supported, currency.Code, func(s *money.Currency, code string) int {
+			return strings.Compare(s.Code, code)
+		})
+		if !ok {
+			return nil, fmt.Errorf("%s has no rate for %q: %w", w.name, currency.Code, rates.ErrUnsupportedCurrency)
+		}
+	}
+
+	return quotes.Rates(currencies)
+}
+
+// Since returns the first day the provider has rates for, zero when it has no history.
+func (w *warmProvider) Since() time.Time {
+	hp, ok := w.provider.(rates.HistoricalProvider)
+	if !ok {
+		return time.Time{}
+	}
+
+	return hp.Since()
+}
+
+// RatesAt asks the provider for its rates as of date.
+func (w *warmProvider) RatesAt(ctx context.Context, date time.Time, c1, c2 *money.Currency, c ...*money.Currency) (*rates.Matrix, error) {
+	hp, ok := w.provider.(rates.HistoricalProvider)
+	if !ok {
+		return nil, rates.ErrNoHistory
+	}
+
+	return hp.RatesAt(ctx, date, c1, c2, c...)
+}
+
+// refreshedAt returns when the provider was last refreshed, zero when it hasn't been yet.
+func (w *warmProvider) refreshedAt() time.Time {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	return w.updatedAt
+}
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/IAmRadek/gorate/internal/rates"
+	"github.com/Rhymond/go-money"
+	"github.com/gin-gonic/gin"
+	"github.com/govalues/decimal"
+)
+
+var errUpstream = errors.New("upstream is down")
+
+// fakeQuotes is a QuoteProvider answering with USD quotes of EUR and GBP, unless the next of errs, taken one per
+// call, is not nil or failing is set.
+type fakeQuotes struct {
+	rates.Provider
+
+	mu      sync.Mutex
+	errs    []error
+	failing bool
+}
+
+func (f *fakeQuotes) Quotes(ctx context.Context) (*rates.Matrix, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.failing {
+		return nil, errUpstream
+	}
+	if len(f.errs) > 0 {
+		err := f.errs[0]
+		f.errs = f.errs[1:]
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	usd := money.GetCurrency("USD")
+	return rates.NewMatrix(
+		rates.ExchangeRate{From: usd, To: money.GetCurrency("EUR"), Rate: decimal.MustParse("0.85"), Source: "fake"},
+		rates.ExchangeRate{From: usd, To: money.GetCurrency("GBP"), Rate: decimal.MustParse("0.75"), Source: "fake"},
+	)
+}
+
+// fakeClock hands every wait asked for over on waits, to be ended with fire. Once ctx is done it no longer does, and
+// the waits never end.
+type fakeClock struct {
+	ctx   context.Context
+	waits chan fakeWait
+
+	mu  sync.Mutex
+	now time.Time
+}
+
+type fakeWait struct {
+	d    time.Duration
+	done chan time.Time
+}
+
+func newFakeClock(ctx context.Context) *fakeClock {
+	return &fakeClock{ctx: ctx, waits: make(chan fakeWait), now: time.Date(2025, 7, 1, 12, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	w := fakeWait{d: d, done: make(chan time.Time, 1)}
+
+	select {
+	case c.waits <- w:
+		return w.done
+	case <-c.ctx.Done():
+		return nil
+	}
+}
+
+// fire moves the clock forward by w and ends it.
+func (c *fakeClock) fire(w fakeWait) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(w.d)
+	w.done <- c.now
+}
+
+func newTestRefresher(t *testing.T, clock *fakeClock) *refresher {
+	t.Helper()
+
+	r, err := newRefresher(refreshConfig{
+		interval:   time.Hour,
+		backoff:    time.Second,
+		maxBackoff: 4 * time.Second,
+		timeout:    time.Second,
+	})
+	if err != nil {
+		t.Fatalf("newRefresher: %v", err)
+	}
+	r.now, r.after = clock.Now, clock.After
+
+	return r
+}
+
+func TestRefresher(t *testing.T) {
+	t.Run("recovers_after_failures", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(t.Context())
+		defer cancel()
+
+		clock := newFakeClock(ctx)
+		r := newTestRefresher(t, clock)
+		w := r.keepWarm("fake", &fakeQuotes{errs: []error{errUpstream, errUpstream, errUpstream, errUpstream, errUpstream}})
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			r.keepRefreshing(ctx, w, nil)
+		}()
+
+		// The backoff doubles with every failure up to its maximum, and the interval is back once refreshed.
+		for i, want := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 4 * time.Second, 4 * time.Second, time.Hour} {
+			wait := <-clock.waits
+			if spread := time.Duration(float64(want) * refreshJitter); wait.d < want-spread || wait.d > want+spread {
+				t.Fatalf("wait %d: expected %s give or take %s got %s", i, want, spread, wait.d)
+			}
+			if warm := !w.refreshedAt().IsZero(); warm != (i == 5) {
+				t.Fatalf("wait %d: expected warm %t got %t", i, i == 5, warm)
+			}
+			if i == 5 && !w.refreshedAt().Equal(clock.Now()) {
+				t.Fatalf("expected refreshed at %s got %s", clock.Now(), w.refreshedAt())
+			}
+
+			clock.fire(wait)
+		}
+
+		cancel()
+		<-done
+	})
+
+	t.Run("ready_once_any_provider_is_warm", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(t.Context())
+		defer cancel()
+
+		clock := newFakeClock(ctx)
+		r := newTestRefresher(t, clock)
+		r.keepWarm("failing", &fakeQuotes{failing: true})
+		r.keepWarm("recovering", &fakeQuotes{errs: []error{errUpstream}})
+
+		router := gin.New()
+		router.GET("/ready", HandleReady(r))
+
+		type response struct {
+			Ready     bool `json:"ready"`
+			Providers []struct {
+				Name string `json:"name"`
+				Warm bool   `json:"warm"`
+			} `json:"providers"`
+		}
+		ready := func() (int, response) {
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ready", nil))
+
+			var res response
+			if err := json.NewDecoder(rec.Body).Decode(&res); err != nil {
+				t.Fatalf("decoding ready: %v", err)
+			}
+
+			return rec.Code, res
+		}
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			r.Run(ctx)
+		}()
+
+		// No provider has answered with quotes yet.
+		if code, res := ready(); code != http.StatusServiceUnavailable || res.Ready {
+			t.Fatalf("expected not to be ready got %d %+v", code, res)
+		}
+
+		for !r.ready.Load() {
+			clock.fire(<-clock.waits)
+		}
+
+		code, res := ready()
+		if code != http.StatusOK || !res.Ready {
+			t.Fatalf("expected to be ready got %d %+v", code, res)
+		}
+		for _, p := range res.Providers {
+			if p.Warm != (p.Name == "recovering") {
+				t.Fatalf("expected only recovering to be warm got %+v", res.Providers)
+			}
+		}
+
+		cancel()
+		<-done
+	})
+}
+
+func TestBackoff(t *testing.T) {
+	for i, want := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second} {
+		if got := backoff(time.Second, 5*time.Second, i+1); got != want {
+			t.Fatalf("%d failures: expected %s got %s", i+1, want, got)
+		}
+	}
+}
+
+func TestWarmProvider(t *testing.T) {
+	usd, eur, gbp, jpy := money.GetCurrency("USD"), money.GetCurrency("EUR"), money.GetCurrency("GBP"), money.GetCurrency("JPY")
+	refreshedAt := time.Date(2025, 7, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("serves_cached_rates", func(t *testing.T) {
+		w := &warmProvider{name: "fake", provider: &fakeQuotes{}, now: func() time.Time { return refreshedAt }}
+
+		if _, err := w.Rates(t.Context(), usd, eur); !errors.Is(err, rates.ErrUnavailable) {
+			t.Fatalf("expected ErrUnavailable before the warm-up got %v", err)
+		}
+
+		if err := w.refresh(t.Context()); err != nil {
+			t.Fatalf("refresh: %v", err)
+		}
+		if got := w.refreshedAt(); !got.Equal(refreshedAt) {
+			t.Fatalf("expected refreshed at %s got %s", refreshedAt, got)
+		}
+
+		m, err := w.Rates(t.Context(), usd, eur, gbp)
+		if err != nil {
+			t.Fatalf("rates: %v", err)
+		}
+		if m.Len() != 6 {
+			t.Fatalf("expected 6 rates got %d", m.Len())
+		}
+		for _, rate := range m.Rates() {
+			if !rate.Cached {
+				t.Fatalf("expected %s to be cached", rate)
+			}
+		}
+
+		if _, err := w.Rates(t.Context(), usd, jpy); !errors.Is(err, rates.ErrUnsupportedCurrency) {
+			t.Fatalf("expected ErrUnsupportedCurrency got %v", err)
+		}
+	})
+
+	t.Run("keeps_quotes_on_failure", func(t *testing.T) {
+		now := refreshedAt
+		w := &warmProvider{name: "fake", provider: &fakeQuotes{errs: []error{nil, errUpstream}}, now: func() time.Time { return now }}
+
+		if err := w.refresh(t.Context()); err != nil {
+			t.Fatalf("refresh: %v", err)
+		}
+
+		now = now.Add(time.Hour)
+		if err := w.refresh(t.Context()); !errors.Is(err, errUpstream) {
+			t.Fatalf("expected errUpstream got %v", err)
+		}
+
+		if got := w.refreshedAt(); !got.Equal(refreshedAt) {
+			t.Fatalf("expected refreshed at %s got %s", refreshedAt, got)
+		}
+		if _, err := w.Rates(t.Context(), usd, eur); err != nil {
+			t.Fatalf("expected the last quotes to be served got %v", err)
+		}
+	})
+}
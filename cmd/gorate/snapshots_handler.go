@@ -0,0 +1,83 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/IAmRadek/gorate/internal/snapshots"
+	"github.com/gin-gonic/gin"
+)
+
+// HandleSnapshot returns the snapshot with the ID given in the path.
+func HandleSnapshot(store snapshots.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		s, err := store.Get(c.Copy(), c.Param("id"))
+		if err != nil {
+			writeSnapshotError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, s)
+	}
+}
+
+// HandleSnapshotAt returns the snapshot a provider's rates were served from at a given time.
+func HandleSnapshotAt(store snapshots.Store) gin.HandlerFunc {
+	type request struct {
+		Provider string `form:"provider"`
+		At       string `form:"at"`
+	}
+
+	return func(c *gin.Context) {
+		var req request
+
+		if err := c.ShouldBindQuery(&req); err != nil {
+			c.JSON(http.StatusBadRequest, map[string]string{
+				"error": fmt.Sprintf("cannot parse request: %v", err),
+			})
+			return
+		}
+
+		if req.Provider == "" {
+			c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "provider is required",
+			})
+			return
+		}
+
+		at := time.Now()
+		if req.At != "" {
+			var err error
+			if at, err = time.Parse(time.RFC3339, req.At); err != nil {
+				c.JSON(http.StatusBadRequest, map[string]string{
+					"error": fmt.Sprintf("cannot parse at: %v", err),
+				})
+				return
+			}
+		}
+
+		s, err := store.At(c.Copy(), req.Provider, at)
+		if err != nil {
+			writeSnapshotError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, s)
+	}
+}
+
+func writeSnapshotError(c *gin.Context, err error) {
+	if errors.Is(err, snapshots.ErrNotFound) {
+		c.JSON(http.StatusNotFound, map[string]string{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	logError(c, "getting snapshot failed", err)
+	c.JSON(http.StatusInternalServerError, map[string]string{
+		"error": "getting snapshot failed",
+	})
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/IAmRadek/gorate/internal/rates"
+	"github.com/Rhymond/go-money"
+	"github.com/gin-gonic/gin"
+)
+
+func HandleTimeSeries(series *rates.TimeSeries) gin.HandlerFunc {
+	type request struct {
+		Currencies string `form:"currencies"`
+		Start      string `form:"start"`
+		End        string `form:"end"`
+	}
+
+	type rate struct {
+		From string      `json:"from"`
+		To   string      `json:"to"`
+		Rate jsonDecimal `json:"rate"`
+	}
+
+	type day struct {
+		Date  string `json:"date"`
+		Rates []rate `json:"rates"`
+	}
+
+	type response struct {
+		Start string   `json:"start"`
+		End   string   `json:"end"`
+		Days  []day    `json:"days"`
+		Gaps  []string `json:"gaps"`
+	}
+
+	return func(c *gin.Context) {
+		var req request
+
+		if err := c.ShouldBindQuery(&req); err != nil {
+			c.JSON(http.StatusBadRequest, map[string]string{
+				"error": fmt.Sprintf("cannot parse request: %v", err),
+			})
+			return
+		}
+
+		exact, err := exactNumbers(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, map[string]string{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		rawCurrencies := strings.Split(req.Currencies, ",")
+		if len(rawCurrencies) < 2 {
+			c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "at least two currencies are required",
+			})
+			return
+		}
+
+		currencies := make([]*money.Currency, 0, len(rawCurrencies))
+		for _, cur := range rawCurrencies {
+			currency := rates.GetCurrency(cur)
+			if currency == nil {
+				c.JSON(http.StatusBadRequest, map[string]string{
+					"error": fmt.Sprintf("unknown currency %q", cur),
+				})
+				return
+			}
+
+			currencies = append(currencies, currency)
+		}
+
+		start, err := time.Parse(time.DateOnly, req.Start)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, map[string]string{
+				"error": fmt.Sprintf("cannot parse start: %v", err),
+			})
+			return
+		}
+
+		end, err := time.Parse(time.DateOnly, req.End)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, map[string]string{
+				"error": fmt.Sprintf("cannot parse end: %v", err),
+			})
+			return
+		}
+
+		s, err := series.Series(c.Copy(), start, end, currencies[0], currencies[1], currencies[1:]...)
+		if err != nil {
+			logError(c, "getting time series failed", err)
+			c.JSON(errorStatus(err, http.StatusInternalServerError), map[string]string{
+				"error": fmt.Sprintf("getting time series failed: %v", err),
+			})
+			return
+		}
+
+		out := response{
+			Start: s.Start.Format(time.DateOnly),
+			End:   s.End.Format(time.DateOnly),
+			Days:  make([]day, 0, len(s.Days)),
+			Gaps:  make([]string, 0, len(s.Gaps)),
+		}
+
+		for _, d := range s.Days {
+			rates := make([]rate, 0, d.Rates.Len())
+			for _, r := range d.Rates.Rates() {
+				rates = append(rates, rate{From: r.From.Code, To: r.To.Code, Rate: jsonDecimal{value: r.Rate, exact: exact}})
+			}
+
+			out.Days = append(out.Days, day{Date: d.Date.Format(time.DateOnly), Rates: rates})
+		}
+
+		for _, gap := range s.Gaps {
+			out.Gaps = append(out.Gaps, gap.Format(time.DateOnly))
+		}
+
+		setNumbersContentType(c, exact)
+		c.JSON(http.StatusOK, out)
+	}
+}
@@ -0,0 +1,67 @@
+// Command oxrfake serves a fake openexchangerates.org API from bundled fixture data.
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/IAmRadek/go-kit/envconfig"
+	"github.com/IAmRadek/gorate/internal/rates/oxrfake"
+)
+
+type Config struct {
+	Addr                     string        `env:"OXRFAKE_ADDR" default:":8081"`
+	GracefulShutdownDuration time.Duration `env:"GRACEFUL_SHUTDOWN_DURATION" default:"5s"`
+}
+
+func main() {
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM, syscall.SIGINT)
+	defer cancel()
+
+	log := slog.Default()
+
+	var cfg Config
+	err := envconfig.Read(&cfg, os.LookupEnv)
+	if err != nil {
+		fatal("reading config: %v", err)
+	}
+
+	httpSrv := &http.Server{
+		Addr:              cfg.Addr,
+		Handler:           oxrfake.New(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	log.Info("Starting fake openexchangerates server", "addr", cfg.Addr, "base_path", oxrfake.BasePath, "app_id", oxrfake.AppID)
+
+	go func() {
+		if err := httpSrv.ListenAndServe(); err != nil {
+			if !errors.Is(err, http.ErrServerClosed) {
+				log.Error("Server Failed", "err", err)
+			}
+		}
+	}()
+
+	<-ctx.Done()
+
+	teardownCtx, cancel := context.WithTimeout(context.Background(), cfg.GracefulShutdownDuration)
+	defer cancel()
+
+	if err := httpSrv.Shutdown(teardownCtx); err != nil {
+		log.Error("Server Failed to Shutdown", "err", err)
+	}
+
+	log.Info("Server Stopped")
+}
+
+func fatal(msg string, a ...any) {
+	_, _ = fmt.Fprintf(os.Stderr, msg, a...)
+	os.Exit(-1)
+}
@@ -5,6 +5,7 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 	"testing"
 
@@ -18,6 +19,37 @@ type response struct {
 	Rate float64 `json:"rate,omitempty"`
 }
 
+func TestReadyE2E(t *testing.T) {
+	baseURL := "http://localhost:8080"
+
+	resp, err := http.Get(baseURL + "/ready")
+	if err != nil {
+		t.Logf("Pinging server error: %v", err)
+		t.Skip("Server is not running. Start the server before running this test.")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected: %d status got: %d", http.StatusOK, resp.StatusCode)
+	}
+
+	var got struct {
+		Ready     bool `json:"ready"`
+		Providers []struct {
+			Name        string `json:"name"`
+			Warm        bool   `json:"warm"`
+			RefreshedAt string `json:"refreshed_at"`
+		} `json:"providers"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+		t.Fatalf("decoding ready: %v", err)
+	}
+
+	if !got.Ready || len(got.Providers) != 1 || got.Providers[0].Name != "openexchangerates" || !got.Providers[0].Warm || got.Providers[0].RefreshedAt == "" {
+		t.Fatalf("expected warm openexchangerates rates got %+v", got)
+	}
+}
+
 func TestRatesE2E(t *testing.T) {
 	baseURL := "http://localhost:8080"
 
@@ -52,16 +84,12 @@ func TestRatesE2E(t *testing.T) {
 			requestedCurrencies: []string{"USD", "GBP", "EUR"},
 			expectedCode:        http.StatusOK,
 			expectedResponse: []response{
-				{"USD", "EUR", 0.848818},
-				{"GBP", "EUR", 1.1608418386535178},
-				{"USD", "EUR", 0.848818},
 				{"EUR", "GBP", 0.8614437959609716},
-				{"USD", "GBP", 0.731209},
-				{"USD", "GBP", 0.731209},
-				{"EUR", "USD", 1.1781088525455399},
 				{"EUR", "USD", 1.1781088525455399},
+				{"GBP", "EUR", 1.1608418386535178},
 				{"GBP", "USD", 1.3675980465229503},
-				{"GBP", "USD", 1.3675980465229503},
+				{"USD", "EUR", 0.848818},
+				{"USD", "GBP", 0.731209},
 			},
 		},
 	}
@@ -110,3 +138,356 @@ const (
 	relTol = 1e-9  // ~9 decimal places
 	absTol = 1e-12 // good down near zero
 )
+
+func TestExactNumbersE2E(t *testing.T) {
+	baseURL := "http://localhost:8080"
+
+	resp, err := http.Get(baseURL + "/rates")
+	if err != nil {
+		t.Logf("Pinging server error: %v", err)
+		t.Skip("Server is not running. Start the server before running this test.")
+	}
+	resp.Body.Close()
+
+	type exactRate struct {
+		From string `json:"from"`
+		To   string `json:"to"`
+		Rate string `json:"rate"`
+	}
+
+	tests := []struct {
+		name   string
+		path   string
+		accept string
+		want   string
+	}{
+		{name: "query", path: "/rates?currencies=USD,BTC&numbers=string", want: "0.000009104837"},
+		{name: "accept", path: "/rates?currencies=USD,BTC", accept: "application/json; numbers=string", want: "0.000009104837"},
+		{name: "exchange", path: "/exchange?from=WBTC&to=BEER&amount=1&numbers=string", want: "2317644047.135310849"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, baseURL+tc.path, nil)
+			if tc.accept != "" {
+				req.Header.Set("Accept", tc.accept)
+			}
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("calling %s: %v", tc.path, err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("expected: %d status got: %d", http.StatusOK, resp.StatusCode)
+			}
+
+			if ct := resp.Header.Get("Content-Type"); !strings.Contains(ct, "numbers=string") {
+				t.Fatalf("expected exact numbers content type got: %s", ct)
+			}
+
+			buf, _ := io.ReadAll(resp.Body)
+
+			var got string
+			if strings.HasPrefix(tc.path, "/exchange") {
+				var body struct {
+					Amount string `json:"amount"`
+				}
+				if err := json.Unmarshal(buf, &body); err != nil {
+					t.Fatalf("decoding exchange response: %v", err)
+				}
+				got = body.Amount
+			} else {
+				var body []exactRate
+				if err := json.Unmarshal(buf, &body); err != nil {
+					t.Fatalf("decoding rates response: %v", err)
+				}
+				for _, r := range body {
+					if r.From == "USD" && r.To == "BTC" {
+						got = r.Rate
+					}
+				}
+			}
+
+			if got != tc.want {
+				t.Fatalf("expected: %s got: %s", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestProvenanceE2E(t *testing.T) {
+	baseURL := "http://localhost:8080"
+
+	resp, err := http.Get(baseURL + "/rates")
+	if err != nil {
+		t.Logf("Pinging server error: %v", err)
+		t.Skip("Server is not running. Start the server before running this test.")
+	}
+	resp.Body.Close()
+
+	type provenance struct {
+		Source  string   `json:"source"`
+		AsOf    string   `json:"as_of"`
+		Derived bool     `json:"derived"`
+		Path    []string `json:"path"`
+	}
+
+	// The fixture table is timestamped 2025-07-01T12:00:00Z and GBP/EUR is derived through USD.
+	want := provenance{Source: "openexchangerates", AsOf: "2025-07-01T12:00:00Z", Derived: true, Path: []string{"GBP", "USD", "EUR"}}
+
+	t.Run("rates", func(t *testing.T) {
+		resp, err := http.Get(baseURL + "/rates?currencies=GBP,EUR&provenance=true")
+		if err != nil {
+			t.Fatalf("calling rates: %v", err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected: %d status got: %d", http.StatusOK, resp.StatusCode)
+		}
+		if asOf := resp.Header.Get("X-Rates-As-Of"); asOf != want.AsOf {
+			t.Fatalf("expected X-Rates-As-Of %s got: %s", want.AsOf, asOf)
+		}
+		// The rates are served from the ones warmed up in the background.
+		if cached := resp.Header.Get("X-Rates-Cached"); cached != "true" {
+			t.Fatalf("expected X-Rates-Cached true got: %q", cached)
+		}
+
+		var body []struct {
+			From       string            `json:"from"`
+			To         string            `json:"to"`
+			Legs       []json.RawMessage `json:"legs"`
+			Provenance provenance        `json:"provenance"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatalf("decoding rates response: %v", err)
+		}
+
+		for _, r := range body {
+			if r.From == "GBP" && r.To == "EUR" {
+				if diff := cmp.Diff(want, r.Provenance); diff != "" {
+					t.Fatalf("provenance mismatch (-want +got):\n%s", diff)
+				}
+				if len(r.Legs) != 2 {
+					t.Fatalf("expected 2 legs got: %d", len(r.Legs))
+				}
+				return
+			}
+		}
+		t.Fatalf("expected GBP/EUR rate got: %v", body)
+	})
+
+	t.Run("rates_without_provenance", func(t *testing.T) {
+		resp, err := http.Get(baseURL + "/rates?currencies=GBP,EUR")
+		if err != nil {
+			t.Fatalf("calling rates: %v", err)
+		}
+		defer resp.Body.Close()
+
+		var body []map[string]any
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatalf("decoding rates response: %v", err)
+		}
+
+		for _, r := range body {
+			if _, ok := r["legs"]; ok {
+				t.Fatalf("expected no legs without provenance got: %v", r)
+			}
+			if _, ok := r["provenance"]; ok {
+				t.Fatalf("expected no provenance got: %v", r)
+			}
+		}
+	})
+
+	t.Run("exchange", func(t *testing.T) {
+		resp, err := http.Get(baseURL + "/exchange?from=GBP&to=EUR&amount=100&provenance=true")
+		if err != nil {
+			t.Fatalf("calling exchange: %v", err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected: %d status got: %d", http.StatusOK, resp.StatusCode)
+		}
+		if asOf := resp.Header.Get("X-Rates-As-Of"); asOf != want.AsOf {
+			t.Fatalf("expected X-Rates-As-Of %s got: %s", want.AsOf, asOf)
+		}
+
+		var body struct {
+			RateLegs       []json.RawMessage `json:"rate_legs"`
+			RateProvenance provenance        `json:"rate_provenance"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatalf("decoding exchange response: %v", err)
+		}
+
+		if diff := cmp.Diff(want, body.RateProvenance); diff != "" {
+			t.Fatalf("provenance mismatch (-want +got):\n%s", diff)
+		}
+		if len(body.RateLegs) != 2 {
+			t.Fatalf("expected 2 rate legs got: %d", len(body.RateLegs))
+		}
+	})
+}
+
+func TestQuotesE2E(t *testing.T) {
+	baseURL := "http://localhost:8080"
+
+	resp, err := http.Get(baseURL + "/rates")
+	if err != nil {
+		t.Logf("Pinging server error: %v", err)
+		t.Skip("Server is not running. Start the server before running this test.")
+	}
+	resp.Body.Close()
+
+	type quote struct {
+		ID         string `json:"id"`
+		NetAmount  string `json:"net_amount"`
+		ExpiresAt  string `json:"expires_at"`
+		ExecutedAt string `json:"executed_at"`
+	}
+
+	post := func(t *testing.T, path, body string) (int, quote) {
+		t.Helper()
+
+		resp, err := http.Post(baseURL+path, "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("calling %s: %v", path, err)
+		}
+		defer resp.Body.Close()
+
+		var q quote
+		buf, _ := io.ReadAll(resp.Body)
+		_ = json.Unmarshal(buf, &q)
+
+		return resp.StatusCode, q
+	}
+
+	code, created := post(t, "/quotes?numbers=string", `{"from": "WBTC", "to": "BEER", "amount": "1"}`)
+	if code != http.StatusCreated {
+		t.Fatalf("expected: %d status got: %d", http.StatusCreated, code)
+	}
+	if created.ID == "" || created.ExpiresAt == "" || created.ExecutedAt != "" {
+		t.Fatalf("unexpected quote: %+v", created)
+	}
+	if created.NetAmount != "2317644047.135310849" {
+		t.Fatalf("expected: 2317644047.135310849 got: %s", created.NetAmount)
+	}
+
+	code, executed := post(t, "/quotes/"+created.ID+"/execute?numbers=string", "")
+	if code != http.StatusOK {
+		t.Fatalf("expected: %d status got: %d", http.StatusOK, code)
+	}
+	if executed.NetAmount != created.NetAmount || executed.ExecutedAt == "" {
+		t.Fatalf("unexpected executed quote: %+v", executed)
+	}
+
+	if code, _ := post(t, "/quotes/"+created.ID+"/execute", ""); code != http.StatusConflict {
+		t.Fatalf("expected: %d status got: %d", http.StatusConflict, code)
+	}
+	if code, _ := post(t, "/quotes/missing/execute", ""); code != http.StatusNotFound {
+		t.Fatalf("expected: %d status got: %d", http.StatusNotFound, code)
+	}
+	if code, _ := post(t, "/quotes", `{"from": "BEER", "to": "WBTC", "amount": "-1"}`); code != http.StatusBadRequest {
+		t.Fatalf("expected: %d status got: %d", http.StatusBadRequest, code)
+	}
+}
+
+func TestAdminCryptoRatesE2E(t *testing.T) {
+	baseURL := "http://localhost:8080"
+
+	resp, err := http.Get(baseURL + "/rates")
+	if err != nil {
+		t.Logf("Pinging server error: %v", err)
+		t.Skip("Server is not running. Start the server before running this test.")
+	}
+	resp.Body.Close()
+
+	type version struct {
+		Version int    `json:"version"`
+		Action  string `json:"action"`
+		Author  string `json:"author"`
+	}
+
+	call := func(t *testing.T, method, path, key, body string) (int, version) {
+		t.Helper()
+
+		req, _ := http.NewRequest(method, baseURL+path, strings.NewReader(body))
+		if key != "" {
+			req.Header.Set("Authorization", "Bearer "+key)
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("calling %s: %v", path, err)
+		}
+		defer resp.Body.Close()
+
+		var v version
+		buf, _ := io.ReadAll(resp.Body)
+		_ = json.Unmarshal(buf, &v)
+
+		return resp.StatusCode, v
+	}
+
+	exchange := func(t *testing.T, path string) (int, string) {
+		t.Helper()
+
+		resp, err := http.Get(baseURL + path)
+		if err != nil {
+			t.Fatalf("calling %s: %v", path, err)
+		}
+		defer resp.Body.Close()
+
+		var body struct {
+			Amount string `json:"amount"`
+		}
+		buf, _ := io.ReadAll(resp.Body)
+		_ = json.Unmarshal(buf, &body)
+
+		return resp.StatusCode, body.Amount
+	}
+
+	const key = "e2e-admin-key"
+
+	code, current := call(t, http.MethodGet, "/admin/crypto-rates", key, "")
+	if code == http.StatusNotFound {
+		t.Skip("Admin API is not enabled. Start the server with ADMIN_API_KEYS=e2e:e2e-admin-key.")
+	}
+	if code != http.StatusOK {
+		t.Fatalf("expected: %d status got: %d", http.StatusOK, code)
+	}
+
+	if code, _ := call(t, http.MethodGet, "/admin/crypto-rates", "", ""); code != http.StatusUnauthorized {
+		t.Fatalf("expected: %d status got: %d", http.StatusUnauthorized, code)
+	}
+
+	code, listed := call(t, http.MethodPost, "/admin/crypto-rates", key,
+		`{"code": "EETOK", "decimals": 4, "usd": "2.5", "reason": "e2e"}`)
+	if code != http.StatusCreated {
+		t.Fatalf("expected: %d status got: %d", http.StatusCreated, code)
+	}
+	if listed.Version != current.Version+1 || listed.Action != "list" || listed.Author != "e2e" {
+		t.Fatalf("unexpected version: %+v", listed)
+	}
+
+	if code, amount := exchange(t, "/exchange?from=EETOK&to=USD&amount=3&numbers=string"); code != http.StatusOK || amount != "7.50" {
+		t.Fatalf("expected: 7.50 got: %d %s", code, amount)
+	}
+
+	if code, _ := call(t, http.MethodPut, "/admin/crypto-rates/EETOK", key, `{"usd": "0"}`); code != http.StatusBadRequest {
+		t.Fatalf("expected: %d status got: %d", http.StatusBadRequest, code)
+	}
+
+	code, _ = call(t, http.MethodPost, "/admin/crypto-rates/rollback", key,
+		`{"version": `+strconv.Itoa(current.Version)+`, "reason": "e2e cleanup"}`)
+	if code != http.StatusOK {
+		t.Fatalf("expected: %d status got: %d", http.StatusOK, code)
+	}
+
+	if code, _ := exchange(t, "/exchange?from=EETOK&to=USD&amount=3"); code != http.StatusBadRequest {
+		t.Fatalf("expected: %d status got: %d", http.StatusBadRequest, code)
+	}
+}
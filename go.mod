@@ -8,6 +8,7 @@ require (
 	github.com/gin-gonic/gin v1.10.1
 	github.com/google/go-cmp v0.6.0
 	github.com/govalues/decimal v0.1.36
+	golang.org/x/sync v0.10.0
 )
 
 require (
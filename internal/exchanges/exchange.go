@@ -3,6 +3,7 @@ package exchanges
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/IAmRadek/gorate/internal/rates"
 	"github.com/Rhymond/go-money"
@@ -11,11 +12,18 @@ import (
 
 type Exchange struct {
 	provider rates.Provider
+	fees     FeePolicy
 }
 
-func NewExchange(prov rates.Provider) *Exchange {
+// NewExchange returns an exchange converting with rates of prov and charging according to fees, nothing if it is nil.
+func NewExchange(prov rates.Provider, fees FeePolicy) *Exchange {
+	if fees == nil {
+		fees = NoFees{}
+	}
+
 	return &Exchange{
 		provider: prov,
+		fees:     fees,
 	}
 }
 
@@ -33,27 +41,214 @@ func (ex *Exchange) SupportedCurrencies(ctx context.Context) ([]string, error) {
 	return out, err
 }
 
-func (ex *Exchange) Exchange(ctx context.Context, from, to *money.Currency, amount decimal.Decimal) (*money.Money, error) {
-	rates, err := ex.provider.Rates(ctx, from, to)
+// Result is the outcome of a conversion.
+type Result struct {
+	From *money.Currency
+	To   *money.Currency
+
+	// Amount is the converted amount of From.
+	Amount decimal.Decimal
+
+	// Rate is the rate the amount was converted with, at its sell side: the bid for two-sided rates.
+	Rate rates.ExchangeRate
+
+	// Gross is Amount converted with the sell side of Rate, before the spread and fees, rounded to the minor unit of To.
+	Gross decimal.Decimal
+
+	// Spread is the amount of To kept as the spread, rounded up to the minor unit of To.
+	Spread decimal.Decimal
+
+	// Fees are the fees charged in To, each rounded up to the minor unit of To.
+	Fees []Fee
+
+	// Unrounded is the exact net amount of To, up to the 19 significant digits a decimal holds.
+	Unrounded decimal.Decimal
+
+	// Converted is the net amount of To delivered: Gross less Spread and Fees.
+	Converted decimal.Decimal
+
+	// Rounding is the rounding mode Gross was rounded with.
+	Rounding Rounding
+}
+
+// TotalFees returns the sum of Fees.
+func (r *Result) TotalFees() (decimal.Decimal, error) {
+	total := decimal.Zero
+	for _, fee := range r.Fees {
+		var err error
+		if total, err = total.Add(fee.Amount); err != nil {
+			return decimal.Decimal{}, fmt.Errorf("adding fees: %w", err)
+		}
+	}
+
+	return total.Pad(r.To.Fraction), nil
+}
+
+// Option configures a single conversion.
+type Option func(*options)
+
+type options struct {
+	rounding Rounding
+}
+
+// WithRounding sets how the converted amount is rounded to the minor unit of the target currency, RoundDown by default.
+func WithRounding(r Rounding) Option {
+	return func(o *options) {
+		o.rounding = r
+	}
+}
+
+// Exchange converts amount of from to to with the current rates, less the fees and spread charged on it.
+func (ex *Exchange) Exchange(ctx context.Context, from, to *money.Currency, amount decimal.Decimal, opts ...Option) (*Result, error) {
+	exchangeRates, charges, err := ex.rates(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	return convert(exchangeRates, from, to, amount, charges, opts)
+}
+
+// ExchangeAt is like Exchange, but uses rates as of date. It requires the provider to be a rates.HistoricalProvider.
+func (ex *Exchange) ExchangeAt(ctx context.Context, date time.Time, from, to *money.Currency, amount decimal.Decimal, opts ...Option) (*Result, error) {
+	exchangeRates, charges, err := ex.ratesAt(ctx, date, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	return convert(exchangeRates, from, to, amount, charges, opts)
+}
+
+// ExchangeFor converts the least amount of from that delivers at least receive of to, after the fees and spread
+// charged on it, with the current rates. The converted amount of the result is receive or, when no amount of from
+// converts to exactly receive at the precision of both currencies, the least amount above it.
+func (ex *Exchange) ExchangeFor(ctx context.Context, from, to *money.Currency, receive decimal.Decimal, opts ...Option) (*Result, error) {
+	exchangeRates, charges, err := ex.rates(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	return convertFor(exchangeRates, from, to, receive, charges, opts)
+}
+
+// ExchangeForAt is like ExchangeFor, but uses rates as of date. It requires the provider to be a rates.HistoricalProvider.
+func (ex *Exchange) ExchangeForAt(ctx context.Context, date time.Time, from, to *money.Currency, receive decimal.Decimal, opts ...Option) (*Result, error) {
+	exchangeRates, charges, err := ex.ratesAt(ctx, date, from, to)
 	if err != nil {
-		return nil, fmt.Errorf("getting rates for %q and %q: %w", from.Code, to.Code, err)
+		return nil, err
 	}
 
-	rate, found := rates.For(from, to)
+	return convertFor(exchangeRates, from, to, receive, charges, opts)
+}
+
+// rates returns the charges on converting from to to and the current rates needed to compute them.
+func (ex *Exchange) rates(ctx context.Context, from, to *money.Currency) (*rates.Matrix, Charges, error) {
+	charges, err := ex.fees.Charges(ctx, from, to)
+	if err != nil {
+		return nil, Charges{}, fmt.Errorf("getting charges for %q and %q: %w", from.Code, to.Code, err)
+	}
+
+	exchangeRates, err := ex.provider.Rates(ctx, from, to, charges.currencies(from, to)...)
+	if err != nil {
+		return nil, Charges{}, fmt.Errorf("getting rates for %q and %q: %w", from.Code, to.Code, err)
+	}
+
+	return exchangeRates, charges, nil
+}
+
+// ratesAt is like rates, but returns rates as of date.
+func (ex *Exchange) ratesAt(ctx context.Context, date time.Time, from, to *money.Currency) (*rates.Matrix, Charges, error) {
+	hp, ok := ex.provider.(rates.HistoricalProvider)
+	if !ok {
+		return nil, Charges{}, fmt.Errorf("getting rates at %s: %w", date.Format(time.DateOnly), rates.ErrNoHistory)
+	}
+
+	charges, err := ex.fees.Charges(ctx, from, to)
+	if err != nil {
+		return nil, Charges{}, fmt.Errorf("getting charges for %q and %q: %w", from.Code, to.Code, err)
+	}
+
+	exchangeRates, err := hp.RatesAt(ctx, date, from, to, charges.currencies(from, to)...)
+	if err != nil {
+		return nil, Charges{}, fmt.Errorf("getting rates at %s for %q and %q: %w", date.Format(time.DateOnly), from.Code, to.Code, err)
+	}
+
+	return exchangeRates, charges, nil
+}
+
+// convert computes the amount in decimals, money.Money cannot hold amounts of currencies with 18 decimal places.
+// Every charge is rounded up to the minor unit of to, so the itemized amounts add up to the net amount exactly.
+func convert(exchangeRates *rates.Matrix, from, to *money.Currency, amount decimal.Decimal, charges Charges, opts []Option) (*Result, error) {
+	o := options{rounding: RoundDown}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	rate, found := exchangeRates.For(from, to)
 	if !found {
-		return nil, fmt.Errorf("rate for %q and %q is not possible", from.Code, to.Code)
+		return nil, fmt.Errorf("rate for %q and %q is not possible: %w", from.Code, to.Code, rates.ErrUnsupportedCurrency)
 	}
 
-	// NOTE: in here we could also insert an external component for adding additional fees etc.
-	newAmount, err := amount.Mul(rate.Rate)
+	// Converting sells from for to, so two-sided rates convert at their bid.
+	exactGross, err := amount.Mul(rate.SellRate())
 	if err != nil {
 		return nil, fmt.Errorf("calculating new amount: %w", err)
 	}
 
-	fl, ok := newAmount.Float64()
-	if !ok {
-		return nil, fmt.Errorf("invalid floating point: %q", newAmount.String())
+	res := &Result{
+		From:     from,
+		To:       to,
+		Amount:   amount,
+		Rate:     rate,
+		Rounding: o.rounding,
+	}
+
+	if res.Gross, err = o.rounding.Round(exactGross, to.Fraction); err != nil {
+		return nil, fmt.Errorf("rounding new amount: %w", err)
+	}
+
+	c := charger{rates: exchangeRates, to: to, charges: charges}
+
+	exactSpread, err := exactGross.Mul(charges.Spread)
+	if err != nil {
+		return nil, fmt.Errorf("calculating spread: %w", err)
+	}
+	if res.Spread, err = c.roundUp(exactSpread); err != nil {
+		return nil, err
+	}
+
+	afterSpread, err := exactGross.Sub(exactSpread)
+	if err != nil {
+		return nil, fmt.Errorf("calculating spread: %w", err)
+	}
+
+	exactFees, err := c.fees(afterSpread)
+	if err != nil {
+		return nil, err
+	}
+
+	res.Unrounded = afterSpread
+	if res.Converted, err = res.Gross.Sub(res.Spread); err != nil {
+		return nil, fmt.Errorf("calculating net amount: %w", err)
+	}
+	for _, fee := range exactFees {
+		if res.Unrounded, err = res.Unrounded.Sub(fee.Amount); err != nil {
+			return nil, fmt.Errorf("calculating net amount: %w", err)
+		}
+
+		rounded, err := c.roundUp(fee.Amount)
+		if err != nil {
+			return nil, err
+		}
+		res.Fees = append(res.Fees, Fee{Kind: fee.Kind, Amount: rounded})
+
+		if res.Converted, err = res.Converted.Sub(rounded); err != nil {
+			return nil, fmt.Errorf("calculating net amount: %w", err)
+		}
+	}
+
+	if res.Converted.Sign() <= 0 {
+		return nil, fmt.Errorf("converting %s %s leaves %s %s: %w", amount, from.Code, res.Converted, to.Code, ErrAmountTooSmall)
 	}
 
-	return money.NewFromFloat(fl, to.Code), nil
+	return res, nil
 }
@@ -1,6 +1,7 @@
 package exchanges
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/IAmRadek/gorate/internal/rates"
@@ -9,7 +10,7 @@ import (
 )
 
 func TestExchange(t *testing.T) {
-	exch := NewExchange(rates.NewStaticRatesProvider())
+	exch := NewExchange(rates.NewStaticRatesProvider(), nil)
 
 	usd := money.GetCurrency("USD")
 	btc := money.GetCurrency("BTC")
@@ -19,7 +20,98 @@ func TestExchange(t *testing.T) {
 		return
 	}
 
-	if ex.IsZero() {
+	if ex.Converted.IsZero() {
 		t.Fatalf("Expected exchanged to be non zero")
 	}
 }
+
+func TestExchangeResult(t *testing.T) {
+	exch := NewExchange(rates.NewFixedCryptoRatesProvider(), nil)
+
+	beer, wbtc := rates.GetCurrency("BEER"), rates.GetCurrency("WBTC")
+
+	res, err := exch.Exchange(t.Context(), beer, wbtc, decimal.MustParse("3000000000"), WithRounding(RoundHalfEven))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	if res.Unrounded.String() != "1.294417925700000000" {
+		t.Fatalf("Expected unrounded amount 1.294417925700000000 got %s", res.Unrounded)
+	}
+	if res.Converted.String() != "1.29441793" {
+		t.Fatalf("Expected amount rounded to WBTC precision 1.29441793 got %s", res.Converted)
+	}
+	if res.Rounding != RoundHalfEven {
+		t.Fatalf("Expected rounding %q got %q", RoundHalfEven, res.Rounding)
+	}
+
+	res, err = exch.Exchange(t.Context(), beer, wbtc, decimal.MustParse("3000000000"))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if res.Converted.String() != "1.29441792" || res.Rounding != RoundDown {
+		t.Fatalf("Expected amount rounded down by default got %s (%s)", res.Converted, res.Rounding)
+	}
+}
+
+func TestExchangeBidAsk(t *testing.T) {
+	usd, pln := money.GetCurrency("USD"), money.GetCurrency("PLN")
+
+	usdPLN := rates.ExchangeRate{
+		From: usd,
+		To:   pln,
+		Rate: decimal.MustParse("3.6019"),
+		Bid:  decimal.MustParse("3.5659"),
+		Ask:  decimal.MustParse("3.6379"),
+	}
+	plnUSD, err := usdPLN.Invert()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	fees, err := ReadFeeRules(strings.NewReader(`{"default": {"fixed": "1", "fee_currency": "USD"}}`))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		fees     FeePolicy
+		from, to *money.Currency
+		amount   string
+		want     string
+	}{
+		// Selling USD for PLN converts at the bid.
+		{name: "sell_at_bid", from: usd, to: pln, amount: "100", want: "356.59"},
+		// Selling PLN is buying USD, at the inverted ask.
+		{name: "buy_at_ask", from: pln, to: usd, amount: "100", want: "27.48"},
+		// The 1 USD fee takes the PLN buying 1 USD costs, at the ask.
+		{name: "fee_at_ask", fees: fees, from: usd, to: pln, amount: "100", want: "352.95"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exch := NewExchange(pairRates{usdPLN, plnUSD}, tt.fees)
+
+			res, err := exch.Exchange(t.Context(), tt.from, tt.to, decimal.MustParse(tt.amount))
+			if err != nil {
+				t.Fatalf("err: %v", err)
+			}
+			if res.Converted.String() != tt.want {
+				t.Fatalf("Expected %s %s got %s", tt.want, tt.to.Code, res.Converted)
+			}
+		})
+	}
+
+	t.Run("reverse_at_bid", func(t *testing.T) {
+		exch := NewExchange(pairRates{usdPLN, plnUSD}, nil)
+
+		res, err := exch.ExchangeFor(t.Context(), usd, pln, decimal.MustParse("356.59"))
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if !res.Amount.Equal(decimal.MustParse("100")) {
+			t.Fatalf("Expected to send 100 USD got %s", res.Amount)
+		}
+	})
+}
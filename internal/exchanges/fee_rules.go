@@ -0,0 +1,203 @@
+package exchanges
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/IAmRadek/gorate/internal/rates"
+	"github.com/Rhymond/go-money"
+	"github.com/govalues/decimal"
+)
+
+// FeeRule configures the charges on conversions it applies to.
+type FeeRule struct {
+	// Percent is the fee charged as a fraction of the converted amount, e.g. "0.01" for 1%.
+	Percent decimal.Decimal `json:"percent"`
+
+	// Fixed is a flat fee in FeeCurrency.
+	Fixed decimal.Decimal `json:"fixed"`
+
+	// Min is the least total fee in FeeCurrency.
+	Min decimal.Decimal `json:"min"`
+
+	// FeeCurrency is the currency of Fixed and Min, the target currency of the conversion when empty.
+	FeeCurrency string `json:"fee_currency"`
+
+	// Spread is the fraction of the converted amount kept as margin, in both directions.
+	Spread decimal.Decimal `json:"spread"`
+}
+
+// PairFeeRule configures the charges on conversions between a pair of currencies, with a spread for each direction.
+type PairFeeRule struct {
+	FeeRule
+
+	// BuySpread replaces Spread when the base currency of the pair is bought, that is converted to.
+	BuySpread decimal.Decimal `json:"buy_spread"`
+
+	// SellSpread replaces Spread when the base currency of the pair is sold, that is converted from.
+	SellSpread decimal.Decimal `json:"sell_spread"`
+}
+
+// FeeRules is the configuration of RuleFeePolicy, e.g.
+//
+//	{
+//	  "default": {"percent": "0.01", "min": "1", "fee_currency": "USD"},
+//	  "currencies": {"BEER": {"percent": "0.02"}},
+//	  "pairs": {"WBTC/USDT": {"percent": "0.005", "buy_spread": "0.002", "sell_spread": "0.003"}}
+//	}
+type FeeRules struct {
+	// Default applies to conversions no other rule applies to.
+	Default FeeRule `json:"default"`
+
+	// Currencies hold rules by currency code, applying to conversions to the currency or, with lower precedence, from it.
+	Currencies map[string]FeeRule `json:"currencies"`
+
+	// Pairs hold rules by pair written as BASE/QUOTE, e.g. "WBTC/USDT", applying to conversions in both directions.
+	Pairs map[string]PairFeeRule `json:"pairs"`
+}
+
+// RuleFeePolicy charges fees according to FeeRules. The rule of a pair takes precedence over the rule of the currency
+// converted to, then the currency converted from, and finally the default rule. Only the single most specific rule applies.
+type RuleFeePolicy struct {
+	rules FeeRules
+}
+
+// NewRuleFeePolicy returns a policy charging according to rules, after checking they are valid.
+func NewRuleFeePolicy(rules FeeRules) (*RuleFeePolicy, error) {
+	if err := rules.Default.validate(); err != nil {
+		return nil, fmt.Errorf("default rule: %w", err)
+	}
+
+	// Rules are looked up by the upper case codes money.Currency holds.
+	currencies := make(map[string]FeeRule, len(rules.Currencies))
+	for code, rule := range rules.Currencies {
+		currency := rates.GetCurrency(code)
+		if currency == nil {
+			return nil, fmt.Errorf("currency rule %q: unknown currency", code)
+		}
+		if err := rule.validate(); err != nil {
+			return nil, fmt.Errorf("currency rule %q: %w", code, err)
+		}
+
+		currencies[currency.Code] = rule
+	}
+	rules.Currencies = currencies
+
+	pairs := make(map[string]PairFeeRule, len(rules.Pairs))
+	for pair, rule := range rules.Pairs {
+		code1, code2, ok := strings.Cut(pair, "/")
+		base, quote := rates.GetCurrency(code1), rates.GetCurrency(code2)
+		if !ok || base == nil || quote == nil {
+			return nil, fmt.Errorf("pair rule %q: expected a pair of known currencies written as BASE/QUOTE", pair)
+		}
+		if err := rule.validate(); err != nil {
+			return nil, fmt.Errorf("pair rule %q: %w", pair, err)
+		}
+		if err := validateFraction("buy_spread", rule.BuySpread); err != nil {
+			return nil, fmt.Errorf("pair rule %q: %w", pair, err)
+		}
+		if err := validateFraction("sell_spread", rule.SellSpread); err != nil {
+			return nil, fmt.Errorf("pair rule %q: %w", pair, err)
+		}
+
+		pairs[base.Code+"/"+quote.Code] = rule
+	}
+	rules.Pairs = pairs
+
+	return &RuleFeePolicy{rules: rules}, nil
+}
+
+// ReadFeeRules reads the rules of a RuleFeePolicy from JSON.
+func ReadFeeRules(r io.Reader) (*RuleFeePolicy, error) {
+	var rules FeeRules
+
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(&rules); err != nil {
+		return nil, fmt.Errorf("decoding fee rules: %w", err)
+	}
+
+	return NewRuleFeePolicy(rules)
+}
+
+// LoadFeeRules reads the rules of a RuleFeePolicy from the JSON file at path.
+func LoadFeeRules(path string) (*RuleFeePolicy, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening fee rules: %w", err)
+	}
+	defer f.Close()
+
+	return ReadFeeRules(f)
+}
+
+func (p *RuleFeePolicy) Charges(ctx context.Context, from, to *money.Currency) (Charges, error) {
+	if rule, ok := p.rules.Pairs[from.Code+"/"+to.Code]; ok {
+		return rule.charges(rule.spread(rule.SellSpread)), nil
+	}
+	if rule, ok := p.rules.Pairs[to.Code+"/"+from.Code]; ok {
+		return rule.charges(rule.spread(rule.BuySpread)), nil
+	}
+
+	if rule, ok := p.rules.Currencies[to.Code]; ok {
+		return rule.charges(rule.Spread), nil
+	}
+	if rule, ok := p.rules.Currencies[from.Code]; ok {
+		return rule.charges(rule.Spread), nil
+	}
+
+	return p.rules.Default.charges(p.rules.Default.Spread), nil
+}
+
+// spread returns the spread of one direction, falling back to the spread of both.
+func (r PairFeeRule) spread(directional decimal.Decimal) decimal.Decimal {
+	if directional.IsZero() {
+		return r.Spread
+	}
+
+	return directional
+}
+
+func (r FeeRule) charges(spread decimal.Decimal) Charges {
+	return Charges{
+		Spread:      spread,
+		Percent:     r.Percent,
+		Fixed:       r.Fixed,
+		Min:         r.Min,
+		FeeCurrency: rates.GetCurrency(r.FeeCurrency),
+	}
+}
+
+func (r FeeRule) validate() error {
+	if r.FeeCurrency != "" && rates.GetCurrency(r.FeeCurrency) == nil {
+		return fmt.Errorf("unknown fee currency %q", r.FeeCurrency)
+	}
+	if err := validateFraction("percent", r.Percent); err != nil {
+		return err
+	}
+	if err := validateFraction("spread", r.Spread); err != nil {
+		return err
+	}
+	if r.Fixed.Sign() < 0 {
+		return fmt.Errorf("fixed fee %s is negative", r.Fixed)
+	}
+	if r.Min.Sign() < 0 {
+		return fmt.Errorf("minimum fee %s is negative", r.Min)
+	}
+
+	return nil
+}
+
+// validateFraction checks d is a fraction of an amount that leaves some of it, i.e. in [0, 1).
+func validateFraction(name string, d decimal.Decimal) error {
+	if d.Sign() < 0 || d.Cmp(decimal.One) >= 0 {
+		return fmt.Errorf("%s %s is not in [0, 1)", name, d)
+	}
+
+	return nil
+}
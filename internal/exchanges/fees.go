@@ -0,0 +1,144 @@
+package exchanges
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/IAmRadek/gorate/internal/rates"
+	"github.com/Rhymond/go-money"
+	"github.com/govalues/decimal"
+)
+
+// ErrAmountTooSmall is returned when the fees and spread charged on a conversion take up the whole converted amount.
+var ErrAmountTooSmall = errors.New("amount does not cover fees")
+
+// FeePolicy decides what is charged on conversions.
+type FeePolicy interface {
+	// Charges returns the fees and spread charged on converting from to to.
+	Charges(ctx context.Context, from, to *money.Currency) (Charges, error)
+}
+
+// Charges are the fees and spread charged on a single conversion.
+type Charges struct {
+	// Spread is the fraction of the converted amount kept as margin, e.g. 0.005 gives a rate 0.5% worse than the mid rate.
+	Spread decimal.Decimal
+
+	// Percent is the fee charged as a fraction of the converted amount left after the spread, e.g. 0.01 for 1%.
+	Percent decimal.Decimal
+
+	// Fixed is a flat fee charged in FeeCurrency.
+	Fixed decimal.Decimal
+
+	// Min is the least total fee charged in FeeCurrency, the percent and fixed fees are topped up to it.
+	Min decimal.Decimal
+
+	// FeeCurrency is the currency of Fixed and Min, the target currency of the conversion when nil.
+	FeeCurrency *money.Currency
+}
+
+// FeeKind tells what a Fee is charged for.
+type FeeKind string
+
+const (
+	FeePercent FeeKind = "percent"
+	FeeFixed   FeeKind = "fixed"
+
+	// FeeMinimum tops up the other fees to the minimum fee.
+	FeeMinimum FeeKind = "minimum"
+)
+
+// Fee is a single fee charged on a conversion, in the target currency.
+type Fee struct {
+	Kind   FeeKind         `json:"kind"`
+	Amount decimal.Decimal `json:"amount"`
+}
+
+// NoFees is a FeePolicy charging nothing.
+type NoFees struct{}
+
+func (NoFees) Charges(ctx context.Context, from, to *money.Currency) (Charges, error) {
+	return Charges{}, nil
+}
+
+// currencies returns the fee currency when rates for it are needed on top of the rate between from and to.
+func (c Charges) currencies(from, to *money.Currency) []*money.Currency {
+	if c.FeeCurrency == nil || c.FeeCurrency.Code == from.Code || c.FeeCurrency.Code == to.Code {
+		return nil
+	}
+
+	return []*money.Currency{c.FeeCurrency}
+}
+
+// charger computes the fees charged on a conversion to to.
+type charger struct {
+	rates   *rates.Matrix
+	to      *money.Currency
+	charges Charges
+}
+
+// fees returns the exact fees charged on converting to amount of to, before the fees.
+func (c charger) fees(amount decimal.Decimal) ([]Fee, error) {
+	var fees []Fee
+
+	percent, err := amount.Mul(c.charges.Percent)
+	if err != nil {
+		return nil, fmt.Errorf("calculating percent fee: %w", err)
+	}
+	if !percent.IsZero() {
+		fees = append(fees, Fee{Kind: FeePercent, Amount: percent})
+	}
+
+	fixed, err := c.inTarget(c.charges.Fixed)
+	if err != nil {
+		return nil, fmt.Errorf("calculating fixed fee: %w", err)
+	}
+	if !fixed.IsZero() {
+		fees = append(fees, Fee{Kind: FeeFixed, Amount: fixed})
+	}
+
+	minimum, err := c.inTarget(c.charges.Min)
+	if err != nil {
+		return nil, fmt.Errorf("calculating minimum fee: %w", err)
+	}
+
+	total, err := percent.Add(fixed)
+	if err != nil {
+		return nil, fmt.Errorf("calculating fees: %w", err)
+	}
+
+	if minimum.Cmp(total) > 0 {
+		topUp, err := minimum.Sub(total)
+		if err != nil {
+			return nil, fmt.Errorf("calculating minimum fee: %w", err)
+		}
+		fees = append(fees, Fee{Kind: FeeMinimum, Amount: topUp})
+	}
+
+	return fees, nil
+}
+
+// inTarget converts amount of the fee currency to the target currency, at the amount of it buying the fee currency
+// takes: the ask for two-sided rates.
+func (c charger) inTarget(amount decimal.Decimal) (decimal.Decimal, error) {
+	if amount.IsZero() || c.charges.FeeCurrency == nil || c.charges.FeeCurrency.Code == c.to.Code {
+		return amount, nil
+	}
+
+	rate, ok := c.rates.For(c.charges.FeeCurrency, c.to)
+	if !ok {
+		return decimal.Decimal{}, fmt.Errorf("rate for %q and %q is not possible: %w", c.charges.FeeCurrency.Code, c.to.Code, rates.ErrUnsupportedCurrency)
+	}
+
+	return amount.Mul(rate.BuyRate())
+}
+
+// roundUp rounds a charge up to the minor unit of the target currency, so no charge is undercounted.
+func (c charger) roundUp(amount decimal.Decimal) (decimal.Decimal, error) {
+	rounded, err := RoundUp.Round(amount, c.to.Fraction)
+	if err != nil {
+		return decimal.Decimal{}, fmt.Errorf("rounding charge: %w", err)
+	}
+
+	return rounded, nil
+}
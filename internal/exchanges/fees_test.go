@@ -0,0 +1,145 @@
+package exchanges
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/IAmRadek/gorate/internal/rates"
+	"github.com/Rhymond/go-money"
+	"github.com/govalues/decimal"
+)
+
+// pairRates is a provider answering with the same rates whatever is asked for.
+type pairRates []rates.ExchangeRate
+
+func (p pairRates) SupportedCurrencies(ctx context.Context) ([]*money.Currency, error) {
+	return nil, nil
+}
+
+func (p pairRates) Rates(ctx context.Context, c1, c2 *money.Currency, c ...*money.Currency) (*rates.Matrix, error) {
+	return rates.NewMatrix(p...)
+}
+
+func TestExchangeFees(t *testing.T) {
+	eur, usd, pln := money.GetCurrency("EUR"), money.GetCurrency("USD"), money.GetCurrency("PLN")
+
+	provider := pairRates{
+		{From: eur, To: usd, Rate: decimal.MustParse("1.1")},
+		{From: usd, To: eur, Rate: decimal.MustParse("0.9")},
+		{From: pln, To: usd, Rate: decimal.MustParse("0.27")},
+		{From: pln, To: eur, Rate: decimal.MustParse("0.25")},
+	}
+
+	policy, err := ReadFeeRules(strings.NewReader(`{
+		"default": {"percent": "0.01", "fixed": "2", "fee_currency": "PLN", "min": "10"},
+		"currencies": {"usd": {"percent": "0.02", "spread": "0.001"}},
+		"pairs": {"EUR/USD": {"percent": "0.005", "buy_spread": "0.002", "sell_spread": "0.003"}}
+	}`))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		policy    FeePolicy
+		from, to  *money.Currency
+		amount    string
+		gross     string
+		spread    string
+		fees      []Fee
+		unrounded string
+		net       string
+	}{
+		{
+			name: "no_fees", policy: NoFees{}, from: eur, to: usd, amount: "100.005",
+			gross: "110.00", spread: "0.00", unrounded: "110.0055", net: "110.00",
+		},
+		{
+			// Selling EUR of the EUR/USD pair: 0.3% spread of 110.0055, then 0.5% of the rest.
+			name: "pair_sell", policy: policy, from: eur, to: usd, amount: "100.005",
+			gross: "110.00", spread: "0.34",
+			fees:      []Fee{{Kind: FeePercent, Amount: decimal.MustParse("0.55")}},
+			unrounded: "109.1271060825", net: "109.11",
+		},
+		{
+			// Buying EUR of the EUR/USD pair.
+			name: "pair_buy", policy: policy, from: usd, to: eur, amount: "100",
+			gross: "90.00", spread: "0.18",
+			fees:      []Fee{{Kind: FeePercent, Amount: decimal.MustParse("0.45")}},
+			unrounded: "89.3709", net: "89.37",
+		},
+		{
+			name: "currency", policy: policy, from: pln, to: usd, amount: "100",
+			gross: "27.00", spread: "0.03",
+			fees:      []Fee{{Kind: FeePercent, Amount: decimal.MustParse("0.54")}},
+			unrounded: "26.43354", net: "26.43",
+		},
+		{
+			// 1% of 25 EUR plus 2 PLN is less than 10 PLN, so the fees are topped up to 10 PLN, i.e. 2.5 EUR.
+			name: "default_minimum", policy: policy, from: pln, to: eur, amount: "100",
+			gross: "25.00", spread: "0.00",
+			fees: []Fee{
+				{Kind: FeePercent, Amount: decimal.MustParse("0.25")},
+				{Kind: FeeFixed, Amount: decimal.MustParse("0.50")},
+				{Kind: FeeMinimum, Amount: decimal.MustParse("1.75")},
+			},
+			unrounded: "22.5", net: "22.50",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			exch := NewExchange(provider, tc.policy)
+
+			res, err := exch.Exchange(t.Context(), tc.from, tc.to, decimal.MustParse(tc.amount))
+			if err != nil {
+				t.Fatalf("err: %v", err)
+			}
+
+			if res.Gross.String() != tc.gross || res.Spread.String() != tc.spread || res.Converted.String() != tc.net {
+				t.Fatalf("Expected gross %s, spread %s, net %s got %s, %s, %s", tc.gross, tc.spread, tc.net, res.Gross, res.Spread, res.Converted)
+			}
+			if !res.Unrounded.Equal(decimal.MustParse(tc.unrounded)) {
+				t.Fatalf("Expected unrounded net %s got %s", tc.unrounded, res.Unrounded)
+			}
+			if len(res.Fees) != len(tc.fees) {
+				t.Fatalf("Expected fees %v got %v", tc.fees, res.Fees)
+			}
+			for i, fee := range tc.fees {
+				if res.Fees[i].Kind != fee.Kind || !res.Fees[i].Amount.Equal(fee.Amount) {
+					t.Fatalf("Expected fees %v got %v", tc.fees, res.Fees)
+				}
+			}
+		})
+	}
+
+	t.Run("amount_too_small", func(t *testing.T) {
+		exch := NewExchange(provider, policy)
+
+		if _, err := exch.Exchange(t.Context(), pln, eur, decimal.MustParse("1")); !errors.Is(err, ErrAmountTooSmall) {
+			t.Fatalf("Expected %v got %v", ErrAmountTooSmall, err)
+		}
+	})
+}
+
+func TestReadFeeRulesInvalid(t *testing.T) {
+	tests := map[string]string{
+		"percent_of_whole": `{"default": {"percent": "1"}}`,
+		"negative_fixed":   `{"default": {"fixed": "-1"}}`,
+		"unknown_currency": `{"currencies": {"XYZ1": {}}}`,
+		"unknown_fee":      `{"default": {"fee_currency": "XYZ1"}}`,
+		"malformed_pair":   `{"pairs": {"EURUSD": {}}}`,
+		"pair_spread":      `{"pairs": {"EUR/USD": {"sell_spread": "1.5"}}}`,
+		"unknown_field":    `{"default": {"percentage": "0.01"}}`,
+	}
+
+	for name, rules := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := ReadFeeRules(strings.NewReader(rules)); err == nil {
+				t.Fatalf("Expected error for %s", rules)
+			}
+		})
+	}
+}
@@ -0,0 +1,201 @@
+package exchanges
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/IAmRadek/gorate/internal/rates"
+	"github.com/Rhymond/go-money"
+	"github.com/govalues/decimal"
+)
+
+// maxReverseSteps bounds the steps convertFor takes up from its estimate, rounding the charges costs a few at most.
+const maxReverseSteps = 32
+
+// convertFor finds the amount of from whose conversion delivers at least receive of to. It estimates the amount from
+// the rate and charges, steps up from the estimate until the rounded charges are covered as well, then searches down
+// for an amount delivering receive while one minor unit less does not. Amounts are only ever checked by converting
+// them forward, so the result never under-delivers.
+func convertFor(exchangeRates *rates.Matrix, from, to *money.Currency, receive decimal.Decimal, charges Charges, opts []Option) (*Result, error) {
+	if receive.Sign() <= 0 {
+		return nil, fmt.Errorf("receiving %s %s: amount is not positive", receive, to.Code)
+	}
+
+	rate, found := exchangeRates.For(from, to)
+	if !found {
+		return nil, fmt.Errorf("rate for %q and %q is not possible: %w", from.Code, to.Code, rates.ErrUnsupportedCurrency)
+	}
+	if rate.Rate.Sign() <= 0 {
+		return nil, fmt.Errorf("rate for %q and %q is %s: %w", from.Code, to.Code, rate.Rate, rates.ErrUnsupportedCurrency)
+	}
+
+	perUnit, net, err := netRates(rate.SellRate(), charges)
+	if err != nil {
+		return nil, err
+	}
+
+	estimate, err := estimateAmount(exchangeRates, to, receive, charges, perUnit, net)
+	if err != nil {
+		return nil, err
+	}
+
+	delivers := func(amount decimal.Decimal) (*Result, bool, error) {
+		res, err := convert(exchangeRates, from, to, amount, charges, opts)
+		if errors.Is(err, ErrAmountTooSmall) {
+			return nil, false, nil
+		}
+		if err != nil {
+			return nil, false, err
+		}
+
+		return res, res.Converted.Cmp(receive) >= 0, nil
+	}
+
+	scale := amountScale(estimate, from)
+	ulp, err := decimal.New(1, scale)
+	if err != nil {
+		return nil, fmt.Errorf("calculating amount: %w", err)
+	}
+
+	amount := estimate.Ceil(scale)
+
+	res, ok, err := delivers(amount)
+	if err != nil {
+		return nil, err
+	}
+
+	for step := 0; !ok; step++ {
+		if step == maxReverseSteps {
+			return nil, fmt.Errorf("no amount of %s found delivering %s %s", from.Code, receive, to.Code)
+		}
+
+		shortfall := receive
+		if res != nil {
+			if shortfall, err = receive.Sub(res.Converted); err != nil {
+				return nil, fmt.Errorf("calculating shortfall: %w", err)
+			}
+		}
+
+		more, err := shortfall.Quo(net)
+		if err != nil {
+			return nil, fmt.Errorf("calculating shortfall: %w", err)
+		}
+		if amount, err = amount.Add(more.Ceil(scale).Max(ulp)); err != nil {
+			return nil, fmt.Errorf("calculating amount: %w", err)
+		}
+
+		if res, ok, err = delivers(amount); err != nil {
+			return nil, err
+		}
+	}
+
+	// The estimate is usually the least amount delivering receive already, otherwise search down for it.
+	lo := decimal.Zero
+	below, err := amount.Sub(ulp)
+	if err != nil {
+		return nil, fmt.Errorf("calculating amount: %w", err)
+	}
+	if below.Sign() > 0 {
+		belowRes, ok, err := delivers(below)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			amount, res = below, belowRes
+		} else {
+			lo = below
+		}
+	}
+
+	for {
+		gap, err := amount.Sub(lo)
+		if err != nil {
+			return nil, fmt.Errorf("calculating amount: %w", err)
+		}
+		if gap.Cmp(ulp) <= 0 {
+			return res, nil
+		}
+
+		// Halving the gap rather than the sum keeps the digits of both bounds, as the sum may not fit a decimal.
+		half, err := gap.Quo(decimal.Two)
+		if err != nil {
+			return nil, fmt.Errorf("calculating amount: %w", err)
+		}
+		mid, err := lo.Add(half.Trunc(scale))
+		if err != nil {
+			return nil, fmt.Errorf("calculating amount: %w", err)
+		}
+
+		midRes, ok, err := delivers(mid)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			amount, res = mid, midRes
+		} else {
+			lo = mid
+		}
+	}
+}
+
+// netRates returns the amount of to a unit of from converts to after the spread, and after the percent fee as well.
+func netRates(rate decimal.Decimal, charges Charges) (perUnit, net decimal.Decimal, err error) {
+	keep, err := decimal.One.Sub(charges.Spread)
+	if err != nil {
+		return decimal.Decimal{}, decimal.Decimal{}, fmt.Errorf("calculating spread: %w", err)
+	}
+	if perUnit, err = rate.Mul(keep); err != nil {
+		return decimal.Decimal{}, decimal.Decimal{}, fmt.Errorf("calculating spread: %w", err)
+	}
+
+	keep, err = decimal.One.Sub(charges.Percent)
+	if err != nil {
+		return decimal.Decimal{}, decimal.Decimal{}, fmt.Errorf("calculating percent fee: %w", err)
+	}
+	if net, err = perUnit.Mul(keep); err != nil {
+		return decimal.Decimal{}, decimal.Decimal{}, fmt.Errorf("calculating percent fee: %w", err)
+	}
+
+	return perUnit, net, nil
+}
+
+// estimateAmount returns the exact amount of from delivering receive of to before rounding. The fees charged are
+// the percent and fixed fees, or the minimum fee when it is more, so the amount has to cover both.
+func estimateAmount(exchangeRates *rates.Matrix, to *money.Currency, receive decimal.Decimal, charges Charges, perUnit, net decimal.Decimal) (decimal.Decimal, error) {
+	c := charger{rates: exchangeRates, to: to, charges: charges}
+
+	fixed, err := c.inTarget(charges.Fixed)
+	if err != nil {
+		return decimal.Decimal{}, fmt.Errorf("calculating fixed fee: %w", err)
+	}
+	minimum, err := c.inTarget(charges.Min)
+	if err != nil {
+		return decimal.Decimal{}, fmt.Errorf("calculating minimum fee: %w", err)
+	}
+
+	withFixed, err := receive.Add(fixed)
+	if err != nil {
+		return decimal.Decimal{}, fmt.Errorf("calculating amount: %w", err)
+	}
+	if withFixed, err = withFixed.Quo(net); err != nil {
+		return decimal.Decimal{}, fmt.Errorf("calculating amount: %w", err)
+	}
+
+	withMinimum, err := receive.Add(minimum)
+	if err != nil {
+		return decimal.Decimal{}, fmt.Errorf("calculating amount: %w", err)
+	}
+	if withMinimum, err = withMinimum.Quo(perUnit); err != nil {
+		return decimal.Decimal{}, fmt.Errorf("calculating amount: %w", err)
+	}
+
+	return withFixed.Max(withMinimum), nil
+}
+
+// amountScale returns the number of decimal places amounts of from around d are computed to: the minor unit of from,
+// or fewer when a decimal cannot hold amounts as large as d to the minor unit of from.
+func amountScale(d decimal.Decimal, from *money.Currency) int {
+	integer := max(d.Prec()-d.Scale(), 0)
+
+	return max(min(from.Fraction, decimal.MaxPrec-integer), 0)
+}
@@ -0,0 +1,95 @@
+package exchanges
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/IAmRadek/gorate/internal/rates"
+	"github.com/govalues/decimal"
+)
+
+func TestExchangeFor(t *testing.T) {
+	policy, err := ReadFeeRules(strings.NewReader(`{
+		"default": {"percent": "0.01", "fixed": "0.5", "min": "2", "fee_currency": "USDT", "spread": "0.002"},
+		"pairs": {"WBTC/USDT": {"percent": "0.005", "buy_spread": "0.002", "sell_spread": "0.003"}}
+	}`))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	policies := map[string]FeePolicy{"no_fees": NoFees{}, "rules": policy}
+	codes := []string{"USD", "USDT", "WBTC", "BEER", "GATE"}
+	receives := []string{"0.01", "1", "100", "12345.678901"}
+
+	for name, policy := range policies {
+		exch := NewExchange(rates.NewFixedCryptoRatesProvider(), policy)
+
+		for _, fromCode := range codes {
+			for _, toCode := range codes {
+				if fromCode == toCode {
+					continue
+				}
+				from, to := rates.GetCurrency(fromCode), rates.GetCurrency(toCode)
+
+				for _, receive := range receives {
+					t.Run(name+"/"+fromCode+"_"+toCode+"/"+receive, func(t *testing.T) {
+						want := decimal.MustParse(receive)
+
+						res, err := exch.ExchangeFor(t.Context(), from, to, want)
+						if err != nil {
+							t.Fatalf("err: %v", err)
+						}
+
+						// The amount to send delivers receive or more, converted forward.
+						forward, err := exch.Exchange(t.Context(), from, to, res.Amount)
+						if err != nil {
+							t.Fatalf("err: %v", err)
+						}
+						if forward.Converted.Cmp(want) < 0 || !forward.Converted.Equal(res.Converted) {
+							t.Fatalf("Sending %s %s delivers %s %s, expected %s and at least %s", res.Amount, fromCode, forward.Converted, toCode, res.Converted, want)
+						}
+
+						// One minor unit less falls short.
+						ulp, _ := decimal.New(1, amountScale(res.Amount, from))
+						less, _ := res.Amount.Sub(ulp)
+						if less.Sign() <= 0 {
+							return
+						}
+						short, err := exch.Exchange(t.Context(), from, to, less)
+						if err != nil && !errors.Is(err, ErrAmountTooSmall) {
+							t.Fatalf("err: %v", err)
+						}
+						if err == nil && short.Converted.Cmp(want) >= 0 {
+							t.Fatalf("Sending %s %s, less than %s, delivers %s %s already", less, fromCode, res.Amount, short.Converted, toCode)
+						}
+					})
+				}
+			}
+		}
+	}
+}
+
+func TestExchangeForRoundTrip(t *testing.T) {
+	wbtc, usdt := rates.GetCurrency("WBTC"), rates.GetCurrency("USDT")
+	exch := NewExchange(rates.NewFixedCryptoRatesProvider(), NoFees{})
+
+	for _, rounding := range []Rounding{RoundDown, RoundHalfEven, RoundUp} {
+		t.Run(string(rounding), func(t *testing.T) {
+			forward, err := exch.Exchange(t.Context(), wbtc, usdt, decimal.MustParse("0.12345678"), WithRounding(rounding))
+			if err != nil {
+				t.Fatalf("err: %v", err)
+			}
+
+			reverse, err := exch.ExchangeFor(t.Context(), wbtc, usdt, forward.Converted, WithRounding(rounding))
+			if err != nil {
+				t.Fatalf("err: %v", err)
+			}
+
+			// Asking for what an amount delivers never costs more than that amount.
+			if reverse.Amount.Cmp(forward.Amount) > 0 || !reverse.Converted.Equal(forward.Converted) {
+				t.Fatalf("Expected at most %s WBTC for %s USDT got %s WBTC for %s USDT", forward.Amount, forward.Converted, reverse.Amount, reverse.Converted)
+			}
+		})
+	}
+}
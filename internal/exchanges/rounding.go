@@ -0,0 +1,88 @@
+package exchanges
+
+import (
+	"fmt"
+
+	"github.com/govalues/decimal"
+)
+
+// Rounding selects how converted amounts are rounded to the minor unit of the target currency.
+type Rounding string
+
+const (
+	// RoundHalfEven rounds to the nearest minor unit, ties to the even one.
+	RoundHalfEven Rounding = "half_even"
+
+	// RoundHalfUp rounds to the nearest minor unit, ties away from zero.
+	RoundHalfUp Rounding = "half_up"
+
+	// RoundDown rounds toward zero. It is the default, as it never pays out more than the exact amount.
+	RoundDown Rounding = "down"
+
+	// RoundUp rounds away from zero.
+	RoundUp Rounding = "up"
+)
+
+// ParseRounding parses the name of a rounding mode, an empty name is RoundDown.
+func ParseRounding(s string) (Rounding, error) {
+	switch r := Rounding(s); r {
+	case "":
+		return RoundDown, nil
+	case RoundHalfEven, RoundHalfUp, RoundDown, RoundUp:
+		return r, nil
+	default:
+		return "", fmt.Errorf("unknown rounding %q, expected one of %q, %q, %q or %q", s, RoundHalfEven, RoundHalfUp, RoundDown, RoundUp)
+	}
+}
+
+// Round rounds d to scale digits after the decimal point, padding it with zeros to exactly scale digits.
+func (r Rounding) Round(d decimal.Decimal, scale int) (decimal.Decimal, error) {
+	if r == RoundHalfEven {
+		return d.Round(scale).Pad(scale), nil
+	}
+
+	truncated := d.Trunc(scale)
+
+	rem, err := d.Sub(truncated)
+	if err != nil {
+		return decimal.Decimal{}, fmt.Errorf("rounding %s: %w", d, err)
+	}
+	if rem.IsZero() {
+		return truncated.Pad(scale), nil
+	}
+
+	ulp, err := decimal.New(1, scale)
+	if err != nil {
+		return decimal.Decimal{}, fmt.Errorf("rounding to %d digits: %w", scale, err)
+	}
+
+	away := false
+	switch r {
+	case RoundDown:
+	case RoundUp:
+		away = true
+	case RoundHalfUp:
+		twice, err := rem.Abs().Mul(decimal.Two)
+		if err != nil {
+			return decimal.Decimal{}, fmt.Errorf("rounding %s: %w", d, err)
+		}
+		away = twice.Cmp(ulp) >= 0
+	default:
+		return decimal.Decimal{}, fmt.Errorf("unknown rounding %q", string(r))
+	}
+
+	if !away {
+		return truncated.Pad(scale), nil
+	}
+
+	if d.Sign() < 0 {
+		ulp = ulp.Neg()
+	}
+
+	rounded, err := truncated.Add(ulp)
+	if err != nil {
+		return decimal.Decimal{}, fmt.Errorf("rounding %s: %w", d, err)
+	}
+
+	return rounded.Pad(scale), nil
+}
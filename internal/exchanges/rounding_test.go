@@ -0,0 +1,49 @@
+package exchanges
+
+import (
+	"testing"
+
+	"github.com/govalues/decimal"
+)
+
+func TestRoundingRound(t *testing.T) {
+	tests := []struct {
+		amount string
+		scale  int
+		want   map[Rounding]string
+	}{
+		{amount: "1.005", scale: 2, want: map[Rounding]string{RoundHalfEven: "1.00", RoundHalfUp: "1.01", RoundDown: "1.00", RoundUp: "1.01"}},
+		{amount: "1.015", scale: 2, want: map[Rounding]string{RoundHalfEven: "1.02", RoundHalfUp: "1.02", RoundDown: "1.01", RoundUp: "1.02"}},
+		{amount: "1.0149", scale: 2, want: map[Rounding]string{RoundHalfEven: "1.01", RoundHalfUp: "1.01", RoundDown: "1.01", RoundUp: "1.02"}},
+		{amount: "-1.005", scale: 2, want: map[Rounding]string{RoundHalfEven: "-1.00", RoundHalfUp: "-1.01", RoundDown: "-1.00", RoundUp: "-1.01"}},
+		{amount: "7", scale: 2, want: map[Rounding]string{RoundHalfEven: "7.00", RoundHalfUp: "7.00", RoundDown: "7.00", RoundUp: "7.00"}},
+		{amount: "0.0000000004314726418", scale: 18, want: map[Rounding]string{RoundHalfEven: "0.000000000431472642", RoundHalfUp: "0.000000000431472642", RoundDown: "0.000000000431472641", RoundUp: "0.000000000431472642"}},
+		{amount: "2317644047.135310849", scale: 8, want: map[Rounding]string{RoundHalfEven: "2317644047.13531085", RoundHalfUp: "2317644047.13531085", RoundDown: "2317644047.13531084", RoundUp: "2317644047.13531085"}},
+	}
+
+	for _, tc := range tests {
+		for rounding, want := range tc.want {
+			t.Run(tc.amount+"/"+string(rounding), func(t *testing.T) {
+				got, err := rounding.Round(decimal.MustParse(tc.amount), tc.scale)
+				if err != nil {
+					t.Fatalf("err: %v", err)
+				}
+				if got.String() != want {
+					t.Fatalf("Expected %s got %s", want, got)
+				}
+			})
+		}
+	}
+}
+
+func TestParseRounding(t *testing.T) {
+	if r, err := ParseRounding(""); err != nil || r != RoundDown {
+		t.Fatalf("Expected default %q got %q, %v", RoundDown, r, err)
+	}
+	if r, err := ParseRounding("half_up"); err != nil || r != RoundHalfUp {
+		t.Fatalf("Expected %q got %q, %v", RoundHalfUp, r, err)
+	}
+	if _, err := ParseRounding("ceiling"); err == nil {
+		t.Fatalf("Expected error for unknown rounding")
+	}
+}
@@ -0,0 +1,130 @@
+// Package jsonlines keeps records in an append-only JSON lines file, one record per line.
+package jsonlines
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// File appends records to a JSON lines file and reads them back by key. A record appended again under the same key
+// replaces it, the last line of a key holds its current state. Only the location of every line is kept in memory.
+// File is not safe for concurrent use, its users guard it along with whatever they index next to it.
+type File[T any] struct {
+	file  *os.File
+	size  int64
+	lines map[string]line
+	key   func(*T) string
+}
+
+// line locates the current record of a key in the file.
+type line struct {
+	offset int64
+	length int
+}
+
+// Open opens the JSON lines file at path, creating it when it doesn't exist, and indexes its records by key.
+// Every record is handed to loaded in the order of the file, when it isn't nil.
+// A partially written last line, left by a crash during a write, is truncated.
+func Open[T any](path string, key func(*T) string, loaded func(*T)) (*File[T], error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("opening file: %w", err)
+	}
+
+	f := &File[T]{
+		file:  file,
+		lines: make(map[string]line),
+		key:   key,
+	}
+
+	if err := f.load(loaded); err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// load indexes the records in the file and truncates whatever follows the last complete line.
+func (f *File[T]) load(loaded func(*T)) error {
+	r := bufio.NewReader(f.file)
+
+	var offset int64
+	for {
+		buf, err := r.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("reading file: %w", err)
+		}
+
+		var v T
+		if err := json.Unmarshal(buf, &v); err != nil {
+			return fmt.Errorf("decoding line at offset %d: %w", offset, err)
+		}
+
+		f.lines[f.key(&v)] = line{offset: offset, length: len(buf)}
+		if loaded != nil {
+			loaded(&v)
+		}
+		offset += int64(len(buf))
+	}
+
+	if err := f.file.Truncate(offset); err != nil {
+		return fmt.Errorf("truncating partial line: %w", err)
+	}
+	f.size = offset
+
+	return nil
+}
+
+// Append writes v as the last line of the file and syncs it, making it the current record of its key.
+func (f *File[T]) Append(v *T) error {
+	buf, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("encoding: %w", err)
+	}
+	buf = append(buf, '\n')
+
+	if _, err := f.file.WriteAt(buf, f.size); err != nil {
+		return fmt.Errorf("writing: %w", err)
+	}
+	if err := f.file.Sync(); err != nil {
+		return fmt.Errorf("syncing file: %w", err)
+	}
+
+	f.lines[f.key(v)] = line{offset: f.size, length: len(buf)}
+	f.size += int64(len(buf))
+
+	return nil
+}
+
+// Read returns the current record of key, reporting whether there is one.
+func (f *File[T]) Read(key string) (*T, bool, error) {
+	l, ok := f.lines[key]
+	if !ok {
+		return nil, false, nil
+	}
+
+	buf := make([]byte, l.length)
+	if _, err := f.file.ReadAt(buf, l.offset); err != nil {
+		return nil, false, fmt.Errorf("reading: %w", err)
+	}
+
+	var v T
+	if err := json.Unmarshal(buf, &v); err != nil {
+		return nil, false, fmt.Errorf("decoding: %w", err)
+	}
+
+	return &v, true, nil
+}
+
+// Close closes the file.
+func (f *File[T]) Close() error {
+	return f.file.Close()
+}
@@ -0,0 +1,91 @@
+package quotes
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/IAmRadek/gorate/internal/jsonlines"
+)
+
+// FileStore appends quotes to a JSON lines file. A quote is written again when it is executed,
+// the last line of a quote holds its current state. Only an index is kept in memory.
+type FileStore struct {
+	mu   sync.Mutex
+	file *jsonlines.File[Quote]
+}
+
+// OpenFileStore opens the quotes file at path, creating it when it doesn't exist.
+// A partially written last line, left by a crash during a write, is truncated.
+func OpenFileStore(path string) (*FileStore, error) {
+	file, err := jsonlines.Open(path, func(q *Quote) string { return q.ID }, nil)
+	if err != nil {
+		return nil, fmt.Errorf("opening quotes file: %w", err)
+	}
+
+	return &FileStore{file: file}, nil
+}
+
+func (f *FileStore) Save(ctx context.Context, q *Quote) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.write(q)
+}
+
+func (f *FileStore) Get(ctx context.Context, id string) (*Quote, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.read(id)
+}
+
+func (f *FileStore) Execute(ctx context.Context, id string, t time.Time) (*Quote, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	q, err := f.read(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := q.execute(t); err != nil {
+		return nil, err
+	}
+
+	// The execution only counts once it is on disk, a crash before that leaves the quote executable.
+	if err := f.write(q); err != nil {
+		return nil, err
+	}
+
+	return q, nil
+}
+
+// Close closes the quotes file.
+func (f *FileStore) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.file.Close()
+}
+
+func (f *FileStore) write(q *Quote) error {
+	if err := f.file.Append(q); err != nil {
+		return fmt.Errorf("quote %q: %w", q.ID, err)
+	}
+
+	return nil
+}
+
+func (f *FileStore) read(id string) (*Quote, error) {
+	q, ok, err := f.file.Read(id)
+	if err != nil {
+		return nil, fmt.Errorf("quote %q: %w", id, err)
+	}
+	if !ok {
+		return nil, fmt.Errorf("%q: %w", id, ErrNotFound)
+	}
+
+	return q, nil
+}
@@ -0,0 +1,87 @@
+package quotes
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// memoryRetention is how long MemoryStore keeps a quote after it expired, so executing it fails with ErrExpired
+// rather than ErrNotFound for a while.
+const memoryRetention = time.Minute
+
+// MemoryStore keeps quotes in memory, they are lost when the process exits. Quotes are forgotten once they expired
+// more than memoryRetention before a quote saved later was created, so memory is bounded by the quotes of that time.
+type MemoryStore struct {
+	mu     sync.Mutex
+	quotes map[string]Quote
+
+	// expiring holds the quotes in the order they were saved, which is the order they expire in for quotes locked for
+	// the same time.
+	expiring []expiry
+}
+
+type expiry struct {
+	id string
+	at time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		quotes: make(map[string]Quote),
+	}
+}
+
+func (m *MemoryStore) Save(ctx context.Context, q *Quote) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.prune(q.CreatedAt)
+
+	if _, ok := m.quotes[q.ID]; !ok {
+		m.expiring = append(m.expiring, expiry{id: q.ID, at: q.ExpiresAt})
+	}
+	m.quotes[q.ID] = *q
+
+	return nil
+}
+
+// prune forgets the quotes that expired more than memoryRetention before now, in the order they were saved.
+func (m *MemoryStore) prune(now time.Time) {
+	n := 0
+	for ; n < len(m.expiring) && now.Sub(m.expiring[n].at) > memoryRetention; n++ {
+		delete(m.quotes, m.expiring[n].id)
+	}
+
+	m.expiring = m.expiring[n:]
+}
+
+func (m *MemoryStore) Get(ctx context.Context, id string) (*Quote, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	q, ok := m.quotes[id]
+	if !ok {
+		return nil, fmt.Errorf("%q: %w", id, ErrNotFound)
+	}
+
+	return &q, nil
+}
+
+func (m *MemoryStore) Execute(ctx context.Context, id string, t time.Time) (*Quote, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	q, ok := m.quotes[id]
+	if !ok {
+		return nil, fmt.Errorf("%q: %w", id, ErrNotFound)
+	}
+
+	if err := q.execute(t); err != nil {
+		return nil, err
+	}
+	m.quotes[id] = q
+
+	return &q, nil
+}
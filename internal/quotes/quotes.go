@@ -0,0 +1,171 @@
+// Package quotes locks the rate of a conversion for a short time,
+// so the amounts a customer is shown are the amounts they get when the conversion is executed.
+package quotes
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/IAmRadek/gorate/internal/exchanges"
+	"github.com/IAmRadek/gorate/internal/rates"
+	"github.com/Rhymond/go-money"
+	"github.com/govalues/decimal"
+)
+
+var (
+	// ErrNotFound is returned when no quote has the requested ID.
+	ErrNotFound = errors.New("quote not found")
+
+	// ErrExpired is returned when a quote is executed after it expired.
+	ErrExpired = errors.New("quote expired")
+
+	// ErrAlreadyExecuted is returned when a quote is executed more than once.
+	ErrAlreadyExecuted = errors.New("quote already executed")
+)
+
+// Quote is a conversion with its rate and amounts locked until ExpiresAt.
+type Quote struct {
+	ID   string `json:"id"`
+	From string `json:"from"`
+	To   string `json:"to"`
+
+	// Amount is the amount of From to convert.
+	Amount decimal.Decimal `json:"amount"`
+
+	// Rate is the locked rate From is converted to To with.
+	Rate decimal.Decimal `json:"rate"`
+
+	// RateAsOf is the time upstream says Rate was in effect at, zero when it is not known.
+	RateAsOf time.Time `json:"rate_as_of,omitzero"`
+
+	// RateFetchedAt is the time Rate was fetched from upstream, zero for rates that are not fetched.
+	RateFetchedAt time.Time `json:"rate_fetched_at,omitzero"`
+
+	// RateSource is the provider Rate came from, the providers of its legs joined with "+" for a cross rate.
+	RateSource string `json:"rate_source,omitempty"`
+
+	Rounding  exchanges.Rounding `json:"rounding"`
+	Gross     decimal.Decimal    `json:"gross_amount"`
+	Spread    decimal.Decimal    `json:"spread"`
+	Fees      []exchanges.Fee    `json:"fees"`
+	Unrounded decimal.Decimal    `json:"unrounded_amount"`
+
+	// Net is the amount of To delivered when the quote is executed.
+	Net decimal.Decimal `json:"net_amount"`
+
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+
+	// ExecutedAt is the time the quote was executed, zero until it is.
+	ExecutedAt time.Time `json:"executed_at,omitzero"`
+}
+
+// ExchangeRate returns the locked rate.
+func (q *Quote) ExchangeRate() rates.ExchangeRate {
+	return rates.ExchangeRate{
+		From:      rates.GetCurrency(q.From),
+		To:        rates.GetCurrency(q.To),
+		Rate:      q.Rate,
+		AsOf:      q.RateAsOf,
+		FetchedAt: q.RateFetchedAt,
+		Source:    q.RateSource,
+	}
+}
+
+// Executed reports whether the quote was executed.
+func (q *Quote) Executed() bool {
+	return !q.ExecutedAt.IsZero()
+}
+
+// execute marks the quote executed at t, unless it already was or expired by then.
+func (q *Quote) execute(t time.Time) error {
+	if q.Executed() {
+		return fmt.Errorf("%q at %s: %w", q.ID, q.ExecutedAt.Format(time.RFC3339), ErrAlreadyExecuted)
+	}
+	if !t.Before(q.ExpiresAt) {
+		return fmt.Errorf("%q at %s: %w", q.ID, q.ExpiresAt.Format(time.RFC3339), ErrExpired)
+	}
+
+	q.ExecutedAt = t.UTC()
+
+	return nil
+}
+
+// Store keeps quotes.
+type Store interface {
+	// Save records a new quote.
+	Save(ctx context.Context, q *Quote) error
+
+	// Get returns the quote with the given ID.
+	Get(ctx context.Context, id string) (*Quote, error)
+
+	// Execute marks the quote with the given ID executed at t and returns it. It fails with ErrExpired when the quote
+	// expired by t and with ErrAlreadyExecuted when it was executed before, so no quote is executed twice.
+	Execute(ctx context.Context, id string, t time.Time) (*Quote, error)
+}
+
+// Service quotes conversions and executes them with the quoted amounts.
+type Service struct {
+	exchange *exchanges.Exchange
+	store    Store
+	ttl      time.Duration
+	now      func() time.Time
+}
+
+// NewService returns a service quoting conversions of exchange, locked for ttl.
+func NewService(exchange *exchanges.Exchange, store Store, ttl time.Duration) *Service {
+	return &Service{
+		exchange: exchange,
+		store:    store,
+		ttl:      ttl,
+		now:      time.Now,
+	}
+}
+
+// Quote converts amount of from to to with the current rates and locks the result.
+func (s *Service) Quote(ctx context.Context, from, to *money.Currency, amount decimal.Decimal, opts ...exchanges.Option) (*Quote, error) {
+	res, err := s.exchange.Exchange(ctx, from, to, amount, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now().UTC()
+
+	q := &Quote{
+		ID:            rand.Text(),
+		From:          from.Code,
+		To:            to.Code,
+		Amount:        res.Amount,
+		Rate:          res.Rate.SellRate(),
+		RateAsOf:      res.Rate.AsOf,
+		RateFetchedAt: res.Rate.FetchedAt,
+		RateSource:    res.Rate.Source,
+		Rounding:      res.Rounding,
+		Gross:         res.Gross,
+		Spread:        res.Spread,
+		Fees:          res.Fees,
+		Unrounded:     res.Unrounded,
+		Net:           res.Converted,
+		CreatedAt:     now,
+		ExpiresAt:     now.Add(s.ttl),
+	}
+
+	if err := s.store.Save(ctx, q); err != nil {
+		return nil, fmt.Errorf("saving quote: %w", err)
+	}
+
+	return q, nil
+}
+
+// Get returns the quote with the given ID.
+func (s *Service) Get(ctx context.Context, id string) (*Quote, error) {
+	return s.store.Get(ctx, id)
+}
+
+// Execute executes the quote with the given ID at its locked rate and amounts.
+func (s *Service) Execute(ctx context.Context, id string) (*Quote, error) {
+	return s.store.Execute(ctx, id, s.now())
+}
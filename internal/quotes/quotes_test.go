@@ -0,0 +1,235 @@
+package quotes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/IAmRadek/gorate/internal/exchanges"
+	"github.com/IAmRadek/gorate/internal/rates"
+	"github.com/Rhymond/go-money"
+	"github.com/govalues/decimal"
+)
+
+var t0 = time.Date(2025, 7, 1, 12, 0, 0, 0, time.UTC)
+
+func quote(id string) *Quote {
+	return &Quote{
+		ID:        id,
+		From:      "EUR",
+		To:        "USD",
+		Amount:    decimal.MustParse("100"),
+		Rate:      decimal.MustParse("1.1000"),
+		Rounding:  exchanges.RoundDown,
+		Gross:     decimal.MustParse("110.00"),
+		Spread:    decimal.MustParse("0.00"),
+		Unrounded: decimal.MustParse("110"),
+		Net:       decimal.MustParse("110.00"),
+		CreatedAt: t0,
+		ExpiresAt: t0.Add(30 * time.Second),
+	}
+}
+
+func testStore(t *testing.T, store Store) {
+	t.Helper()
+
+	for _, id := range []string{"expiring", "executed", "raced"} {
+		if err := store.Save(t.Context(), quote(id)); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+
+	got, err := store.Get(t.Context(), "executed")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	// Amounts are kept exactly, trailing zeros included.
+	if got.Rate.String() != "1.1000" || got.Net.String() != "110.00" || got.Executed() {
+		t.Fatalf("Unexpected quote %+v", got)
+	}
+
+	if _, err := store.Execute(t.Context(), "expiring", t0.Add(30*time.Second)); !errors.Is(err, ErrExpired) {
+		t.Fatalf("Expected %v got %v", ErrExpired, err)
+	}
+
+	executed, err := store.Execute(t.Context(), "executed", t0.Add(10*time.Second))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !executed.ExecutedAt.Equal(t0.Add(10 * time.Second)) {
+		t.Fatalf("Expected executed at %v got %v", t0.Add(10*time.Second), executed.ExecutedAt)
+	}
+	if _, err := store.Execute(t.Context(), "executed", t0.Add(20*time.Second)); !errors.Is(err, ErrAlreadyExecuted) {
+		t.Fatalf("Expected %v got %v", ErrAlreadyExecuted, err)
+	}
+	if got, err := store.Get(t.Context(), "executed"); err != nil || !got.Executed() {
+		t.Fatalf("Expected executed quote got %+v, %v", got, err)
+	}
+
+	// However many requests race to execute a quote, only one of them does.
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		successes int
+	)
+	for range 16 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			_, err := store.Execute(context.Background(), "raced", t0.Add(time.Second))
+			if err != nil && !errors.Is(err, ErrAlreadyExecuted) {
+				t.Errorf("err: %v", err)
+				return
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err == nil {
+				successes++
+			}
+		}()
+	}
+	wg.Wait()
+
+	if successes != 1 {
+		t.Fatalf("Expected a single execution got %d", successes)
+	}
+
+	if _, err := store.Get(t.Context(), "missing"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Expected %v got %v", ErrNotFound, err)
+	}
+	if _, err := store.Execute(t.Context(), "missing", t0); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Expected %v got %v", ErrNotFound, err)
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	testStore(t, NewMemoryStore())
+
+	t.Run("forgets_expired", func(t *testing.T) {
+		store := NewMemoryStore()
+
+		for i := range 1000 {
+			q := quote(fmt.Sprintf("q%d", i))
+			q.CreatedAt = t0.Add(time.Duration(i) * time.Second)
+			q.ExpiresAt = q.CreatedAt.Add(30 * time.Second)
+
+			if err := store.Save(t.Context(), q); err != nil {
+				t.Fatalf("err: %v", err)
+			}
+		}
+
+		// Only the quotes that expired within memoryRetention of the last one are kept.
+		if got, want := len(store.quotes), 30+int(memoryRetention/time.Second)+1; got != want {
+			t.Fatalf("Expected %d quotes kept got %d", want, got)
+		}
+		if _, err := store.Get(t.Context(), "q0"); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("Expected %v got %v", ErrNotFound, err)
+		}
+		if _, err := store.Execute(t.Context(), "q950", t0.Add(999*time.Second)); !errors.Is(err, ErrExpired) {
+			t.Fatalf("Expected %v got %v", ErrExpired, err)
+		}
+	})
+}
+
+func TestFileStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "quotes.jsonl")
+
+	store, err := OpenFileStore(path)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	testStore(t, store)
+
+	if err := store.Close(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	reopened, err := OpenFileStore(path)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer reopened.Close()
+
+	// Executions survive a restart.
+	if _, err := reopened.Execute(t.Context(), "executed", t0.Add(time.Second)); !errors.Is(err, ErrAlreadyExecuted) {
+		t.Fatalf("Expected %v got %v", ErrAlreadyExecuted, err)
+	}
+	if got, err := reopened.Get(t.Context(), "raced"); err != nil || !got.Executed() {
+		t.Fatalf("Expected executed quote got %+v, %v", got, err)
+	}
+}
+
+// movingRates is a provider whose single rate can change between calls.
+type movingRates struct {
+	mu   sync.Mutex
+	rate rates.ExchangeRate
+}
+
+func (m *movingRates) set(rate string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.rate.Rate = decimal.MustParse(rate)
+}
+
+func (m *movingRates) SupportedCurrencies(ctx context.Context) ([]*money.Currency, error) {
+	return []*money.Currency{m.rate.From, m.rate.To}, nil
+}
+
+func (m *movingRates) Rates(ctx context.Context, c1, c2 *money.Currency, c ...*money.Currency) (*rates.Matrix, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return rates.NewMatrix(m.rate)
+}
+
+func TestServiceLocksRate(t *testing.T) {
+	eur, usd := money.GetCurrency("EUR"), money.GetCurrency("USD")
+
+	provider := &movingRates{rate: rates.ExchangeRate{From: eur, To: usd, FetchedAt: t0}}
+	provider.set("1.1")
+
+	now := t0
+	service := NewService(exchanges.NewExchange(provider, nil), NewMemoryStore(), 30*time.Second)
+	service.now = func() time.Time { return now }
+
+	q, err := service.Quote(t.Context(), eur, usd, decimal.MustParse("100"))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if q.Net.String() != "110.00" || !q.ExpiresAt.Equal(t0.Add(30*time.Second)) {
+		t.Fatalf("Unexpected quote %+v", q)
+	}
+	if rate := q.ExchangeRate(); rate.From != eur || rate.To != usd || !rate.FetchedAt.Equal(t0) {
+		t.Fatalf("Unexpected locked rate %+v", rate)
+	}
+
+	// The market moves, the quote does not.
+	provider.set("1.2")
+	now = t0.Add(29 * time.Second)
+
+	executed, err := service.Execute(t.Context(), q.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if executed.Rate.String() != "1.1" || executed.Net.String() != "110.00" {
+		t.Fatalf("Expected the locked rate 1.1 and net 110.00 got %s and %s", executed.Rate, executed.Net)
+	}
+
+	late, err := service.Quote(t.Context(), eur, usd, decimal.MustParse("100"))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if late.Net.String() != "120.00" {
+		t.Fatalf("Expected a new quote at the moved rate got %s", late.Net)
+	}
+
+	now = late.ExpiresAt
+	if _, err := service.Execute(t.Context(), late.ID); !errors.Is(err, ErrExpired) {
+		t.Fatalf("Expected %v got %v", ErrExpired, err)
+	}
+}
@@ -0,0 +1,229 @@
+package rates
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Rhymond/go-money"
+	"golang.org/x/sync/errgroup"
+)
+
+// CompositeProvider merges providers quoting rates against a common pivot currency, e.g. fiat rates from one
+// and crypto rates from another. When a single provider supports all requested currencies it answers alone.
+// Otherwise each currency is taken from the first provider supporting it along with the pivot, and rates between
+// currencies of different providers are derived through the pivot, keeping the legs they were derived from.
+type CompositeProvider struct {
+	pivot     *money.Currency
+	providers []NamedProvider
+	now       func() time.Time
+
+	supported supportCache
+}
+
+func NewCompositeProvider(pivot *money.Currency, providers ...NamedProvider) *CompositeProvider {
+	return &CompositeProvider{
+		pivot:     pivot,
+		providers: providers,
+		now:       time.Now,
+		supported: newSupportCache(),
+	}
+}
+
+// SupportedCurrencies returns the currencies of the providers supporting the pivot, which are the ones rates can be derived for.
+func (p *CompositeProvider) SupportedCurrencies(ctx context.Context) ([]*money.Currency, error) {
+	uniq := map[string]*money.Currency{}
+	var errs []error
+
+	for _, np := range p.providers {
+		currencies, err := np.SupportedCurrencies(ctx)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", np.Name, err))
+			continue
+		}
+		if !slices.ContainsFunc(currencies, func(c *money.Currency) bool { return c.Code == p.pivot.Code }) {
+			continue
+		}
+
+		for _, c := range currencies {
+			uniq[c.Code] = c
+		}
+	}
+
+	if len(uniq) == 0 && len(errs) > 0 {
+		return nil, fmt.Errorf("getting supported currencies: %w", errors.Join(errs...))
+	}
+
+	out := make([]*money.Currency, 0, len(uniq))
+	for _, c := range uniq {
+		out = append(out, c)
+	}
+
+	slices.SortFunc(out, func(a, b *money.Currency) int {
+		return strings.Compare(a.Code, b.Code)
+	})
+
+	return out, nil
+}
+
+func (p *CompositeProvider) Rates(ctx context.Context, c1, c2 *money.Currency, c ...*money.Currency) (*Matrix, error) {
+	return p.merge(ctx, append([]*money.Currency{c1, c2}, c...), func(ctx context.Context, np NamedProvider, currencies []*money.Currency) (*Matrix, error) {
+		return np.Rates(ctx, currencies[0], currencies[1], currencies[2:]...)
+	})
+}
+
+// Since returns the first day any of the historical providers has rates for.
+func (p *CompositeProvider) Since() time.Time {
+	var since time.Time
+	for _, np := range p.providers {
+		hp, ok := np.Provider.(HistoricalProvider)
+		if !ok {
+			continue
+		}
+
+		if since.IsZero() || hp.Since().Before(since) {
+			since = hp.Since()
+		}
+	}
+
+	return since
+}
+
+// RatesAt is like Rates, but merges rates as of date. Every provider contributing a currency has to keep history covering date.
+func (p *CompositeProvider) RatesAt(ctx context.Context, date time.Time, c1, c2 *money.Currency, c ...*money.Currency) (*Matrix, error) {
+	return p.merge(ctx, append([]*money.Currency{c1, c2}, c...), func(ctx context.Context, np NamedProvider, currencies []*money.Currency) (*Matrix, error) {
+		hp, ok := np.Provider.(HistoricalProvider)
+		if !ok {
+			return nil, ErrNoHistory
+		}
+
+		if err := CheckDate(hp, date, p.now()); err != nil {
+			return nil, err
+		}
+
+		return hp.RatesAt(ctx, date, currencies[0], currencies[1], currencies[2:]...)
+	})
+}
+
+// merge gets the rates of currencies with get, from a single provider when one supports them all and derived through the pivot otherwise.
+func (p *CompositeProvider) merge(
+	ctx context.Context,
+	currencies []*money.Currency,
+	get func(ctx context.Context, np NamedProvider, currencies []*money.Currency) (*Matrix, error),
+) (*Matrix, error) {
+	uniq := make(map[string]*money.Currency, len(currencies))
+	for _, c := range currencies {
+		uniq[c.Code] = c
+	}
+	if len(uniq) < 2 {
+		return nil, fmt.Errorf("at least 2 distinct currencies required")
+	}
+
+	// Providers whose supported currencies can't be read are only skipped, unless none of them could be.
+	codes := make([]map[string]struct{}, len(p.providers))
+	var unknown []error
+	for i, np := range p.providers {
+		supported, err := p.supported.codes(ctx, np, p.now())
+		if err != nil {
+			unknown = append(unknown, fmt.Errorf("%s: %w", np.Name, err))
+			continue
+		}
+
+		codes[i] = supported
+	}
+	if len(unknown) > 0 && len(unknown) == len(p.providers) {
+		return nil, fmt.Errorf("getting supported currencies: %w: %w", ErrUnavailable, errors.Join(unknown...))
+	}
+
+	for i, np := range p.providers {
+		if !supportsAll(codes[i], currencies) {
+			continue
+		}
+
+		rates, err := get(ctx, np, currencies)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", np.Name, err)
+		}
+
+		return rates.withSource(np.Name), nil
+	}
+
+	all := make([]*money.Currency, 0, len(uniq))
+	for _, code := range slices.Sorted(maps.Keys(uniq)) {
+		all = append(all, uniq[code])
+	}
+
+	owned := make([][]*money.Currency, len(p.providers))
+	for _, c := range all {
+		if c.Code == p.pivot.Code {
+			continue
+		}
+
+		owner := slices.IndexFunc(codes, func(supported map[string]struct{}) bool {
+			return supportsAll(supported, []*money.Currency{c, p.pivot})
+		})
+		if owner < 0 && len(unknown) > 0 {
+			// One of the providers it couldn't be told about may well have rates for it.
+			return nil, fmt.Errorf("no provider known to have rates for %q against %q: %w: %w",
+				c.Code, p.pivot.Code, ErrUnavailable, errors.Join(unknown...))
+		}
+		if owner < 0 {
+			return nil, fmt.Errorf("no provider has rates for %q against %q: %w", c.Code, p.pivot.Code, ErrUnsupportedCurrency)
+		}
+
+		owned[owner] = append(owned[owner], c)
+	}
+
+	legs := make([]*Matrix, len(p.providers))
+
+	g, gctx := errgroup.WithContext(ctx)
+	for i, currencies := range owned {
+		if len(currencies) == 0 {
+			continue
+		}
+
+		np := p.providers[i]
+		g.Go(func() error {
+			rates, err := get(gctx, np, append([]*money.Currency{p.pivot}, currencies...))
+			if err != nil {
+				return fmt.Errorf("%s: %w", np.Name, err)
+			}
+
+			legs[i] = rates.withSource(np.Name)
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	// The currencies of different providers only meet at the pivot, so rates between currencies of the same
+	// provider are taken from it directly and the others are derived through the pivot.
+	graph := NewGraph()
+	for _, rates := range legs {
+		if err := graph.Add(rates.Rates()...); err != nil {
+			return nil, err
+		}
+	}
+
+	return graph.Rates(all)
+}
+
+// supportsAll reports whether supported holds every one of currencies, false when supported is not known.
+func supportsAll(supported map[string]struct{}, currencies []*money.Currency) bool {
+	if supported == nil {
+		return false
+	}
+
+	for _, c := range currencies {
+		if _, ok := supported[c.Code]; !ok {
+			return false
+		}
+	}
+
+	return true
+}
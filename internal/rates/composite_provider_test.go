@@ -0,0 +1,136 @@
+package rates
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/Rhymond/go-money"
+)
+
+func TestCompositeProvider(t *testing.T) {
+	usd, eur, gbp := money.GetCurrency("USD"), money.GetCurrency("EUR"), money.GetCurrency("GBP")
+	wbtc, beer := GetCurrency("WBTC"), GetCurrency("BEER")
+
+	newComposite := func() (*CompositeProvider, *stubProvider, *stubProvider) {
+		fiat := &stubProvider{Provider: NewStaticRatesProvider()}
+		crypto := &stubProvider{Provider: NewFixedCryptoRatesProvider()}
+
+		return NewCompositeProvider(usd,
+			NamedProvider{Name: "fiat", Provider: fiat},
+			NamedProvider{Name: "crypto", Provider: crypto},
+		), fiat, crypto
+	}
+
+	t.Run("single_provider_answers_alone", func(t *testing.T) {
+		prov, fiat, crypto := newComposite()
+
+		rates, err := prov.Rates(t.Context(), usd, eur, gbp)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		if fiat.calls != 1 || crypto.calls != 0 {
+			t.Fatalf("Expected only the fiat provider to be called got %d, %d", fiat.calls, crypto.calls)
+		}
+		if sources := rates.Sources(); !slices.Equal(sources, []string{"fiat"}) {
+			t.Fatalf("Expected rates to come from fiat got %v", sources)
+		}
+	})
+
+	t.Run("derives_cross_rates_through_pivot", func(t *testing.T) {
+		prov, fiat, crypto := newComposite()
+
+		rates, err := prov.Rates(t.Context(), wbtc, eur, beer, gbp)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		if fiat.calls != 1 || crypto.calls != 1 {
+			t.Fatalf("Expected each provider to be called once got %d, %d", fiat.calls, crypto.calls)
+		}
+		if rates.Len() != 12 {
+			t.Fatalf("Expected a rate for each of the 12 pairs got %d", rates.Len())
+		}
+
+		fiatRates, _ := NewStaticRatesProvider().Rates(t.Context(), usd, eur)
+		cryptoRates, _ := NewFixedCryptoRatesProvider().Rates(t.Context(), usd, wbtc, beer)
+
+		wbtcUSD, _ := cryptoRates.For(wbtc, usd)
+		usdEUR, _ := fiatRates.For(usd, eur)
+
+		got, ok := rates.For(wbtc, eur)
+		if !ok {
+			t.Fatalf("Expected WBTC/EUR rate in %v", rates)
+		}
+
+		want, _ := wbtcUSD.Rate.Mul(usdEUR.Rate)
+		if !got.Rate.Equal(want) {
+			t.Fatalf("Expected WBTC/EUR rate %s got %s", want, got.Rate)
+		}
+		if got.Source != "crypto+fiat" || len(got.Legs) != 2 {
+			t.Fatalf("Expected a rate derived from crypto and fiat legs got %q with %v", got.Source, got.Legs)
+		}
+		if leg := got.Legs[0]; leg.From != wbtc || leg.To != usd || leg.Source != "crypto" {
+			t.Fatalf("Unexpected first leg %v from %q", leg, leg.Source)
+		}
+		if leg := got.Legs[1]; leg.From != usd || leg.To != eur || leg.Source != "fiat" {
+			t.Fatalf("Unexpected second leg %v from %q", leg, leg.Source)
+		}
+
+		// Rates between currencies of the same provider are taken from it as they are.
+		wbtcBEER, _ := cryptoRates.For(wbtc, beer)
+		if got, _ := rates.For(wbtc, beer); !got.Rate.Equal(wbtcBEER.Rate) || got.Source != "crypto" || len(got.Legs) != len(wbtcBEER.Legs) {
+			t.Fatalf("Expected the direct crypto rate %s got %s from %q", wbtcBEER.Rate, got.Rate, got.Source)
+		}
+
+		if sources := rates.Sources(); !slices.Equal(sources, []string{"crypto", "fiat"}) {
+			t.Fatalf("Expected rates to come from crypto and fiat got %v", sources)
+		}
+	})
+
+	t.Run("unsupported_currency", func(t *testing.T) {
+		prov, _, _ := newComposite()
+
+		if _, err := prov.Rates(t.Context(), wbtc, money.GetCurrency("PLN")); !errors.Is(err, ErrUnsupportedCurrency) {
+			t.Fatalf("Expected %v got %v", ErrUnsupportedCurrency, err)
+		}
+	})
+
+	t.Run("supported_currencies_unknown", func(t *testing.T) {
+		down := func() Provider {
+			return &stubProvider{Provider: NewStaticRatesProvider(), supportedErr: ErrUnavailable}
+		}
+
+		tests := map[string]*CompositeProvider{
+			"all_providers": NewCompositeProvider(usd,
+				NamedProvider{Name: "fiat", Provider: down()},
+				NamedProvider{Name: "crypto", Provider: &stubProvider{Provider: NewFixedCryptoRatesProvider(), supportedErr: ErrUnavailable}},
+			),
+			"owner": NewCompositeProvider(usd,
+				NamedProvider{Name: "fiat", Provider: down()},
+				NamedProvider{Name: "crypto", Provider: NewFixedCryptoRatesProvider()},
+			),
+		}
+
+		for name, prov := range tests {
+			t.Run(name, func(t *testing.T) {
+				_, err := prov.Rates(t.Context(), wbtc, eur)
+				if !errors.Is(err, ErrUnavailable) || errors.Is(err, ErrUnsupportedCurrency) {
+					t.Fatalf("Expected %v got %v", ErrUnavailable, err)
+				}
+			})
+		}
+	})
+
+	t.Run("leg_failure", func(t *testing.T) {
+		prov := NewCompositeProvider(usd,
+			NamedProvider{Name: "fiat", Provider: NewStaticRatesProvider()},
+			NamedProvider{Name: "crypto", Provider: &stubProvider{Provider: NewFixedCryptoRatesProvider(), err: ErrUnavailable}},
+		)
+
+		if _, err := prov.Rates(t.Context(), wbtc, eur); !errors.Is(err, ErrUnavailable) {
+			t.Fatalf("Expected %v got %v", ErrUnavailable, err)
+		}
+	})
+}
@@ -0,0 +1,462 @@
+package rates
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Rhymond/go-money"
+	"github.com/govalues/decimal"
+)
+
+// ConsensusMethod is how ConsensusProvider aggregates the rates of a pair answered by several providers.
+type ConsensusMethod string
+
+const (
+	// ConsensusMedian takes the median of the rates, the mean of the middle two for an even number of them.
+	ConsensusMedian ConsensusMethod = "median"
+
+	// ConsensusWeightedMean takes the mean of the rates weighted by the weight of each provider.
+	ConsensusWeightedMean ConsensusMethod = "weighted_mean"
+)
+
+// ParseConsensusMethod parses the name of a consensus method, defaulting to ConsensusMedian when it is empty.
+func ParseConsensusMethod(s string) (ConsensusMethod, error) {
+	switch m := ConsensusMethod(s); m {
+	case "":
+		return ConsensusMedian, nil
+	case ConsensusMedian, ConsensusWeightedMean:
+		return m, nil
+	default:
+		return "", fmt.Errorf("unknown consensus method %q", s)
+	}
+}
+
+// Consensus describes how the rate of a pair was agreed on by several providers.
+type Consensus struct {
+	// Method is how the rates of the Sources were aggregated.
+	Method ConsensusMethod
+
+	// Sources are the providers whose rates the consensus was taken of, in the order they are configured in.
+	Sources []string
+
+	// Rejected are the providers that answered with a rate deviating from the median beyond the tolerance.
+	Rejected []string
+
+	// Dispersion is the difference between the highest and the lowest rate of the Sources relative to the consensus rate.
+	Dispersion decimal.Decimal
+}
+
+// ConsensusProvider asks several providers for rates in parallel and answers with the consensus of every pair, so a
+// single provider's glitch doesn't make it to the rates served. The rates deviating from the median of a pair by
+// more than the tolerance are rejected, and the rest are aggregated with the method. A pair needs the rates of at
+// least a quorum of providers, so as many providers as there are beyond it may fail or be rejected.
+//
+// Consensus rates are quoted directly, carrying the Consensus they were agreed with. They are two-sided when the rates of
+// all Sources are, with the bid and ask aggregated like the rate.
+type ConsensusProvider struct {
+	providers []NamedProvider
+
+	method    ConsensusMethod
+	tolerance decimal.Decimal
+	quorum    int
+	weights   map[string]decimal.Decimal
+
+	// timeout bounds a single provider call, a non-positive value leaves it to the caller's context.
+	timeout time.Duration
+	now     func() time.Time
+}
+
+// ConsensusOption configures ConsensusProvider.
+type ConsensusOption func(*ConsensusProvider)
+
+// WithConsensusMethod sets how the rates of a pair are aggregated, ConsensusMedian by default.
+func WithConsensusMethod(m ConsensusMethod) ConsensusOption {
+	return func(p *ConsensusProvider) {
+		p.method = m
+	}
+}
+
+// WithTolerance sets how far a rate may deviate from the median of its pair, relative to it, e.g. 0.02 for 2%,
+// before it is rejected. A non-positive tolerance rejects no rates.
+func WithTolerance(tolerance decimal.Decimal) ConsensusOption {
+	return func(p *ConsensusProvider) {
+		p.tolerance = tolerance
+	}
+}
+
+// WithQuorum sets how many providers have to agree on the rate of a pair, a majority of them by default.
+func WithQuorum(n int) ConsensusOption {
+	return func(p *ConsensusProvider) {
+		p.quorum = n
+	}
+}
+
+// WithWeights sets the weights of the providers by name for ConsensusWeightedMean, providers without one weigh 1.
+func WithWeights(weights map[string]decimal.Decimal) ConsensusOption {
+	return func(p *ConsensusProvider) {
+		p.weights = weights
+	}
+}
+
+// WithConsensusTimeout bounds every provider call, so a slow provider counts as failed rather than holding up the others.
+func WithConsensusTimeout(timeout time.Duration) ConsensusOption {
+	return func(p *ConsensusProvider) {
+		p.timeout = timeout
+	}
+}
+
+// NewConsensusProvider returns a provider answering with the consensus of providers. It fails without providers,
+// with a quorum more than there are of them, an unknown method or a weight that is not positive.
+func NewConsensusProvider(providers []NamedProvider, opts ...ConsensusOption) (*ConsensusProvider, error) {
+	p := &ConsensusProvider{
+		providers: providers,
+		method:    ConsensusMedian,
+		quorum:    len(providers)/2 + 1,
+		now:       time.Now,
+	}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	if len(p.providers) == 0 {
+		return nil, fmt.Errorf("no consensus providers configured")
+	}
+	if p.quorum < 1 || p.quorum > len(p.providers) {
+		return nil, fmt.Errorf("quorum of %d out of %d providers", p.quorum, len(p.providers))
+	}
+	if _, err := ParseConsensusMethod(string(p.method)); err != nil {
+		return nil, err
+	}
+	for name, weight := range p.weights {
+		if weight.Sign() <= 0 {
+			return nil, fmt.Errorf("weight %s of %q is not positive", weight, name)
+		}
+	}
+
+	return p, nil
+}
+
+// SupportedCurrencies returns the currencies supported by at least a quorum of the providers.
+func (p *ConsensusProvider) SupportedCurrencies(ctx context.Context) ([]*money.Currency, error) {
+	counts := make(map[string]int)
+	uniq := make(map[string]*money.Currency)
+	var errs []error
+
+	for _, np := range p.providers {
+		ctx, cancel := p.withTimeout(ctx)
+		currencies, err := np.SupportedCurrencies(ctx)
+		cancel()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", np.Name, err))
+			continue
+		}
+
+		for _, c := range currencies {
+			counts[c.Code]++
+			uniq[c.Code] = c
+		}
+	}
+
+	if len(p.providers)-len(errs) < p.quorum {
+		return nil, fmt.Errorf("getting supported currencies: %w: %w", ErrNoConsensus, errors.Join(errs...))
+	}
+
+	var out []*money.Currency
+	for code, n := range counts {
+		if n >= p.quorum {
+			out = append(out, uniq[code])
+		}
+	}
+
+	slices.SortFunc(out, func(a, b *money.Currency) int {
+		return strings.Compare(a.Code, b.Code)
+	})
+
+	return out, nil
+}
+
+// Rates returns the consensus rates of the providers.
+func (p *ConsensusProvider) Rates(ctx context.Context, c1, c2 *money.Currency, c ...*money.Currency) (*Matrix, error) {
+	return p.consensus(ctx, func(ctx context.Context, np NamedProvider) (*Matrix, error) {
+		return np.Rates(ctx, c1, c2, c...)
+	})
+}
+
+// Since returns the first day any of the historical providers has rates for. Days covered by fewer than a quorum of
+// them fail with ErrNoConsensus.
+func (p *ConsensusProvider) Since() time.Time {
+	var since time.Time
+	for _, np := range p.providers {
+		hp, ok := np.Provider.(HistoricalProvider)
+		if !ok {
+			continue
+		}
+
+		if since.IsZero() || hp.Since().Before(since) {
+			since = hp.Since()
+		}
+	}
+
+	return since
+}
+
+// RatesAt returns the consensus rates of the historical providers as of date.
+func (p *ConsensusProvider) RatesAt(ctx context.Context, date time.Time, c1, c2 *money.Currency, c ...*money.Currency) (*Matrix, error) {
+	return p.consensus(ctx, func(ctx context.Context, np NamedProvider) (*Matrix, error) {
+		hp, ok := np.Provider.(HistoricalProvider)
+		if !ok {
+			return nil, ErrNoHistory
+		}
+
+		if err := CheckDate(hp, date, p.now()); err != nil {
+			return nil, err
+		}
+
+		return hp.RatesAt(ctx, date, c1, c2, c...)
+	})
+}
+
+// answer is the rates a provider answered with, or why it didn't.
+type answer struct {
+	name  string
+	rates *Matrix
+	err   error
+}
+
+// consensus calls get for every provider in parallel and takes the consensus of every pair they answered with.
+func (p *ConsensusProvider) consensus(ctx context.Context, get func(ctx context.Context, np NamedProvider) (*Matrix, error)) (*Matrix, error) {
+	answers := make([]answer, len(p.providers))
+
+	var wg sync.WaitGroup
+	for i, np := range p.providers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			ctx, cancel := p.withTimeout(ctx)
+			defer cancel()
+
+			rates, err := get(ctx, np)
+			if err != nil {
+				answers[i] = answer{name: np.Name, err: err}
+				return
+			}
+
+			answers[i] = answer{name: np.Name, rates: rates.withSource(np.Name)}
+		}()
+	}
+	wg.Wait()
+
+	var (
+		answered []answer
+		errs     []error
+	)
+	for _, a := range answers {
+		if a.err != nil {
+			slog.WarnContext(ctx, "consensus rates provider failed", "provider", a.name, "err", a.err)
+			errs = append(errs, fmt.Errorf("%s: %w", a.name, a.err))
+			continue
+		}
+
+		answered = append(answered, a)
+	}
+
+	if len(answered) < p.quorum {
+		return nil, fmt.Errorf("%d of %d providers answered, %d required: %w: %w",
+			len(answered), len(p.providers), p.quorum, ErrNoConsensus, errors.Join(errs...))
+	}
+
+	var out []ExchangeRate
+	for _, rate := range answered[0].rates.Rates() {
+		agreed, err := p.agree(ctx, rate.From, rate.To, answered)
+		if err != nil {
+			return nil, err
+		}
+
+		out = append(out, agreed)
+	}
+
+	return NewMatrix(out...)
+}
+
+// agree takes the consensus of the rates of the pair from from to to answered by the providers.
+func (p *ConsensusProvider) agree(ctx context.Context, from, to *money.Currency, answered []answer) (ExchangeRate, error) {
+	var (
+		names []string
+		rates []ExchangeRate
+	)
+	for _, a := range answered {
+		if rate, ok := a.rates.For(from, to); ok {
+			names = append(names, a.name)
+			rates = append(rates, rate)
+		}
+	}
+
+	median, err := median(mids(rates))
+	if err != nil {
+		return ExchangeRate{}, fmt.Errorf("agreeing on rate for %q and %q: %w", from.Code, to.Code, err)
+	}
+
+	consensus := &Consensus{Method: p.method}
+	var accepted []ExchangeRate
+	for i, rate := range rates {
+		deviates, err := p.deviates(rate.Rate, median)
+		if err != nil {
+			return ExchangeRate{}, fmt.Errorf("agreeing on rate for %q and %q: %w", from.Code, to.Code, err)
+		}
+
+		if deviates {
+			slog.WarnContext(ctx, "rate deviates from consensus, rejecting it",
+				"provider", names[i], "from", from.Code, "to", to.Code, "rate", rate.Rate, "median", median)
+			consensus.Rejected = append(consensus.Rejected, names[i])
+			continue
+		}
+
+		consensus.Sources = append(consensus.Sources, names[i])
+		accepted = append(accepted, rate)
+	}
+
+	if len(accepted) < p.quorum {
+		return ExchangeRate{}, fmt.Errorf("rate for %q and %q: %d of %d providers agree, %d required: %w",
+			from.Code, to.Code, len(accepted), len(p.providers), p.quorum, ErrNoConsensus)
+	}
+
+	out := ExchangeRate{From: from, To: to, Cached: anyCached(accepted), Consensus: consensus}
+	out.AsOf, _ = oldest(accepted, asOf)
+	out.FetchedAt, _ = oldest(accepted, fetchedAt)
+
+	weights := p.weightsOf(consensus.Sources)
+	if out.Rate, err = p.aggregate(mids(accepted), weights); err != nil {
+		return ExchangeRate{}, fmt.Errorf("agreeing on rate for %q and %q: %w", from.Code, to.Code, err)
+	}
+	if !slices.ContainsFunc(accepted, func(r ExchangeRate) bool { return !r.TwoSided() }) {
+		if out.Bid, err = p.aggregate(sides(accepted, ExchangeRate.SellRate), weights); err != nil {
+			return ExchangeRate{}, fmt.Errorf("agreeing on bid for %q and %q: %w", from.Code, to.Code, err)
+		}
+		if out.Ask, err = p.aggregate(sides(accepted, ExchangeRate.BuyRate), weights); err != nil {
+			return ExchangeRate{}, fmt.Errorf("agreeing on ask for %q and %q: %w", from.Code, to.Code, err)
+		}
+	}
+
+	if consensus.Dispersion, err = dispersion(mids(accepted), out.Rate); err != nil {
+		return ExchangeRate{}, fmt.Errorf("agreeing on rate for %q and %q: %w", from.Code, to.Code, err)
+	}
+
+	return out, nil
+}
+
+// deviates reports whether rate deviates from median by more than the tolerance.
+func (p *ConsensusProvider) deviates(rate, median decimal.Decimal) (bool, error) {
+	if p.tolerance.Sign() <= 0 {
+		return false, nil
+	}
+
+	diff, err := rate.SubAbs(median)
+	if err != nil {
+		return false, err
+	}
+
+	allowed, err := median.Mul(p.tolerance)
+	if err != nil {
+		return false, err
+	}
+
+	return diff.Cmp(allowed) > 0, nil
+}
+
+// aggregate aggregates values with the method of p.
+func (p *ConsensusProvider) aggregate(values, weights []decimal.Decimal) (decimal.Decimal, error) {
+	if p.method == ConsensusWeightedMean {
+		return weightedMean(values, weights)
+	}
+
+	return median(values)
+}
+
+// median returns the median of values, the mean of the middle two for an even number of them.
+func median(values []decimal.Decimal) (decimal.Decimal, error) {
+	sorted := slices.SortedFunc(slices.Values(values), decimal.Decimal.Cmp)
+
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 1 {
+		return sorted[mid], nil
+	}
+
+	sum, err := sorted[mid-1].Add(sorted[mid])
+	if err != nil {
+		return decimal.Decimal{}, err
+	}
+
+	return sum.Quo(decimal.Two)
+}
+
+// weightsOf returns the weights of the providers with the names, 1 for those without one.
+func (p *ConsensusProvider) weightsOf(names []string) []decimal.Decimal {
+	out := make([]decimal.Decimal, 0, len(names))
+	for _, name := range names {
+		weight, ok := p.weights[name]
+		if !ok {
+			weight = decimal.One
+		}
+
+		out = append(out, weight)
+	}
+
+	return out
+}
+
+func (p *ConsensusProvider) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if p.timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+
+	return context.WithTimeout(ctx, p.timeout)
+}
+
+// weightedMean returns the mean of values weighted by weights.
+func weightedMean(values, weights []decimal.Decimal) (decimal.Decimal, error) {
+	var sum, total decimal.Decimal
+	for i, v := range values {
+		var err error
+		if sum, err = sum.AddMul(v, weights[i]); err != nil {
+			return decimal.Decimal{}, err
+		}
+		if total, err = total.Add(weights[i]); err != nil {
+			return decimal.Decimal{}, err
+		}
+	}
+
+	return sum.Quo(total)
+}
+
+// dispersion returns the difference between the highest and the lowest of values relative to rate.
+func dispersion(values []decimal.Decimal, rate decimal.Decimal) (decimal.Decimal, error) {
+	spread, err := slices.MaxFunc(values, decimal.Decimal.Cmp).Sub(slices.MinFunc(values, decimal.Decimal.Cmp))
+	if err != nil {
+		return decimal.Decimal{}, err
+	}
+
+	return spread.Quo(rate)
+}
+
+// mids returns the mid rates of rates.
+func mids(rates []ExchangeRate) []decimal.Decimal {
+	return sides(rates, func(r ExchangeRate) decimal.Decimal { return r.Rate })
+}
+
+// sides returns the side of every rate of rates.
+func sides(rates []ExchangeRate, side func(ExchangeRate) decimal.Decimal) []decimal.Decimal {
+	out := make([]decimal.Decimal, 0, len(rates))
+	for _, r := range rates {
+		out = append(out, side(r))
+	}
+
+	return out
+}
@@ -0,0 +1,219 @@
+package rates
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/Rhymond/go-money"
+	"github.com/govalues/decimal"
+)
+
+// usdTable answers with the cross rates of a table of quotes against USD fetched at fetchedAt, or fails with err.
+type usdTable struct {
+	quotes    map[string]string
+	fetchedAt time.Time
+	err       error
+}
+
+func (u usdTable) SupportedCurrencies(ctx context.Context) ([]*money.Currency, error) {
+	return nil, nil
+}
+
+func (u usdTable) Rates(ctx context.Context, c1, c2 *money.Currency, c ...*money.Currency) (*Matrix, error) {
+	if u.err != nil {
+		return nil, u.err
+	}
+
+	table := map[string]decimal.Decimal{money.USD: decimal.One}
+	for code, quote := range u.quotes {
+		table[code] = decimal.MustParse(quote)
+	}
+
+	return crossRates(money.USD, table, currencyCodes(append([]*money.Currency{c1, c2}, c...)), provenance{fetchedAt: u.fetchedAt})
+}
+
+func (u usdTable) Quotes(ctx context.Context) (*Matrix, error) {
+	if u.err != nil {
+		return nil, u.err
+	}
+
+	table := make(map[string]decimal.Decimal, len(u.quotes))
+	for code, quote := range u.quotes {
+		table[code] = decimal.MustParse(quote)
+	}
+
+	return allQuotes(money.USD, midPrices(table), provenance{fetchedAt: u.fetchedAt}, false)
+}
+
+// quotedRates answers with its rates as quoted.
+type quotedRates []ExchangeRate
+
+func (q quotedRates) SupportedCurrencies(ctx context.Context) ([]*money.Currency, error) {
+	return nil, nil
+}
+
+func (q quotedRates) Rates(ctx context.Context, c1, c2 *money.Currency, c ...*money.Currency) (*Matrix, error) {
+	return NewMatrix(q...)
+}
+
+func TestConsensusProvider(t *testing.T) {
+	usd, eur := money.GetCurrency("USD"), money.GetCurrency("EUR")
+
+	eurAt := func(quote string) Provider {
+		return usdTable{quotes: map[string]string{"EUR": quote}}
+	}
+	failing := usdTable{err: ErrUnavailable}
+
+	t.Run("median_rejects_outliers", func(t *testing.T) {
+		prov, err := NewConsensusProvider([]NamedProvider{
+			{Name: "a", Provider: eurAt("0.85")},
+			{Name: "b", Provider: eurAt("0.86")},
+			{Name: "glitch", Provider: eurAt("0.0085")},
+		}, WithTolerance(decimal.MustParse("0.02")))
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		rates, err := prov.Rates(t.Context(), usd, eur)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		rate, _ := rates.For(usd, eur)
+		if !rate.Rate.Equal(decimal.MustParse("0.855")) {
+			t.Fatalf("Expected USD/EUR rate 0.855 got %s", rate.Rate)
+		}
+		if rate.Consensus == nil || !slices.Equal(rate.Consensus.Sources, []string{"a", "b"}) || !slices.Equal(rate.Consensus.Rejected, []string{"glitch"}) {
+			t.Fatalf("Expected consensus of a and b rejecting glitch got %+v", rate.Consensus)
+		}
+
+		// (0.86 - 0.85) / 0.855
+		if want := decimal.MustParse("0.0116959064327485380"); !rate.Consensus.Dispersion.Equal(want) {
+			t.Fatalf("Expected dispersion %s got %s", want, rate.Consensus.Dispersion)
+		}
+	})
+
+	t.Run("weighted_mean", func(t *testing.T) {
+		prov, err := NewConsensusProvider([]NamedProvider{
+			{Name: "a", Provider: eurAt("0.85")},
+			{Name: "b", Provider: eurAt("0.86")},
+		},
+			WithConsensusMethod(ConsensusWeightedMean),
+			WithWeights(map[string]decimal.Decimal{"a": decimal.MustParse("3")}),
+		)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		rates, err := prov.Rates(t.Context(), usd, eur)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		// (3 * 0.85 + 0.86) / 4
+		if rate, _ := rates.For(usd, eur); !rate.Rate.Equal(decimal.MustParse("0.8525")) {
+			t.Fatalf("Expected USD/EUR rate 0.8525 got %s", rate.Rate)
+		}
+	})
+
+	t.Run("two_sided", func(t *testing.T) {
+		quote := func(bid, mid, ask string) ExchangeRate {
+			return ExchangeRate{From: usd, To: eur, Rate: decimal.MustParse(mid), Bid: decimal.MustParse(bid), Ask: decimal.MustParse(ask)}
+		}
+
+		prov, err := NewConsensusProvider([]NamedProvider{
+			{Name: "a", Provider: quotedRates{quote("0.84", "0.85", "0.86")}},
+			{Name: "b", Provider: quotedRates{quote("0.85", "0.86", "0.87")}},
+		})
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		rates, err := prov.Rates(t.Context(), usd, eur)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		rate, _ := rates.For(usd, eur)
+		if !rate.Bid.Equal(decimal.MustParse("0.845")) || !rate.Ask.Equal(decimal.MustParse("0.865")) {
+			t.Fatalf("Expected USD/EUR bid 0.845 and ask 0.865 got %s and %s", rate.Bid, rate.Ask)
+		}
+	})
+
+	t.Run("tolerates_failures_within_quorum", func(t *testing.T) {
+		prov, err := NewConsensusProvider([]NamedProvider{
+			{Name: "a", Provider: eurAt("0.85")},
+			{Name: "failing", Provider: failing},
+			{Name: "b", Provider: eurAt("0.86")},
+		})
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		rates, err := prov.Rates(t.Context(), usd, eur)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if rate, _ := rates.For(eur, usd); !slices.Equal(rate.Consensus.Sources, []string{"a", "b"}) {
+			t.Fatalf("Expected consensus of a and b got %v", rate.Consensus.Sources)
+		}
+	})
+
+	t.Run("no_consensus", func(t *testing.T) {
+		tests := []struct {
+			name      string
+			providers []NamedProvider
+		}{
+			{
+				name: "too_many_failures",
+				providers: []NamedProvider{
+					{Name: "a", Provider: eurAt("0.85")},
+					{Name: "failing", Provider: failing},
+					{Name: "also_failing", Provider: failing},
+				},
+			},
+			{
+				name: "too_many_rejected",
+				providers: []NamedProvider{
+					{Name: "a", Provider: eurAt("0.85")},
+					{Name: "b", Provider: eurAt("0.95")},
+				},
+			},
+		}
+
+		for _, tc := range tests {
+			t.Run(tc.name, func(t *testing.T) {
+				prov, err := NewConsensusProvider(tc.providers, WithTolerance(decimal.MustParse("0.02")))
+				if err != nil {
+					t.Fatalf("err: %v", err)
+				}
+
+				if _, err := prov.Rates(t.Context(), usd, eur); !errors.Is(err, ErrNoConsensus) {
+					t.Fatalf("Expected ErrNoConsensus got %v", err)
+				}
+			})
+		}
+	})
+
+	t.Run("invalid_config", func(t *testing.T) {
+		providers := []NamedProvider{{Name: "a", Provider: eurAt("0.85")}}
+
+		tests := map[string][]ConsensusOption{
+			"quorum_above_providers": {WithQuorum(2)},
+			"zero_quorum":            {WithQuorum(0)},
+			"unknown_method":         {WithConsensusMethod("mode")},
+			"zero_weight":            {WithWeights(map[string]decimal.Decimal{"a": decimal.Zero})},
+		}
+
+		for name, opts := range tests {
+			t.Run(name, func(t *testing.T) {
+				if _, err := NewConsensusProvider(providers, opts...); err == nil {
+					t.Fatalf("Expected error")
+				}
+			})
+		}
+	})
+}
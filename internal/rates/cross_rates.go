@@ -0,0 +1,135 @@
+package rates
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Rhymond/go-money"
+	"github.com/govalues/decimal"
+)
+
+// crossRates computes the rate between every ordered pair of codes from a table of quotes against base,
+// where table[code] is the amount of code worth one unit of base.
+// Each pair is returned once, ordered by From and then To.
+func crossRates(base string, table map[string]decimal.Decimal, codes []string, prov provenance) (*Matrix, error) {
+	return tableRates(base, midPrices(table), codes, prov, false)
+}
+
+// valueCrossRates is like crossRates for a table of values, where table[code] is the amount of base worth one unit of code.
+func valueCrossRates(base string, table map[string]decimal.Decimal, codes []string, prov provenance) (*Matrix, error) {
+	return tableRates(base, midPrices(table), codes, prov, true)
+}
+
+// provenance is what a provider knows of where a table came from, carried by every quote of it.
+type provenance struct {
+	// asOf is the time upstream says the table was in effect at, zero when it doesn't tell.
+	asOf time.Time
+	// fetchedAt is the time the table was fetched from upstream, zero for tables that are not fetched.
+	fetchedAt time.Time
+	// cached is set when the table was fetched before it was asked for.
+	cached bool
+}
+
+// price is a quote of a table: its mid rate, along with its bid and ask rates when it is quoted two-sided.
+type price struct {
+	mid, bid, ask decimal.Decimal
+}
+
+// midPrices returns the prices of a table quoted at mid rates only.
+func midPrices(table map[string]decimal.Decimal) map[string]price {
+	out := make(map[string]price, len(table))
+	for code, mid := range table {
+		out[code] = price{mid: mid}
+	}
+
+	return out
+}
+
+// tableRates adds the quotes of codes in table against base to a Graph and derives the rates between codes from it.
+// When values is set, table holds values of the codes in base as valueCrossRates does, otherwise quotes of base as
+// crossRates does.
+func tableRates(base string, table map[string]price, codes []string, prov provenance, values bool) (*Matrix, error) {
+	quotes, currencies, err := tableQuotes(base, table, codes, prov, values)
+	if err != nil {
+		return nil, err
+	}
+
+	g := NewGraph()
+	if err := g.Add(quotes...); err != nil {
+		return nil, err
+	}
+
+	return g.Rates(currencies)
+}
+
+// allQuotes returns the quotes of every currency in table known by its code against base, see tableRates.
+func allQuotes(base string, table map[string]price, prov provenance, values bool) (*Matrix, error) {
+	codes := make([]string, 0, len(table))
+	for code := range table {
+		if GetCurrency(code) != nil {
+			codes = append(codes, code)
+		}
+	}
+
+	quotes, _, err := tableQuotes(base, table, codes, prov, values)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewMatrix(quotes...)
+}
+
+// tableQuotes returns the quotes of codes in table against base, along with the currencies of codes.
+func tableQuotes(base string, table map[string]price, codes []string, prov provenance, values bool) ([]ExchangeRate, []*money.Currency, error) {
+	baseCurrency := GetCurrency(base)
+	if baseCurrency == nil {
+		return nil, nil, fmt.Errorf("unknown base currency %q", base)
+	}
+
+	quotes := make([]ExchangeRate, 0, len(codes))
+	currencies := make([]*money.Currency, 0, len(codes))
+	for _, code := range codes {
+		currency := GetCurrency(code)
+		if currency == nil {
+			return nil, nil, fmt.Errorf("unknown currency %q: %w", code, ErrUnsupportedCurrency)
+		}
+
+		currencies = append(currencies, currency)
+		if currency.Code == baseCurrency.Code {
+			continue
+		}
+
+		p, ok := table[currency.Code]
+		if !ok {
+			return nil, nil, fmt.Errorf("missing rate for %q: %w", currency.Code, ErrUnsupportedCurrency)
+		}
+
+		quote := ExchangeRate{
+			From:      baseCurrency,
+			To:        currency,
+			Rate:      p.mid,
+			Bid:       p.bid,
+			Ask:       p.ask,
+			AsOf:      prov.asOf,
+			FetchedAt: prov.fetchedAt,
+			Cached:    prov.cached,
+		}
+		if values {
+			quote.From, quote.To = currency, baseCurrency
+		}
+
+		quotes = append(quotes, quote)
+	}
+
+	return quotes, currencies, nil
+}
+
+// currencyCodes returns the codes of currencies.
+func currencyCodes(currencies []*money.Currency) []string {
+	out := make([]string, 0, len(currencies))
+	for _, c := range currencies {
+		out = append(out, c.Code)
+	}
+
+	return out
+}
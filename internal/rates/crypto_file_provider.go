@@ -0,0 +1,221 @@
+package rates
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"os"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/govalues/decimal"
+)
+
+// maxTokenDecimals is the most decimal places a token can have, so its amounts still fit a decimal.
+const maxTokenDecimals = 18
+
+// tokenCode is the form of token codes, e.g. "WBTC" or "1INCH".
+var tokenCode = regexp.MustCompile(`^[A-Z0-9]{2,12}$`)
+
+// CryptoToken declares a token and its price.
+type CryptoToken struct {
+	// Symbol is the symbol amounts of the token are formatted with, the code when empty.
+	Symbol string `json:"symbol"`
+
+	// Decimals is the number of decimal places of the token.
+	Decimals int `json:"decimals"`
+
+	// USD is the price of one unit of the token in USD.
+	USD decimal.Decimal `json:"usd"`
+}
+
+// CryptoConfig is the configuration of CryptoFileProvider, e.g.
+//
+//	{
+//	  "tokens": {
+//	    "WBTC": {"symbol": "WBTC", "decimals": 8, "usd": "57037.22"},
+//	    "USDT": {"symbol": "USDT", "decimals": 6, "usd": "0.999"}
+//	  }
+//	}
+type CryptoConfig struct {
+	// Tokens hold the tokens by code.
+	Tokens map[string]CryptoToken `json:"tokens"`
+}
+
+// ReadCryptoConfig reads a CryptoConfig from JSON and checks it is valid.
+func ReadCryptoConfig(r io.Reader) (CryptoConfig, error) {
+	var cfg CryptoConfig
+
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(&cfg); err != nil {
+		return CryptoConfig{}, fmt.Errorf("decoding crypto config: %w", err)
+	}
+
+	if err := cfg.validate(); err != nil {
+		return CryptoConfig{}, err
+	}
+
+	return cfg, nil
+}
+
+// LoadCryptoConfig reads a CryptoConfig from the JSON file at path.
+func LoadCryptoConfig(path string) (CryptoConfig, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return CryptoConfig{}, fmt.Errorf("opening crypto config: %w", err)
+	}
+	defer f.Close()
+
+	return ReadCryptoConfig(f)
+}
+
+func (c CryptoConfig) clone() CryptoConfig {
+	return CryptoConfig{Tokens: maps.Clone(c.Tokens)}
+}
+
+func (c CryptoConfig) validate() error {
+	if len(c.Tokens) == 0 {
+		return fmt.Errorf("%w: no tokens declared", ErrInvalidCryptoRates)
+	}
+
+	for code, token := range c.Tokens {
+		if !tokenCode.MatchString(code) {
+			return fmt.Errorf("%w: token %q: code is not 2 to 12 upper case letters or digits", ErrInvalidCryptoRates, code)
+		}
+
+		// A token can't take the place of a fiat currency, the rates of which come from other providers.
+		if isFiat(code) {
+			return fmt.Errorf("%w: token %q: code of a fiat currency", ErrInvalidCryptoRates, code)
+		}
+
+		if token.Decimals < 0 || token.Decimals > maxTokenDecimals {
+			return fmt.Errorf("%w: token %q: decimals %d not in [0, %d]", ErrInvalidCryptoRates, code, token.Decimals,
+				maxTokenDecimals)
+		}
+		if token.USD.Sign() <= 0 {
+			return fmt.Errorf("%w: token %q: usd price %s is not positive", ErrInvalidCryptoRates, code, token.USD)
+		}
+	}
+
+	return nil
+}
+
+// CryptoFileProvider serves the tokens declared in a JSON file, see CryptoConfig, as a MutableCryptoRatesProvider.
+// The file is reloaded with Reload, or by Watch when it changes, replacing the tokens as a new version. The tokens are
+// only ever changed from the file, other changes fail with ErrCryptoRatesManaged. A reload failing to read a valid
+// config keeps the current version.
+type CryptoFileProvider struct {
+	*MutableCryptoRatesProvider
+
+	path string
+
+	// reloading serializes reloads, so a slower one can't replace the config a later one loaded.
+	reloading sync.Mutex
+
+	// mu guards the version of the file last loaded.
+	mu      sync.Mutex
+	modTime time.Time
+	size    int64
+}
+
+// NewCryptoFileProvider returns a provider serving the tokens declared in the file at path. It fails when the file
+// does not hold a valid config.
+func NewCryptoFileProvider(path string, opts ...CryptoOption) (*CryptoFileProvider, error) {
+	p := &CryptoFileProvider{path: path}
+
+	cfg, err := p.load()
+	if err != nil {
+		return nil, err
+	}
+
+	p.MutableCryptoRatesProvider, err = NewMutableCryptoRatesProvider(cfg, p.change("loaded"), opts...)
+	if err != nil {
+		return nil, err
+	}
+	p.managedBy = path
+
+	return p, nil
+}
+
+// Reload reads the file again and serves the tokens declared in it. When the file does not hold a valid config
+// the current tokens are kept and the error is returned.
+func (p *CryptoFileProvider) Reload() error {
+	p.reloading.Lock()
+	defer p.reloading.Unlock()
+
+	cfg, err := p.load()
+	if err != nil {
+		return fmt.Errorf("reloading crypto config: %w", err)
+	}
+
+	if _, err := p.Replace(cfg, p.change("reloaded")); err != nil {
+		return fmt.Errorf("reloading crypto config: %w", err)
+	}
+
+	return nil
+}
+
+// load reads the config from the file, noting the version of the file it was read from, so Watch doesn't reload
+// an invalid file again until it changes.
+func (p *CryptoFileProvider) load() (CryptoConfig, error) {
+	info, err := os.Stat(p.path)
+	if err != nil {
+		return CryptoConfig{}, fmt.Errorf("opening crypto config: %w", err)
+	}
+
+	p.mu.Lock()
+	p.modTime, p.size = info.ModTime(), info.Size()
+	p.mu.Unlock()
+
+	return LoadCryptoConfig(p.path)
+}
+
+func (p *CryptoFileProvider) change(reason string) CryptoChange {
+	return CryptoChange{Author: "file", Reason: reason + " " + p.path}
+}
+
+// Watch reloads the file every interval when its modification time or size changed, until ctx is done.
+// Failed reloads are logged and the current tokens are kept.
+func (p *CryptoFileProvider) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if !p.changed() {
+			continue
+		}
+
+		if err := p.Reload(); err != nil {
+			slog.Error("crypto config reload failed, keeping the last good config", "path", p.path, "err", err)
+			continue
+		}
+
+		slog.Info("crypto config reloaded", "path", p.path)
+	}
+}
+
+// changed reports whether the file changed since it was last loaded. A file that can't be stat-ed, e.g. while it is
+// replaced, did not change, the last good config is served until it is back.
+func (p *CryptoFileProvider) changed() bool {
+	info, err := os.Stat(p.path)
+	if err != nil {
+		return false
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return !info.ModTime().Equal(p.modTime) || info.Size() != p.size
+}
@@ -0,0 +1,178 @@
+package rates
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Rhymond/go-money"
+	"github.com/govalues/decimal"
+)
+
+func TestCryptoFileProvider(t *testing.T) {
+	usd, wbtc := GetCurrency("USD"), GetCurrency("WBTC")
+
+	write := func(t *testing.T, path, config string) {
+		t.Helper()
+
+		if err := os.WriteFile(path, []byte(config), 0o644); err != nil {
+			t.Fatalf("writing config: %v", err)
+		}
+	}
+
+	newProvider := func(t *testing.T) (*CryptoFileProvider, string) {
+		path := filepath.Join(t.TempDir(), "crypto.json")
+		write(t, path, `{"tokens": {"WBTC": {"decimals": 8, "usd": "57037.22"}, "USDT": {"decimals": 6, "usd": "0.999"}}}`)
+
+		p, err := NewCryptoFileProvider(path)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		return p, path
+	}
+
+	rate := func(t *testing.T, p *CryptoFileProvider, from, to *money.Currency) decimal.Decimal {
+		t.Helper()
+
+		rates, err := p.Rates(t.Context(), from, to)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		r, ok := rates.For(from, to)
+		if !ok {
+			t.Fatalf("Expected %s/%s rate in %v", from.Code, to.Code, rates.Rates())
+		}
+
+		return r.Rate
+	}
+
+	t.Run("rates", func(t *testing.T) {
+		p, _ := newProvider(t)
+
+		if got := rate(t, p, wbtc, usd); !got.Equal(decimal.MustParse("57037.22")) {
+			t.Fatalf("Expected WBTC/USD rate 57037.22 got %s", got)
+		}
+
+		supported, _ := p.SupportedCurrencies(t.Context())
+		if codes := strings.Join(currencyCodes(supported), ","); codes != "USD,USDT,WBTC" {
+			t.Fatalf("Expected USD, USDT and WBTC supported got %s", codes)
+		}
+	})
+
+	t.Run("reload_adds_tokens", func(t *testing.T) {
+		p, path := newProvider(t)
+
+		write(t, path, `{"tokens": {"WBTC": {"decimals": 8, "usd": "60000"}, "PEPEX": {"symbol": "PX", "decimals": 18, "usd": "0.000012"}}}`)
+		if err := p.Reload(); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		pepe := GetCurrency("PEPEX")
+		if pepe == nil || pepe.Fraction != 18 || pepe.Grapheme != "PX" {
+			t.Fatalf("Expected PEPEX registered with 18 decimals got %+v", pepe)
+		}
+		if got := rate(t, p, wbtc, usd); !got.Equal(decimal.MustParse("60000")) {
+			t.Fatalf("Expected WBTC/USD rate 60000 got %s", got)
+		}
+		if got := rate(t, p, pepe, usd); !got.Equal(decimal.MustParse("0.000012")) {
+			t.Fatalf("Expected PEPEX/USD rate 0.000012 got %s", got)
+		}
+
+		if _, err := p.Rates(t.Context(), GetCurrency("USDT"), usd); !errors.Is(err, ErrUnsupportedCurrency) {
+			t.Fatalf("Expected the removed USDT to be unsupported got %v", err)
+		}
+
+		write(t, path, `{"tokens": {"WBTC": {"decimals": 8, "usd": "60000"}}}`)
+		if err := p.Reload(); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if pepe := GetCurrency("PEPEX"); pepe != nil {
+			t.Fatalf("Expected the removed PEPEX to be unknown got %+v", pepe)
+		}
+	})
+
+	t.Run("rejects_other_changes", func(t *testing.T) {
+		p, _ := newProvider(t)
+
+		if _, err := p.SetPrice("WBTC", decimal.MustParse("1"), CryptoChange{Author: "ops", Reason: "incident"}); !errors.Is(err, ErrCryptoRatesManaged) {
+			t.Fatalf("Expected ErrCryptoRatesManaged got %v", err)
+		}
+		if got := rate(t, p, wbtc, usd); !got.Equal(decimal.MustParse("57037.22")) {
+			t.Fatalf("Expected the WBTC/USD rate of the file 57037.22 got %s", got)
+		}
+	})
+
+	t.Run("non_fiat_codes", func(t *testing.T) {
+		p, path := newProvider(t)
+
+		// Codes known to money that aren't ISO fiat currencies can be listed, with the decimals of the config.
+		write(t, path, `{"tokens": {"WBTC": {"decimals": 8, "usd": "57037.22"}, "XPD": {"decimals": 6, "usd": "1021.5"}}}`)
+		if err := p.Reload(); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		if xpd := GetCurrency("XPD"); xpd == nil || xpd.Fraction != 6 {
+			t.Fatalf("Expected XPD registered with 6 decimals got %+v", xpd)
+		}
+	})
+
+	t.Run("invalid_reload_keeps_last_good", func(t *testing.T) {
+		for name, config := range map[string]string{
+			"malformed":      `{"tokens": {"WBTC": `,
+			"no_tokens":      `{"tokens": {}}`,
+			"unknown_field":  `{"tokens": {"WBTC": {"decimals": 8, "usd": "1", "price": "1"}}}`,
+			"fiat_code":      `{"tokens": {"EUR": {"decimals": 2, "usd": "1.17"}}}`,
+			"x_fiat_code":    `{"tokens": {"XAF": {"decimals": 0, "usd": "0.0018"}}}`,
+			"lower_case":     `{"tokens": {"wbtc": {"decimals": 8, "usd": "1"}}}`,
+			"decimals":       `{"tokens": {"WBTC": {"decimals": 19, "usd": "1"}}}`,
+			"non_positive":   `{"tokens": {"WBTC": {"decimals": 8, "usd": "0"}}}`,
+			"negative_price": `{"tokens": {"WBTC": {"decimals": 8, "usd": "-1"}}}`,
+		} {
+			t.Run(name, func(t *testing.T) {
+				p, path := newProvider(t)
+
+				write(t, path, config)
+				if err := p.Reload(); err == nil {
+					t.Fatalf("Expected an error reloading %s", config)
+				}
+
+				if got := rate(t, p, wbtc, usd); !got.Equal(decimal.MustParse("57037.22")) {
+					t.Fatalf("Expected the last good WBTC/USD rate 57037.22 got %s", got)
+				}
+			})
+		}
+	})
+
+	t.Run("watch", func(t *testing.T) {
+		p, path := newProvider(t)
+
+		ctx, cancel := context.WithCancel(t.Context())
+		defer cancel()
+		go p.Watch(ctx, 10*time.Millisecond)
+
+		write(t, path, `{"tokens": {"WBTC": {"decimals": 8, "usd": "61000.5"}}}`)
+
+		deadline := time.Now().Add(5 * time.Second)
+		for !rate(t, p, wbtc, usd).Equal(decimal.MustParse("61000.5")) {
+			if time.Now().After(deadline) {
+				t.Fatalf("Expected the changed file to be reloaded")
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	})
+
+	t.Run("invalid_initial_config", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "crypto.json")
+		write(t, path, `{"tokens": {}}`)
+
+		if _, err := NewCryptoFileProvider(path); err == nil {
+			t.Fatalf("Expected an error loading an invalid config")
+		}
+	})
+}
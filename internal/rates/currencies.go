@@ -0,0 +1,95 @@
+package rates
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/Rhymond/go-money"
+)
+
+// registry holds the currencies registered while the server runs, e.g. the tokens of a reloaded crypto config.
+// money keeps its currencies in a map without a lock, so they can't be added to it once requests are served.
+// builtin holds the currencies known from the start, e.g. the fixed tokens, they are known again once unregistered.
+var registry = struct {
+	sync.RWMutex
+	byCode  map[string]*money.Currency
+	builtin map[string]*money.Currency
+}{byCode: make(map[string]*money.Currency), builtin: make(map[string]*money.Currency)}
+
+// GetCurrency returns the currency with the code, either registered with RegisterCurrency, known from the start or
+// known to money. It returns nil when there is none.
+func GetCurrency(code string) *money.Currency {
+	code = strings.ToUpper(code)
+
+	registry.RLock()
+	c, ok := registry.byCode[code]
+	if !ok {
+		c, ok = registry.builtin[code]
+	}
+	registry.RUnlock()
+
+	if ok {
+		return c
+	}
+
+	return money.GetCurrency(code)
+}
+
+// RegisterCurrency registers a currency with the code, symbol and number of decimal places, replacing the one
+// registered with the code before. Currencies already handed out are not changed.
+func RegisterCurrency(code, symbol string, decimals int) *money.Currency {
+	c := newCurrency(code, symbol, decimals)
+
+	registry.Lock()
+	registry.byCode[c.Code] = c
+	registry.Unlock()
+
+	return c
+}
+
+// registerBuiltinCurrency makes a currency with the code, symbol and number of decimal places known from the start.
+// A currency registered with RegisterCurrency under the same code takes precedence over it.
+func registerBuiltinCurrency(code, symbol string, decimals int) {
+	c := newCurrency(code, symbol, decimals)
+
+	registry.Lock()
+	registry.builtin[c.Code] = c
+	registry.Unlock()
+}
+
+func newCurrency(code, symbol string, decimals int) *money.Currency {
+	return &money.Currency{
+		Code:     strings.ToUpper(code),
+		Grapheme: symbol,
+		Template: "1 $",
+		Decimal:  ".",
+		Thousand: ",",
+		Fraction: decimals,
+	}
+}
+
+// unregisterCurrency forgets the currency registered with the code, the one known from the start or money's one is
+// returned for it again if there is one. Currencies already handed out are not changed.
+func unregisterCurrency(code string) {
+	registry.Lock()
+	delete(registry.byCode, strings.ToUpper(code))
+	registry.Unlock()
+}
+
+// nonFiatCodes are the ISO 4217 codes that aren't the currency of any country: precious metals, bond market units,
+// special drawing rights, testing and no currency.
+var nonFiatCodes = map[string]struct{}{
+	"XAG": {}, "XAU": {}, "XBA": {}, "XBB": {}, "XBC": {}, "XBD": {}, "XDR": {}, "XPD": {}, "XPT": {}, "XSU": {},
+	"XTS": {}, "XUA": {}, "XXX": {},
+}
+
+// isFiat reports whether code is the ISO 4217 code of a fiat currency. money knows the ISO currencies by their
+// numeric code, currencies added to it without one, e.g. BTC or CNH, are not ISO currencies.
+func isFiat(code string) bool {
+	if _, ok := nonFiatCodes[code]; ok {
+		return false
+	}
+
+	c := money.GetCurrency(code)
+	return c != nil && c.NumericCode != ""
+}
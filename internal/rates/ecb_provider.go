@@ -0,0 +1,266 @@
+package rates
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Rhymond/go-money"
+	"github.com/govalues/decimal"
+)
+
+// Sources of the European Central Bank euro foreign exchange reference rates.
+const (
+	// ECBDailyURL serves the reference rates of the latest business day.
+	ECBDailyURL = "https://www.ecb.europa.eu/stats/eurofxref/eurofxref-daily.xml"
+
+	// ECBNinetyDaysURL serves the reference rates of the last 90 days.
+	ECBNinetyDaysURL = "https://www.ecb.europa.eu/stats/eurofxref/eurofxref-hist-90d.xml"
+
+	// ECBHistoryURL serves all reference rates since 1999.
+	ECBHistoryURL = "https://www.ecb.europa.eu/stats/eurofxref/eurofxref-hist.xml"
+)
+
+// ecbName identifies the provider in errors.
+const ecbName = "ecb"
+
+// ecbCacheTTL is how long a downloaded document is served from memory, the ECB publishes new rates once a business day.
+const ecbCacheTTL = time.Hour
+
+// ecbSince is the first day the ECB published reference rates on.
+var ecbSince = time.Date(1999, time.January, 4, 0, 0, 0, 0, time.UTC)
+
+// ECBProvider provides the European Central Bank euro foreign exchange reference rates.
+// It reads any of the eurofxref documents and serves rates of the latest day found in it,
+// and of the past days it covers from RatesAt.
+type ECBProvider struct {
+	client *http.Client
+	url    string
+	now    func() time.Time
+
+	mu     sync.Mutex
+	cached *ecbDocument
+}
+
+// ecbDocument holds the parsed reference rates, with days ordered from the latest.
+type ecbDocument struct {
+	days      []ecbDay
+	fetchedAt time.Time
+}
+
+// ecbDay holds the reference rates published for a single day, as the amount of each currency worth one euro.
+type ecbDay struct {
+	date  time.Time
+	rates map[string]decimal.Decimal
+}
+
+// NewECBProvider returns a provider reading reference rates from url, e.g. ECBDailyURL.
+func NewECBProvider(cli *http.Client, url string) *ECBProvider {
+	return &ECBProvider{
+		client: cli,
+		url:    url,
+		now:    time.Now,
+	}
+}
+
+func (e *ECBProvider) SupportedCurrencies(ctx context.Context) ([]*money.Currency, error) {
+	doc, _, err := e.document(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("getting reference rates: %w", err)
+	}
+
+	out := make([]*money.Currency, 0, len(doc.days[0].rates))
+	for code := range doc.days[0].rates {
+		if currency := GetCurrency(code); currency != nil {
+			out = append(out, currency)
+		}
+	}
+
+	slices.SortFunc(out, func(a, b *money.Currency) int {
+		return strings.Compare(a.Code, b.Code)
+	})
+
+	return out, nil
+}
+
+func (e *ECBProvider) Rates(ctx context.Context, c1, c2 *money.Currency, c ...*money.Currency) (*Matrix, error) {
+	currencies := []*money.Currency{c1, c2}
+	currencies = append(currencies, c...)
+
+	doc, cached, err := e.document(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("getting reference rates: %w", err)
+	}
+
+	rates, err := crossRates(money.EUR, doc.days[0].rates, currencyCodes(currencies), doc.provenance(doc.days[0], cached))
+	if err != nil {
+		return nil, fmt.Errorf("getting rates: %w", err)
+	}
+
+	return rates, nil
+}
+
+// Quotes returns the latest reference rates as quotes of EUR, downloaded rather than served from cache.
+func (e *ECBProvider) Quotes(ctx context.Context) (*Matrix, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	doc, err := e.fetch(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("getting reference rates: %w", err)
+	}
+
+	e.cached = doc
+
+	return allQuotes(money.EUR, midPrices(doc.days[0].rates), doc.provenance(doc.days[0], false), false)
+}
+
+// Since returns the first day the ECB published reference rates on. Whether a day
+// is actually covered depends on the document the provider reads.
+func (e *ECBProvider) Since() time.Time {
+	return ecbSince
+}
+
+// RatesAt returns cross rates of the reference rates published on date. It fails with ErrNoData for days
+// the ECB didn't publish rates on, and with ErrDateOutOfRange for days before the document starts.
+func (e *ECBProvider) RatesAt(ctx context.Context, date time.Time, c1, c2 *money.Currency, c ...*money.Currency) (*Matrix, error) {
+	currencies := []*money.Currency{c1, c2}
+	currencies = append(currencies, c...)
+
+	if err := CheckDate(e, date, e.now()); err != nil {
+		return nil, err
+	}
+
+	doc, cached, err := e.document(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("getting reference rates: %w", err)
+	}
+
+	day, err := doc.day(Day(date))
+	if err != nil {
+		return nil, err
+	}
+
+	rates, err := crossRates(money.EUR, day.rates, currencyCodes(currencies), doc.provenance(*day, cached))
+	if err != nil {
+		return nil, fmt.Errorf("getting rates: %w", err)
+	}
+
+	return rates, nil
+}
+
+// day returns the rates published on date.
+func (d *ecbDocument) day(date time.Time) (*ecbDay, error) {
+	oldest := d.days[len(d.days)-1].date
+	if date.Before(oldest) {
+		return nil, fmt.Errorf("%s is before %s: %w", date.Format(time.DateOnly), oldest.Format(time.DateOnly), ErrDateOutOfRange)
+	}
+
+	i, found := slices.BinarySearchFunc(d.days, date, func(day ecbDay, date time.Time) int {
+		return date.Compare(day.date)
+	})
+	if !found {
+		return nil, fmt.Errorf("%s: %w", date.Format(time.DateOnly), ErrNoData)
+	}
+
+	return &d.days[i], nil
+}
+
+// provenance returns the provenance of the rates of day, as of the day they were published for.
+func (d *ecbDocument) provenance(day ecbDay, cached bool) provenance {
+	return provenance{asOf: day.date, fetchedAt: d.fetchedAt, cached: cached}
+}
+
+// document returns the parsed reference rates, downloading them when the cached copy is missing or expired.
+// It reports whether the cached copy was returned.
+func (e *ECBProvider) document(ctx context.Context) (*ecbDocument, bool, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.cached != nil && e.now().Sub(e.cached.fetchedAt) < ecbCacheTTL {
+		return e.cached, true, nil
+	}
+
+	doc, err := e.fetch(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+
+	e.cached = doc
+
+	return doc, false, nil
+}
+
+func (e *ECBProvider) fetch(ctx context.Context) (*ecbDocument, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, e.url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("making request: %w: %w", ErrUnavailable, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		upErr := &UpstreamError{Provider: ecbName, StatusCode: resp.StatusCode}
+		if resp.StatusCode >= http.StatusInternalServerError {
+			upErr.Err = ErrUnavailable
+		}
+		return nil, upErr
+	}
+
+	var raw struct {
+		Cube struct {
+			Days []struct {
+				Time  string `xml:"time,attr"`
+				Rates []struct {
+					Currency string          `xml:"currency,attr"`
+					Rate     decimal.Decimal `xml:"rate,attr"`
+				} `xml:"Cube"`
+			} `xml:"Cube"`
+		} `xml:"Cube"`
+	}
+
+	if err := xml.NewDecoder(resp.Body).Decode(&raw); err != nil {
+		return nil, fmt.Errorf("decoding data: %w", err)
+	}
+
+	days := make([]ecbDay, 0, len(raw.Cube.Days))
+	for _, d := range raw.Cube.Days {
+		date, err := time.Parse(time.DateOnly, d.Time)
+		if err != nil {
+			return nil, fmt.Errorf("parsing date %q: %w", d.Time, err)
+		}
+
+		day := ecbDay{
+			date:  date,
+			rates: make(map[string]decimal.Decimal, len(d.Rates)+1),
+		}
+		for _, r := range d.Rates {
+			day.rates[r.Currency] = r.Rate
+		}
+		day.rates[money.EUR] = decimal.One
+
+		days = append(days, day)
+	}
+
+	if len(days) == 0 {
+		return nil, fmt.Errorf("no rates found")
+	}
+
+	slices.SortFunc(days, func(a, b ecbDay) int {
+		return b.date.Compare(a.date)
+	})
+
+	return &ecbDocument{
+		days:      days,
+		fetchedAt: e.now(),
+	}, nil
+}
@@ -0,0 +1,109 @@
+package rates
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Rhymond/go-money"
+	"github.com/govalues/decimal"
+)
+
+func TestECBProvider(t *testing.T) {
+	srv := httptest.NewServer(http.FileServer(http.Dir("testdata/ecb")))
+	defer srv.Close()
+
+	eur, usd, gbp, pln := money.GetCurrency("EUR"), money.GetCurrency("USD"), money.GetCurrency("GBP"), money.GetCurrency("PLN")
+
+	for _, file := range []string{"eurofxref-daily.xml", "eurofxref-hist-90d.xml"} {
+		t.Run(file, func(t *testing.T) {
+			prov := NewECBProvider(srv.Client(), srv.URL+"/"+file)
+
+			rates, err := prov.Rates(t.Context(), usd, gbp, eur, pln)
+			if err != nil {
+				t.Fatalf("err: %v", err)
+			}
+
+			if rates.Len() != 12 {
+				t.Fatalf("Expected 12 rates got %d: %v", rates.Len(), rates.Rates())
+			}
+
+			expected := map[[2]*money.Currency]string{
+				{eur, usd}: "1.1787",
+				{usd, eur}: "0.8483922965979468906",
+				{usd, gbp}: "0.7291931789259353525",
+				{gbp, pln}: "4.935776614310645724",
+			}
+
+			for pair, want := range expected {
+				rate, ok := rates.For(pair[0], pair[1])
+				if !ok {
+					t.Fatalf("Expected rate for %s/%s", pair[0].Code, pair[1].Code)
+				}
+				if !rate.Rate.Equal(decimal.MustParse(want)) {
+					t.Fatalf("Expected %s/%s rate %s got %s", pair[0].Code, pair[1].Code, want, rate.Rate)
+				}
+			}
+
+			supported, err := prov.SupportedCurrencies(t.Context())
+			if err != nil {
+				t.Fatalf("err: %v", err)
+			}
+			if len(supported) != 31 {
+				t.Fatalf("Expected 31 supported currencies got %d", len(supported))
+			}
+
+			if _, err := prov.Rates(t.Context(), eur, money.GetCurrency("BTC")); !errors.Is(err, ErrUnsupportedCurrency) {
+				t.Fatalf("Expected %v got %v", ErrUnsupportedCurrency, err)
+			}
+		})
+	}
+
+	t.Run("quotes", func(t *testing.T) {
+		prov := NewECBProvider(srv.Client(), srv.URL+"/eurofxref-daily.xml")
+
+		quotes, err := prov.Quotes(t.Context())
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		rate, ok := quotes.For(eur, usd)
+		if !ok || !rate.Rate.Equal(decimal.MustParse("1.1787")) || rate.Cached {
+			t.Fatalf("Expected fetched EUR/USD quote 1.1787 got %v", rate)
+		}
+		if _, ok := quotes.For(usd, gbp); ok {
+			t.Fatalf("Expected only quotes of EUR got %v", quotes.Rates())
+		}
+	})
+
+	t.Run("rates_at", func(t *testing.T) {
+		prov := NewECBProvider(srv.Client(), srv.URL+"/eurofxref-hist-90d.xml")
+
+		rates, err := prov.RatesAt(t.Context(), time.Date(2025, 6, 27, 0, 0, 0, 0, time.UTC), eur, usd)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		rate, ok := rates.For(eur, usd)
+		if !ok || !rate.Rate.Equal(decimal.MustParse("1.1728")) {
+			t.Fatalf("Expected EUR/USD rate 1.1728 got %v", rates)
+		}
+
+		tests := []struct {
+			date time.Time
+			want error
+		}{
+			{date: time.Date(2025, 6, 28, 0, 0, 0, 0, time.UTC), want: ErrNoData},
+			{date: time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC), want: ErrDateOutOfRange},
+			{date: time.Date(1998, 12, 31, 0, 0, 0, 0, time.UTC), want: ErrDateOutOfRange},
+		}
+
+		for _, tc := range tests {
+			if _, err := prov.RatesAt(t.Context(), tc.date, eur, usd); !errors.Is(err, tc.want) {
+				t.Fatalf("%s: expected %v got %v", tc.date.Format(time.DateOnly), tc.want, err)
+			}
+		}
+	})
+}
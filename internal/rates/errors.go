@@ -0,0 +1,118 @@
+package rates
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+)
+
+var (
+	// ErrUnsupportedCurrency is returned when a provider has no rate for a requested currency.
+	ErrUnsupportedCurrency = errors.New("unsupported currency")
+
+	// ErrFutureDate is returned when rates are requested for a day that has not happened yet.
+	ErrFutureDate = errors.New("date is in the future")
+
+	// ErrDateOutOfRange is returned when rates are requested for a day before a provider's coverage.
+	ErrDateOutOfRange = errors.New("date is out of range")
+
+	// ErrNoData is returned when a provider published no rates for a requested day, e.g. on weekends and holidays.
+	ErrNoData = errors.New("no rates published for date")
+
+	// ErrRangeTooLong is returned when rates are requested for more days than allowed at once.
+	ErrRangeTooLong = errors.New("date range too long")
+
+	// ErrInvalidRange is returned when a date range ends before it starts.
+	ErrInvalidRange = errors.New("date range ends before it starts")
+
+	// ErrNoHistory is returned when historical rates are requested from a provider that doesn't have them.
+	ErrNoHistory = errors.New("historical rates not supported")
+
+	// ErrMissingAppID is returned when the upstream API was called without credentials.
+	ErrMissingAppID = errors.New("missing app id")
+
+	// ErrInvalidAppID is returned when the upstream API rejected the configured credentials.
+	ErrInvalidAppID = errors.New("invalid app id")
+
+	// ErrNotAllowed is returned when the configured account is not allowed to use the requested feature.
+	ErrNotAllowed = errors.New("not allowed")
+
+	// ErrQuotaExceeded is returned when the configured account ran out of requests.
+	ErrQuotaExceeded = errors.New("quota exceeded")
+
+	// ErrInvalidBase is returned when the upstream API does not support the requested base currency.
+	ErrInvalidBase = errors.New("invalid base currency")
+
+	// ErrUnavailable is returned when the upstream API could not be reached or failed on its side.
+	ErrUnavailable = errors.New("upstream unavailable")
+
+	// ErrNoConsensus is returned when fewer than a quorum of providers answer with rates, or agree on the rate of a pair.
+	ErrNoConsensus = errors.New("no consensus among rates providers")
+
+	// ErrRateRejected is returned when a provider answers with a rate its guardrails reject and there is no rate
+	// accepted before to fall back to.
+	ErrRateRejected = errors.New("rate rejected by guardrails")
+
+	// ErrInvalidCryptoRates is returned when crypto rates are loaded or changed into an invalid config,
+	// or changed without an author and a reason.
+	ErrInvalidCryptoRates = errors.New("invalid crypto rates")
+
+	// ErrTokenNotFound is returned when a change refers to a token that is not listed.
+	ErrTokenNotFound = errors.New("token not found")
+
+	// ErrTokenExists is returned when a token is listed again.
+	ErrTokenExists = errors.New("token already listed")
+
+	// ErrCryptoRatesManaged is returned when crypto rates loaded from a file are changed other than by reloading it.
+	ErrCryptoRatesManaged = errors.New("crypto rates managed by a file")
+
+	// ErrVersionNotFound is returned when crypto rates are rolled back to a version that does not exist.
+	ErrVersionNotFound = errors.New("crypto rates version not found")
+)
+
+// UpstreamError describes an error response returned by a provider's upstream API.
+type UpstreamError struct {
+	// Provider is the name of the provider that called the upstream API.
+	Provider string
+
+	// StatusCode is the HTTP status code of the response.
+	StatusCode int
+
+	// Message is the machine-readable error code reported by the upstream API, if any.
+	Message string
+
+	// Description is the human-readable explanation reported by the upstream API, if any.
+	Description string
+
+	// Err is one of the sentinel errors of this package classifying the failure, nil if it is not recognized.
+	Err error
+}
+
+func (e *UpstreamError) Error() string {
+	msg := fmt.Sprintf("%s responded with status %d", e.Provider, e.StatusCode)
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	if e.Description != "" {
+		msg += " (" + e.Description + ")"
+	}
+	return msg
+}
+
+func (e *UpstreamError) Unwrap() error {
+	return e.Err
+}
+
+func (e *UpstreamError) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("provider", e.Provider),
+		slog.Int("status", e.StatusCode),
+		slog.String("message", e.Message),
+		slog.String("description", e.Description),
+	)
+}
+
+// IsTransient reports whether err is a failure that may go away when the request is retried.
+func IsTransient(err error) bool {
+	return errors.Is(err, ErrUnavailable)
+}
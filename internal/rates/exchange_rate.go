@@ -2,6 +2,8 @@ package rates
 
 import (
 	"fmt"
+	"slices"
+	"time"
 
 	"github.com/Rhymond/go-money"
 	"github.com/govalues/decimal"
@@ -15,22 +17,177 @@ type ExchangeRate struct {
 	// To represents the target currency in the exchange rate.
 	To *money.Currency
 
-	// Rate represents the conversion rate between two currencies in an exchange rate.
+	// Rate represents the conversion rate between two currencies in an exchange rate, the mid rate when the rate is
+	// quoted with Bid and Ask.
 	Rate decimal.Decimal
+
+	// Bid is the amount of To received for selling one unit of From. Zero, along with Ask, for rates quoted at a
+	// single rate.
+	Bid decimal.Decimal
+
+	// Ask is the amount of To paid for buying one unit of From. Zero, along with Bid, for rates quoted at a single rate.
+	Ask decimal.Decimal
+
+	// AsOf is the time upstream says the rate was in effect at, e.g. the timestamp of an Open Exchange Rates table or
+	// the day of a central bank table, zero when it is not known. For a rate derived from Legs, it is the oldest of theirs.
+	AsOf time.Time
+
+	// FetchedAt is the time the rate was fetched from upstream, zero for rates that are not fetched.
+	FetchedAt time.Time
+
+	// Cached reports whether the rate was served from a copy fetched before it was asked for.
+	// For a rate derived from Legs, it reports whether any of them was.
+	Cached bool
+
+	// Source is the name of the provider that answered with the rate, empty when it is not known.
+	// For a rate derived from Legs, it is the sources of the legs joined with "+".
+	Source string
+
+	// Consensus describes how the rate was agreed on by several providers, nil for rates answered by a single one.
+	Consensus *Consensus
+
+	// Legs are the rates a cross rate was derived from, in order, each with its own Source. Nil for rates quoted directly.
+	Legs []ExchangeRate
 }
 
 func (r ExchangeRate) String() string {
 	return fmt.Sprintf("%q => %q (%v)", r.From.Code, r.To.Code, r.Rate.String())
 }
 
-type ExchangeRates []ExchangeRate
+// TwoSided reports whether the rate is quoted with Bid and Ask.
+func (r ExchangeRate) TwoSided() bool {
+	return !r.Bid.IsZero() && !r.Ask.IsZero()
+}
+
+// SellRate returns the amount of To received for selling one unit of From: Bid for two-sided rates, Rate otherwise.
+func (r ExchangeRate) SellRate() decimal.Decimal {
+	if r.TwoSided() {
+		return r.Bid
+	}
+
+	return r.Rate
+}
+
+// BuyRate returns the amount of To paid for buying one unit of From: Ask for two-sided rates, Rate otherwise.
+func (r ExchangeRate) BuyRate() decimal.Decimal {
+	if r.TwoSided() {
+		return r.Ask
+	}
+
+	return r.Rate
+}
+
+// Derived reports whether the rate was derived from Legs rather than quoted directly.
+func (r ExchangeRate) Derived() bool {
+	return len(r.Legs) > 0
+}
+
+// Path returns the codes of the currencies the rate goes through, from From to To, e.g. ["GBP", "USD", "EUR"] for a
+// rate derived through USD.
+func (r ExchangeRate) Path() []string {
+	if !r.Derived() {
+		return []string{r.From.Code, r.To.Code}
+	}
+
+	out := []string{r.From.Code}
+	for _, leg := range r.Legs {
+		path := leg.Path()
+		out = append(out, path[1:]...)
+	}
+
+	return out
+}
+
+// Invert returns the rate from To to From, with the legs it was derived from inverted in reverse order.
+// Selling To is buying From, so the bid of the inverted rate is the inverted ask, and the other way around.
+func (r ExchangeRate) Invert() (ExchangeRate, error) {
+	rate, err := decimal.One.Quo(r.Rate)
+	if err != nil {
+		return ExchangeRate{}, fmt.Errorf("inverting rate for %q and %q: %w", r.From.Code, r.To.Code, err)
+	}
+
+	out := r
+	out.From, out.To, out.Rate = r.To, r.From, rate
+
+	if r.TwoSided() {
+		if out.Bid, err = decimal.One.Quo(r.Ask); err != nil {
+			return ExchangeRate{}, fmt.Errorf("inverting ask for %q and %q: %w", r.From.Code, r.To.Code, err)
+		}
+		if out.Ask, err = decimal.One.Quo(r.Bid); err != nil {
+			return ExchangeRate{}, fmt.Errorf("inverting bid for %q and %q: %w", r.From.Code, r.To.Code, err)
+		}
+	}
+
+	if len(r.Legs) > 0 {
+		out.Legs = make([]ExchangeRate, len(r.Legs))
+		for i, leg := range r.Legs {
+			if out.Legs[len(r.Legs)-1-i], err = leg.Invert(); err != nil {
+				return ExchangeRate{}, err
+			}
+		}
+	}
+
+	return out, nil
+}
+
+// withSource returns r with its Source, and those of the legs it was derived from, set to name when they have none.
+func (r ExchangeRate) withSource(name string) ExchangeRate {
+	if r.Source == "" {
+		r.Source = name
+	}
+
+	if len(r.Legs) > 0 {
+		legs := make([]ExchangeRate, len(r.Legs))
+		for i, leg := range r.Legs {
+			legs[i] = leg.withSource(name)
+		}
+		r.Legs = legs
+	}
+
+	return r
+}
+
+// oldest returns the oldest of the times of rates returned by at, skipping zero ones.
+// It reports false when all of them are zero.
+func oldest(rates []ExchangeRate, at func(ExchangeRate) time.Time) (time.Time, bool) {
+	var out time.Time
+	for _, rate := range rates {
+		t := at(rate)
+		if t.IsZero() {
+			continue
+		}
+		if out.IsZero() || t.Before(out) {
+			out = t
+		}
+	}
+
+	return out, !out.IsZero()
+}
+
+func fetchedAt(r ExchangeRate) time.Time { return r.FetchedAt }
+
+func asOf(r ExchangeRate) time.Time { return r.AsOf }
+
+// anyCached reports whether any of rates was served from cache.
+func anyCached(rates []ExchangeRate) bool {
+	return slices.ContainsFunc(rates, func(r ExchangeRate) bool { return r.Cached })
+}
+
+// sources returns the distinct, non-empty sources of rates in order of appearance, those of the legs for derived rates.
+func sources(rates []ExchangeRate) []string {
+	var out []string
+	for _, rate := range rates {
+		names := []string{rate.Source}
+		if len(rate.Legs) > 0 {
+			names = sources(rate.Legs)
+		}
 
-func (r ExchangeRates) For(from, to *money.Currency) (ExchangeRate, bool) {
-	for _, rate := range r {
-		if rate.From.Code == from.Code && rate.To.Code == to.Code {
-			return rate, true
+		for _, name := range names {
+			if name != "" && !slices.Contains(out, name) {
+				out = append(out, name)
+			}
 		}
 	}
 
-	return ExchangeRate{}, false
+	return out
 }
@@ -0,0 +1,249 @@
+package rates
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Rhymond/go-money"
+)
+
+// supportedCurrenciesTTL is how long FailoverProvider remembers the currencies supported by each provider.
+const supportedCurrenciesTTL = time.Hour
+
+// NamedProvider is a Provider together with the name it is configured and reported under.
+type NamedProvider struct {
+	Name string
+	Provider
+}
+
+// FailoverProvider asks an ordered list of providers for rates and answers with the first one that succeeds.
+// Providers that don't support all requested currencies are skipped.
+type FailoverProvider struct {
+	providers []NamedProvider
+
+	// timeout bounds a single provider call, a non-positive value leaves it to the caller's context.
+	timeout time.Duration
+	now     func() time.Time
+
+	supported supportCache
+}
+
+func NewFailoverProvider(timeout time.Duration, providers ...NamedProvider) *FailoverProvider {
+	return &FailoverProvider{
+		providers: providers,
+		timeout:   timeout,
+		now:       time.Now,
+		supported: newSupportCache(),
+	}
+}
+
+// SupportedCurrencies returns currencies supported by any of the providers.
+func (f *FailoverProvider) SupportedCurrencies(ctx context.Context) ([]*money.Currency, error) {
+	uniq := map[string]*money.Currency{}
+	var errs []error
+
+	for _, p := range f.providers {
+		ctx, cancel := f.withTimeout(ctx)
+		currencies, err := p.SupportedCurrencies(ctx)
+		cancel()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", p.Name, err))
+			continue
+		}
+
+		for _, c := range currencies {
+			uniq[c.Code] = c
+		}
+	}
+
+	if len(uniq) == 0 && len(errs) > 0 {
+		return nil, fmt.Errorf("getting supported currencies: %w", errors.Join(errs...))
+	}
+
+	out := make([]*money.Currency, 0, len(uniq))
+	for _, c := range uniq {
+		out = append(out, c)
+	}
+
+	slices.SortFunc(out, func(a, b *money.Currency) int {
+		return strings.Compare(a.Code, b.Code)
+	})
+
+	return out, nil
+}
+
+// Rates returns rates from the first provider that supports all currencies and answers in time.
+// Each rate carries the name of the answering provider as its Source.
+func (f *FailoverProvider) Rates(ctx context.Context, c1, c2 *money.Currency, c ...*money.Currency) (*Matrix, error) {
+	return f.firstAnswer(ctx, c1, c2, c, func(ctx context.Context, p NamedProvider) (*Matrix, error) {
+		return p.Rates(ctx, c1, c2, c...)
+	})
+}
+
+// Since returns the first day any of the historical providers has rates for.
+func (f *FailoverProvider) Since() time.Time {
+	var since time.Time
+	for _, p := range f.providers {
+		hp, ok := p.Provider.(HistoricalProvider)
+		if !ok {
+			continue
+		}
+
+		if since.IsZero() || hp.Since().Before(since) {
+			since = hp.Since()
+		}
+	}
+
+	return since
+}
+
+// RatesAt returns rates as of date from the first historical provider covering it
+// that supports all currencies and answers in time.
+func (f *FailoverProvider) RatesAt(ctx context.Context, date time.Time, c1, c2 *money.Currency, c ...*money.Currency) (*Matrix, error) {
+	return f.firstAnswer(ctx, c1, c2, c, func(ctx context.Context, p NamedProvider) (*Matrix, error) {
+		hp, ok := p.Provider.(HistoricalProvider)
+		if !ok {
+			return nil, ErrNoHistory
+		}
+
+		if err := CheckDate(hp, date, f.now()); err != nil {
+			return nil, err
+		}
+
+		return hp.RatesAt(ctx, date, c1, c2, c...)
+	})
+}
+
+// firstAnswer calls get for every provider supporting all currencies in turn and returns the first rates it succeeds with.
+func (f *FailoverProvider) firstAnswer(
+	ctx context.Context,
+	c1, c2 *money.Currency, c []*money.Currency,
+	get func(ctx context.Context, p NamedProvider) (*Matrix, error),
+) (*Matrix, error) {
+	currencies := []*money.Currency{c1, c2}
+	currencies = append(currencies, c...)
+
+	var errs []error
+
+	for _, p := range f.providers {
+		if !f.supports(ctx, p, currencies) {
+			errs = append(errs, fmt.Errorf("%s: %w", p.Name, ErrUnsupportedCurrency))
+			continue
+		}
+
+		rates, err := f.call(ctx, p, get)
+		if err != nil {
+			slog.WarnContext(ctx, "rates provider failed, trying next", "provider", p.Name, "err", err)
+			errs = append(errs, fmt.Errorf("%s: %w", p.Name, err))
+
+			if ctx.Err() != nil {
+				break
+			}
+			continue
+		}
+
+		return rates.withSource(p.Name), nil
+	}
+
+	if len(errs) == 0 {
+		return nil, fmt.Errorf("no rates providers configured")
+	}
+
+	return nil, fmt.Errorf("all rates providers failed: %w", errors.Join(errs...))
+}
+
+func (f *FailoverProvider) call(ctx context.Context, p NamedProvider, get func(ctx context.Context, p NamedProvider) (*Matrix, error)) (*Matrix, error) {
+	ctx, cancel := f.withTimeout(ctx)
+	defer cancel()
+
+	return get(ctx, p)
+}
+
+// supports reports whether p supports all currencies. A provider whose
+// supported currencies cannot be determined is assumed to support them, and left to fail in Rates.
+func (f *FailoverProvider) supports(ctx context.Context, p NamedProvider, currencies []*money.Currency) bool {
+	ctx, cancel := f.withTimeout(ctx)
+	defer cancel()
+
+	codes, err := f.supported.codes(ctx, p, f.now())
+	if err != nil {
+		return true
+	}
+
+	for _, c := range currencies {
+		if _, ok := codes[c.Code]; !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (f *FailoverProvider) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if f.timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+
+	return context.WithTimeout(ctx, f.timeout)
+}
+
+// supportCache remembers the currencies supported by named providers for supportedCurrenciesTTL.
+type supportCache struct {
+	mu        sync.Mutex
+	supported map[string]supportedCurrencies
+}
+
+type supportedCurrencies struct {
+	codes     map[string]struct{}
+	fetchedAt time.Time
+}
+
+// liveCurrencies is implemented by providers answering SupportedCurrencies from memory with currencies that change
+// while they serve, e.g. on reload, so they are asked every time rather than cached.
+type liveCurrencies interface {
+	liveCurrencies()
+}
+
+func newSupportCache() supportCache {
+	return supportCache{supported: make(map[string]supportedCurrencies)}
+}
+
+// codes returns the codes of the currencies p supports as of now, asking p when they are not cached.
+// It reports false when they cannot be determined.
+func (s *supportCache) codes(ctx context.Context, p NamedProvider, now time.Time) (map[string]struct{}, error) {
+	_, live := p.Provider.(liveCurrencies)
+
+	s.mu.Lock()
+	supported, ok := s.supported[p.Name]
+	s.mu.Unlock()
+
+	if ok && !live && now.Sub(supported.fetchedAt) < supportedCurrenciesTTL {
+		return supported.codes, nil
+	}
+
+	list, err := p.SupportedCurrencies(ctx)
+	if err != nil {
+		slog.WarnContext(ctx, "getting supported currencies failed", "provider", p.Name, "err", err)
+		return nil, err
+	}
+
+	supported = supportedCurrencies{
+		codes:     make(map[string]struct{}, len(list)),
+		fetchedAt: now,
+	}
+	for _, c := range list {
+		supported.codes[c.Code] = struct{}{}
+	}
+
+	s.mu.Lock()
+	s.supported[p.Name] = supported
+	s.mu.Unlock()
+
+	return supported.codes, nil
+}
@@ -0,0 +1,108 @@
+package rates
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Rhymond/go-money"
+)
+
+type stubProvider struct {
+	Provider
+
+	err          error
+	supportedErr error
+	delay        time.Duration
+	calls        int
+}
+
+func (s *stubProvider) SupportedCurrencies(ctx context.Context) ([]*money.Currency, error) {
+	if s.supportedErr != nil {
+		return nil, s.supportedErr
+	}
+
+	return s.Provider.SupportedCurrencies(ctx)
+}
+
+func (s *stubProvider) Rates(ctx context.Context, c1, c2 *money.Currency, c ...*money.Currency) (*Matrix, error) {
+	s.calls++
+
+	select {
+	case <-time.After(s.delay):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	if s.err != nil {
+		return nil, s.err
+	}
+
+	return s.Provider.Rates(ctx, c1, c2, c...)
+}
+
+func TestFailoverProvider(t *testing.T) {
+	usd, eur, btc := money.GetCurrency("USD"), money.GetCurrency("EUR"), money.GetCurrency("BTC")
+	wbtc := GetCurrency("WBTC")
+
+	t.Run("falls_over_to_next_provider", func(t *testing.T) {
+		failing := &stubProvider{Provider: NewStaticRatesProvider(), err: ErrUnavailable}
+		slow := &stubProvider{Provider: NewStaticRatesProvider(), delay: time.Second}
+		answering := &stubProvider{Provider: NewStaticRatesProvider()}
+
+		prov := NewFailoverProvider(50*time.Millisecond,
+			NamedProvider{Name: "failing", Provider: failing},
+			NamedProvider{Name: "slow", Provider: slow},
+			NamedProvider{Name: "answering", Provider: answering},
+		)
+
+		rates, err := prov.Rates(t.Context(), usd, eur, btc)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		if failing.calls != 1 || slow.calls != 1 || answering.calls != 1 {
+			t.Fatalf("Expected every provider to be called once got %d, %d, %d", failing.calls, slow.calls, answering.calls)
+		}
+
+		if sources := rates.Sources(); len(sources) != 1 || sources[0] != "answering" {
+			t.Fatalf("Expected rates to come from %q got %v", "answering", sources)
+		}
+	})
+
+	t.Run("skips_providers_not_supporting_currencies", func(t *testing.T) {
+		static := &stubProvider{Provider: NewStaticRatesProvider()}
+		crypto := &stubProvider{Provider: NewFixedCryptoRatesProvider()}
+
+		prov := NewFailoverProvider(time.Second,
+			NamedProvider{Name: "static", Provider: static},
+			NamedProvider{Name: "crypto", Provider: crypto},
+		)
+
+		rates, err := prov.Rates(t.Context(), usd, wbtc)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		if static.calls != 0 {
+			t.Fatalf("Expected provider not supporting WBTC to be skipped")
+		}
+
+		if _, ok := rates.For(wbtc, usd); !ok {
+			t.Fatalf("Expected WBTC rate in %v", rates)
+		}
+	})
+
+	t.Run("reports_all_failures", func(t *testing.T) {
+		prov := NewFailoverProvider(time.Second,
+			NamedProvider{Name: "static", Provider: NewStaticRatesProvider()},
+			NamedProvider{Name: "failing", Provider: &stubProvider{Provider: NewFixedCryptoRatesProvider(), err: ErrQuotaExceeded}},
+		)
+
+		_, err := prov.Rates(t.Context(), usd, wbtc)
+		if !errors.Is(err, ErrUnsupportedCurrency) || !errors.Is(err, ErrQuotaExceeded) {
+			t.Fatalf("Expected both provider failures to be reported got %v", err)
+		}
+	})
+}
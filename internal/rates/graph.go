@@ -0,0 +1,268 @@
+package rates
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/Rhymond/go-money"
+	"github.com/govalues/decimal"
+)
+
+// Graph derives rates between currencies from directed quotes against any currencies, e.g. a table quoted against EUR
+// together with another quoted against BTC. A rate is derived along the shortest path of quotes between two
+// currencies, each quote usable in its direction or inverted in the opposite one. Of paths with as few quotes,
+// the one inverting the fewest is taken, then the one made of quotes added first.
+type Graph struct {
+	currencies map[string]*money.Currency
+
+	// hops holds the quotes leaving each currency, as added and inverted, in the order they were added.
+	hops map[string][]hop
+}
+
+type hop struct {
+	to       string
+	quote    ExchangeRate
+	inverted bool
+}
+
+func NewGraph() *Graph {
+	return &Graph{
+		currencies: make(map[string]*money.Currency),
+		hops:       make(map[string][]hop),
+	}
+}
+
+// Add adds quotes to the graph, each being the amount of To worth one unit of From, along with the Bid and Ask
+// around it for two-sided quotes.
+func (g *Graph) Add(quotes ...ExchangeRate) error {
+	for _, q := range quotes {
+		if q.From == nil || q.To == nil {
+			return fmt.Errorf("quote %v: missing currency", q.Rate)
+		}
+		if q.From.Code == q.To.Code {
+			return fmt.Errorf("quote %s: same currency on both sides", q)
+		}
+		if q.Rate.Sign() <= 0 {
+			return fmt.Errorf("quote %s: rate is not positive", q)
+		}
+		if !q.Bid.IsZero() || !q.Ask.IsZero() {
+			if q.Bid.Sign() <= 0 || q.Ask.Sign() <= 0 {
+				return fmt.Errorf("quote %s: bid %s and ask %s are not both positive", q, q.Bid, q.Ask)
+			}
+			if q.Bid.Cmp(q.Rate) > 0 || q.Rate.Cmp(q.Ask) > 0 {
+				return fmt.Errorf("quote %s: rate is not between bid %s and ask %s", q, q.Bid, q.Ask)
+			}
+		}
+
+		g.currencies[q.From.Code] = q.From
+		g.currencies[q.To.Code] = q.To
+		g.hops[q.From.Code] = append(g.hops[q.From.Code], hop{to: q.To.Code, quote: q})
+		g.hops[q.To.Code] = append(g.hops[q.To.Code], hop{to: q.From.Code, quote: q, inverted: true})
+	}
+
+	return nil
+}
+
+// Rate returns the rate between from and to derived along the shortest path of quotes. A rate derived from more
+// than one quote carries the quotes as Legs, in the direction they were used in, and their sources joined with "+".
+// It is as of the oldest of the quotes, and cached when any of them is.
+func (g *Graph) Rate(from, to *money.Currency) (ExchangeRate, error) {
+	if from.Code == to.Code {
+		return ExchangeRate{}, fmt.Errorf("rate for %q and %q: same currency on both sides", from.Code, to.Code)
+	}
+
+	return g.shortestPaths(from.Code).rate(from, to)
+}
+
+// Rates returns the rate between every ordered pair of currencies, ordered by From and then To.
+func (g *Graph) Rates(currencies []*money.Currency) (*Matrix, error) {
+	currencies = slices.Clone(currencies)
+	slices.SortFunc(currencies, func(a, b *money.Currency) int {
+		return strings.Compare(a.Code, b.Code)
+	})
+	currencies = slices.CompactFunc(currencies, func(a, b *money.Currency) bool {
+		return a.Code == b.Code
+	})
+
+	if len(currencies) < 2 {
+		return nil, fmt.Errorf("at least 2 distinct currencies required")
+	}
+
+	out := make([]ExchangeRate, 0, len(currencies)*(len(currencies)-1))
+	for _, from := range currencies {
+		paths := g.shortestPaths(from.Code)
+
+		for _, to := range currencies {
+			if from.Code == to.Code {
+				continue
+			}
+
+			rate, err := paths.rate(from, to)
+			if err != nil {
+				return nil, err
+			}
+
+			out = append(out, rate)
+		}
+	}
+
+	return NewMatrix(out...)
+}
+
+// cost orders paths by the number of quotes and then the number of inverted ones.
+type cost struct {
+	hops, inversions int
+}
+
+func (c cost) less(o cost) bool {
+	return c.hops < o.hops || c.hops == o.hops && c.inversions < o.inversions
+}
+
+// paths holds the shortest paths from a single currency, as the hop each currency is reached with.
+type paths struct {
+	from string
+	via  map[string]hop
+	prev map[string]string
+}
+
+// shortestPaths finds the shortest paths from the currency with the code from to every currency reachable from it.
+func (g *Graph) shortestPaths(from string) paths {
+	p := paths{from: from, via: make(map[string]hop), prev: make(map[string]string)}
+	if _, ok := g.currencies[from]; !ok {
+		return p
+	}
+
+	costs := map[string]cost{from: {}}
+	done := make(map[string]bool, len(g.currencies))
+
+	// The graphs are a few hundred currencies at most, a linear scan for the closest one is fast enough.
+	codes := slices.Sorted(maps.Keys(g.currencies))
+
+	for {
+		current, found := "", false
+		for _, code := range codes {
+			c, reached := costs[code]
+			if !reached || done[code] {
+				continue
+			}
+			if !found || c.less(costs[current]) {
+				current, found = code, true
+			}
+		}
+		if !found {
+			return p
+		}
+		done[current] = true
+
+		for _, h := range g.hops[current] {
+			next := costs[current]
+			next.hops++
+			if h.inverted {
+				next.inversions++
+			}
+
+			if c, reached := costs[h.to]; !reached || next.less(c) {
+				costs[h.to] = next
+				p.via[h.to] = h
+				p.prev[h.to] = current
+			}
+		}
+	}
+}
+
+// rate derives the rate between from and to along the shortest path to to. The quotes used as given are multiplied
+// and divided by the ones used inverted in a single division, so a rate between two currencies quoted against a
+// common one is as exact as dividing the quotes.
+//
+// When any of the quotes is two-sided, so is the rate. Selling from along the path sells at the bid of every quote
+// used as given and buys at the ask of every quote used inverted, so the bid is derived from those and the ask from
+// the opposite sides. One-sided quotes count at their rate on both sides.
+func (p paths) rate(from, to *money.Currency) (ExchangeRate, error) {
+	if _, ok := p.via[to.Code]; !ok || from.Code != p.from {
+		return ExchangeRate{}, fmt.Errorf("no quotes lead from %q to %q: %w", from.Code, to.Code, ErrUnsupportedCurrency)
+	}
+
+	var hops []hop
+	for code := to.Code; code != p.from; code = p.prev[code] {
+		hops = append(hops, p.via[code])
+	}
+	slices.Reverse(hops)
+
+	mid, bid, ask := unit(), unit(), unit()
+	twoSided := false
+	legs := make([]ExchangeRate, 0, len(hops))
+	for _, h := range hops {
+		leg := h.quote
+		twoSided = twoSided || leg.TwoSided()
+
+		var err error
+		if h.inverted {
+			err = errors.Join(mid.div(leg.Rate), bid.div(leg.BuyRate()), ask.div(leg.SellRate()))
+			if err == nil {
+				leg, err = leg.Invert()
+			}
+		} else {
+			err = errors.Join(mid.mul(leg.Rate), bid.mul(leg.SellRate()), ask.mul(leg.BuyRate()))
+		}
+		if err != nil {
+			return ExchangeRate{}, fmt.Errorf("deriving rate for %q and %q: %w", from.Code, to.Code, err)
+		}
+
+		legs = append(legs, leg)
+	}
+
+	if len(legs) == 1 {
+		return legs[0], nil
+	}
+
+	out := ExchangeRate{
+		From:   from,
+		To:     to,
+		Cached: anyCached(legs),
+		Source: strings.Join(sources(legs), "+"),
+		Legs:   legs,
+	}
+	out.AsOf, _ = oldest(legs, asOf)
+	out.FetchedAt, _ = oldest(legs, fetchedAt)
+
+	var err error
+	if out.Rate, err = mid.value(); err != nil {
+		return ExchangeRate{}, fmt.Errorf("deriving rate for %q and %q: %w", from.Code, to.Code, err)
+	}
+	if twoSided {
+		if out.Bid, err = bid.value(); err != nil {
+			return ExchangeRate{}, fmt.Errorf("deriving bid for %q and %q: %w", from.Code, to.Code, err)
+		}
+		if out.Ask, err = ask.value(); err != nil {
+			return ExchangeRate{}, fmt.Errorf("deriving ask for %q and %q: %w", from.Code, to.Code, err)
+		}
+	}
+
+	return out, nil
+}
+
+// fraction is a product of rates kept as a numerator and a denominator, so it is divided once.
+type fraction struct {
+	num, den decimal.Decimal
+}
+
+func unit() fraction {
+	return fraction{num: decimal.One, den: decimal.One}
+}
+
+func (f *fraction) mul(d decimal.Decimal) (err error) {
+	f.num, err = f.num.Mul(d)
+	return err
+}
+
+func (f *fraction) div(d decimal.Decimal) (err error) {
+	f.den, err = f.den.Mul(d)
+	return err
+}
+
+func (f fraction) value() (decimal.Decimal, error) {
+	return f.num.Quo(f.den)
+}
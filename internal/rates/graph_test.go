@@ -0,0 +1,258 @@
+package rates
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/Rhymond/go-money"
+	"github.com/govalues/decimal"
+)
+
+func TestGraph(t *testing.T) {
+	eur, usd, pln := money.GetCurrency("EUR"), money.GetCurrency("USD"), money.GetCurrency("PLN")
+	btc, wbtc, beer := money.GetCurrency("BTC"), GetCurrency("WBTC"), GetCurrency("BEER")
+
+	quote := func(from, to *money.Currency, rate, source string) ExchangeRate {
+		return ExchangeRate{From: from, To: to, Rate: decimal.MustParse(rate), Source: source}
+	}
+
+	// A fiat table quoted against EUR and a crypto table quoted against BTC, bridged by a single BTC/USD quote.
+	newGraph := func(t *testing.T) *Graph {
+		g := NewGraph()
+		if err := g.Add(
+			quote(eur, usd, "1.25", "ecb"),
+			quote(eur, pln, "4.25", "ecb"),
+			quote(wbtc, btc, "0.999", "dex"),
+			quote(beer, btc, "0.0000001", "dex"),
+			quote(btc, usd, "60000", "exchange"),
+		); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		return g
+	}
+
+	t.Run("single_quote", func(t *testing.T) {
+		g := newGraph(t)
+
+		rate, err := g.Rate(eur, usd)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if !rate.Rate.Equal(decimal.MustParse("1.25")) || rate.Source != "ecb" || rate.Legs != nil {
+			t.Fatalf("Expected the EUR/USD quote got %s from %q with %v", rate, rate.Source, rate.Legs)
+		}
+
+		inverted, err := g.Rate(usd, eur)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if !inverted.Rate.Equal(decimal.MustParse("0.8")) || inverted.From != usd || inverted.To != eur {
+			t.Fatalf("Expected the inverted EUR/USD quote got %s", inverted)
+		}
+	})
+
+	t.Run("common_quote_currency", func(t *testing.T) {
+		g := newGraph(t)
+
+		rate, err := g.Rate(usd, pln)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if !rate.Rate.Equal(decimal.MustParse("3.4")) {
+			t.Fatalf("Expected USD/PLN rate 3.4 got %s", rate.Rate)
+		}
+		if len(rate.Legs) != 2 || rate.Legs[0].From != usd || rate.Legs[0].To != eur || rate.Legs[1].From != eur || rate.Legs[1].To != pln {
+			t.Fatalf("Expected USD/EUR and EUR/PLN legs got %v", rate.Legs)
+		}
+		if rate.Source != "ecb" {
+			t.Fatalf("Expected source ecb got %q", rate.Source)
+		}
+	})
+
+	t.Run("path_across_tables", func(t *testing.T) {
+		g := newGraph(t)
+
+		rate, err := g.Rate(beer, pln)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		// BEER -> BTC -> USD -> EUR -> PLN: 0.0000001 * 60000 * 4.25 / 1.25
+		if !rate.Rate.Equal(decimal.MustParse("0.0204")) {
+			t.Fatalf("Expected BEER/PLN rate 0.0204 got %s", rate.Rate)
+		}
+
+		path := []*money.Currency{beer, btc, usd, eur, pln}
+		if len(rate.Legs) != len(path)-1 {
+			t.Fatalf("Expected %d legs got %v", len(path)-1, rate.Legs)
+		}
+		for i, leg := range rate.Legs {
+			if leg.From != path[i] || leg.To != path[i+1] {
+				t.Fatalf("Expected leg %d from %s to %s got %s", i, path[i].Code, path[i+1].Code, leg)
+			}
+		}
+		if rate.Source != "dex+exchange+ecb" {
+			t.Fatalf("Expected source dex+exchange+ecb got %q", rate.Source)
+		}
+	})
+
+	t.Run("provenance", func(t *testing.T) {
+		older := time.Date(2025, time.July, 1, 0, 0, 0, 0, time.UTC)
+		newer := older.Add(time.Hour)
+
+		g := NewGraph()
+		if err := g.Add(
+			ExchangeRate{From: eur, To: usd, Rate: decimal.MustParse("1.25"), AsOf: newer, FetchedAt: newer, Source: "ecb"},
+			ExchangeRate{From: eur, To: pln, Rate: decimal.MustParse("4.25"), AsOf: older, FetchedAt: newer, Cached: true, Source: "ecb"},
+		); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		rate, err := g.Rate(usd, pln)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if !rate.AsOf.Equal(older) || !rate.FetchedAt.Equal(newer) || !rate.Cached {
+			t.Fatalf("Expected rate as of %v, fetched at %v and cached got %v, %v and %v", older, newer, rate.AsOf, rate.FetchedAt, rate.Cached)
+		}
+		if !rate.Derived() || !slices.Equal(rate.Path(), []string{"USD", "EUR", "PLN"}) {
+			t.Fatalf("Expected rate derived through EUR got %v", rate.Path())
+		}
+
+		direct, err := g.Rate(eur, usd)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if direct.Derived() || direct.Cached || !slices.Equal(direct.Path(), []string{"EUR", "USD"}) {
+			t.Fatalf("Expected the EUR/USD quote got %v", direct.Path())
+		}
+	})
+
+	t.Run("prefers_shorter_paths", func(t *testing.T) {
+		g := newGraph(t)
+		if err := g.Add(quote(pln, btc, "0.000004", "exchange")); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		rate, err := g.Rate(beer, pln)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if len(rate.Legs) != 2 || rate.Legs[1].From != btc || rate.Legs[1].To != pln {
+			t.Fatalf("Expected the path through the PLN/BTC quote got %v", rate.Legs)
+		}
+	})
+
+	t.Run("prefers_quotes_as_given", func(t *testing.T) {
+		g := newGraph(t)
+		if err := g.Add(quote(usd, eur, "0.81", "exchange")); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		rate, err := g.Rate(usd, eur)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if !rate.Rate.Equal(decimal.MustParse("0.81")) || rate.Source != "exchange" {
+			t.Fatalf("Expected the USD/EUR quote got %s from %q", rate.Rate, rate.Source)
+		}
+	})
+
+	t.Run("all_pairs", func(t *testing.T) {
+		g := newGraph(t)
+
+		rates, err := g.Rates([]*money.Currency{pln, wbtc, eur, pln})
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		want := [][2]*money.Currency{{eur, pln}, {eur, wbtc}, {pln, eur}, {pln, wbtc}, {wbtc, eur}, {wbtc, pln}}
+		if rates.Len() != len(want) {
+			t.Fatalf("Expected %d rates got %d", len(want), rates.Len())
+		}
+		for i, pair := range want {
+			if rate := rates.Rates()[i]; rate.From != pair[0] || rate.To != pair[1] {
+				t.Fatalf("Expected rate %d from %s to %s got %s", i, pair[0].Code, pair[1].Code, rate)
+			}
+		}
+	})
+
+	t.Run("no_path", func(t *testing.T) {
+		g := NewGraph()
+		if err := g.Add(quote(eur, usd, "1.25", "ecb"), quote(beer, btc, "0.0000001", "dex")); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		if _, err := g.Rate(usd, btc); !errors.Is(err, ErrUnsupportedCurrency) {
+			t.Fatalf("Expected %v got %v", ErrUnsupportedCurrency, err)
+		}
+		if _, err := g.Rate(usd, pln); !errors.Is(err, ErrUnsupportedCurrency) {
+			t.Fatalf("Expected %v got %v", ErrUnsupportedCurrency, err)
+		}
+	})
+
+	t.Run("bid_ask", func(t *testing.T) {
+		twoSided := quote(eur, usd, "1.25", "bank")
+		twoSided.Bid, twoSided.Ask = decimal.MustParse("1.2"), decimal.MustParse("1.3")
+
+		g := NewGraph()
+		if err := g.Add(twoSided, quote(eur, pln, "4.25", "ecb")); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		// Selling USD for PLN buys EUR at the EUR/USD ask, then sells it at the one-sided EUR/PLN rate.
+		rate, err := g.Rate(usd, pln)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if !rate.Rate.Equal(decimal.MustParse("3.4")) ||
+			!rate.Bid.Equal(decimal.MustParse("3.269230769230769231")) ||
+			!rate.Ask.Equal(decimal.MustParse("3.541666666666666667")) {
+			t.Fatalf("Expected USD/PLN bid 4.25/1.3, mid 3.4 and ask 4.25/1.2 got %s %s %s", rate.Bid, rate.Rate, rate.Ask)
+		}
+
+		inverted, err := g.Rate(pln, usd)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if !inverted.Bid.Equal(decimal.MustParse("0.2823529411764705882")) ||
+			!inverted.Ask.Equal(decimal.MustParse("0.3058823529411764706")) {
+			t.Fatalf("Expected PLN/USD bid 1.2/4.25 and ask 1.3/4.25 got %s %s", inverted.Bid, inverted.Ask)
+		}
+
+		single, err := g.Rate(usd, eur)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if !single.Bid.Equal(decimal.MustParse("0.7692307692307692308")) || !single.Ask.Equal(decimal.MustParse("0.8333333333333333333")) {
+			t.Fatalf("Expected USD/EUR bid 1/1.3 and ask 1/1.2 got %s %s", single.Bid, single.Ask)
+		}
+
+		if oneSided, _ := g.Rate(eur, pln); oneSided.TwoSided() {
+			t.Fatalf("Expected EUR/PLN to stay one-sided got %s %s", oneSided.Bid, oneSided.Ask)
+		}
+	})
+
+	t.Run("invalid_quotes", func(t *testing.T) {
+		sides := func(q ExchangeRate, bid, ask string) ExchangeRate {
+			q.Bid, q.Ask = decimal.MustParse(bid), decimal.MustParse(ask)
+			return q
+		}
+
+		for _, q := range []ExchangeRate{
+			quote(eur, eur, "1", "ecb"),
+			quote(eur, usd, "0", "ecb"),
+			quote(eur, usd, "-1.25", "ecb"),
+			sides(quote(eur, usd, "1.25", "bank"), "1.2", "0"),
+			sides(quote(eur, usd, "1.25", "bank"), "1.3", "1.2"),
+			sides(quote(eur, usd, "1.25", "bank"), "1.26", "1.3"),
+		} {
+			if err := NewGraph().Add(q); err == nil {
+				t.Fatalf("Expected an error adding %s", q)
+			}
+		}
+	})
+}
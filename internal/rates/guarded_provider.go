@@ -0,0 +1,428 @@
+package rates
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/Rhymond/go-money"
+	"github.com/govalues/decimal"
+)
+
+// GuardAction is what GuardedProvider does with a rate moving beyond its threshold.
+type GuardAction string
+
+const (
+	// GuardReject rejects the rate, serving the last accepted one instead.
+	GuardReject GuardAction = "reject"
+
+	// GuardQuarantine holds the rate back, serving the last accepted one instead, until a rate fetched after it
+	// confirms the move by being within the threshold of it.
+	GuardQuarantine GuardAction = "quarantine"
+)
+
+// ParseGuardAction parses the name of a guard action, defaulting to GuardReject when it is empty.
+func ParseGuardAction(s string) (GuardAction, error) {
+	switch a := GuardAction(s); a {
+	case "":
+		return GuardReject, nil
+	case GuardReject, GuardQuarantine:
+		return a, nil
+	default:
+		return "", fmt.Errorf("unknown guard action %q", s)
+	}
+}
+
+// GuardEventKind tells how GuardedProvider intervened.
+type GuardEventKind string
+
+const (
+	// GuardRejected is reported for a rate that was rejected, because it is not positive, out of bounds or moved
+	// beyond its threshold.
+	GuardRejected GuardEventKind = "rejected"
+
+	// GuardQuarantined is reported for a rate that moved beyond its threshold and is held back until it is confirmed.
+	GuardQuarantined GuardEventKind = "quarantined"
+
+	// GuardReleased is reported for a quarantined move that was confirmed, so the rate confirming it was accepted.
+	GuardReleased GuardEventKind = "released"
+)
+
+// GuardEvent describes an intervention of GuardedProvider on the rate of a pair.
+type GuardEvent struct {
+	Kind     GuardEventKind
+	Provider string
+	From, To string
+
+	// Rate is the rate the provider answered with.
+	Rate decimal.Decimal
+
+	// Last is the last accepted rate of the pair, which is served instead of a rejected or quarantined Rate.
+	// Zero when no rate of the pair was accepted yet, in which case the request fails with ErrRateRejected.
+	Last decimal.Decimal
+
+	// Reason tells why the rate was rejected or quarantined.
+	Reason string
+}
+
+// GuardedProvider validates the rates of a provider before they are served, so an upstream anomaly, e.g. EUR quoted at
+// 0.0085 instead of 0.85, doesn't make it to conversions. Rates that are not positive or out of bounds are rejected.
+// Rates that moved from the last accepted rate of their pair by more than the threshold of either currency are
+// rejected or quarantined, depending on the action. In place of a rate it doesn't accept, it serves the last accepted
+// one, marked as cached, and fails with ErrRateRejected when there is none, or leaves out a quote, see Quotes.
+// Every intervention is logged and reported to the listener, if there is one.
+//
+// Historical rates are only checked to be positive and within bounds, as they are not comparable with the latest ones.
+type GuardedProvider struct {
+	name     string
+	provider Provider
+
+	action   GuardAction
+	maxMove  decimal.Decimal
+	maxMoves map[string]decimal.Decimal
+	minRate  decimal.Decimal
+	maxRate  decimal.Decimal
+	listener func(context.Context, GuardEvent)
+	recorder Recorder
+
+	mu          sync.Mutex
+	accepted    map[pair]ExchangeRate
+	quarantined map[pair]ExchangeRate
+}
+
+// GuardOption configures GuardedProvider.
+type GuardOption func(*GuardedProvider)
+
+// WithGuardAction sets what is done with rates moving beyond their threshold, GuardReject by default.
+func WithGuardAction(a GuardAction) GuardOption {
+	return func(g *GuardedProvider) {
+		g.action = a
+	}
+}
+
+// WithMaxMove sets how far a rate may move from the last accepted rate of its pair, relative to it, e.g. 0.1 for 10%,
+// for currencies without a threshold of their own. A non-positive threshold lets rates move any amount.
+func WithMaxMove(threshold decimal.Decimal) GuardOption {
+	return func(g *GuardedProvider) {
+		g.maxMove = threshold
+	}
+}
+
+// WithCurrencyMaxMove sets the threshold of the currency with the code, e.g. a wider one for a volatile currency.
+// A pair may move as far as the wider threshold of its currencies.
+func WithCurrencyMaxMove(code string, threshold decimal.Decimal) GuardOption {
+	return func(g *GuardedProvider) {
+		g.maxMoves[code] = threshold
+	}
+}
+
+// WithRateBounds sets the lowest and the highest rate considered sane, rates outside of them are rejected.
+func WithRateBounds(minRate, maxRate decimal.Decimal) GuardOption {
+	return func(g *GuardedProvider) {
+		g.minRate, g.maxRate = minRate, maxRate
+	}
+}
+
+// WithGuardListener makes the provider report every intervention to listener, along with logging it.
+func WithGuardListener(listener func(context.Context, GuardEvent)) GuardOption {
+	return func(g *GuardedProvider) {
+		g.listener = listener
+	}
+}
+
+// WithGuardRecorder makes the provider record the quotes it serves with r every time they are asked for, as the
+// table of the provider fetched along with them.
+func WithGuardRecorder(r Recorder) GuardOption {
+	return func(g *GuardedProvider) {
+		g.recorder = r
+	}
+}
+
+// NewGuardedProvider returns provider guarded against anomalies, reported under name. By default rates are rejected
+// when they move by more than 10% or fall outside of 1e-12 and 1e12.
+func NewGuardedProvider(name string, provider Provider, opts ...GuardOption) (*GuardedProvider, error) {
+	g := &GuardedProvider{
+		name:        name,
+		provider:    provider,
+		action:      GuardReject,
+		maxMove:     decimal.MustNew(1, 1),
+		maxMoves:    make(map[string]decimal.Decimal),
+		minRate:     decimal.MustNew(1, 12),
+		maxRate:     decimal.MustNew(1_000_000_000_000, 0),
+		accepted:    make(map[pair]ExchangeRate),
+		quarantined: make(map[pair]ExchangeRate),
+	}
+
+	for _, opt := range opts {
+		opt(g)
+	}
+
+	if _, err := ParseGuardAction(string(g.action)); err != nil {
+		return nil, err
+	}
+	if g.minRate.Sign() <= 0 || g.minRate.Cmp(g.maxRate) >= 0 {
+		return nil, fmt.Errorf("rate bounds %s and %s: not positive and increasing", g.minRate, g.maxRate)
+	}
+
+	return g, nil
+}
+
+func (g *GuardedProvider) SupportedCurrencies(ctx context.Context) ([]*money.Currency, error) {
+	return g.provider.SupportedCurrencies(ctx)
+}
+
+// Rates returns the rates of the provider it accepts, along with the last accepted rates of the pairs it doesn't.
+func (g *GuardedProvider) Rates(ctx context.Context, c1, c2 *money.Currency, c ...*money.Currency) (*Matrix, error) {
+	rates, err := g.provider.Rates(ctx, c1, c2, c...)
+	if err != nil {
+		return nil, err
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	out := make([]ExchangeRate, 0, rates.Len())
+	for _, rate := range rates.Rates() {
+		guarded, err := g.guard(ctx, rate)
+		if err != nil {
+			return nil, err
+		}
+
+		out = append(out, guarded)
+	}
+
+	return NewMatrix(out...)
+}
+
+// Quotes returns the quotes of the provider it accepts, along with the last accepted quotes of the pairs it doesn't.
+// A quote rejected with no accepted one to serve instead is left out, so a single odd currency doesn't keep the
+// quotes of every other one from being served. It fails when the provider doesn't derive its rates from quotes.
+func (g *GuardedProvider) Quotes(ctx context.Context) (*Matrix, error) {
+	qp, ok := g.provider.(QuoteProvider)
+	if !ok {
+		return nil, fmt.Errorf("%s: provider has no quotes", g.name)
+	}
+
+	quotes, err := qp.Quotes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	out := make([]ExchangeRate, 0, quotes.Len())
+	for _, quote := range quotes.Rates() {
+		guarded, err := g.guard(ctx, quote)
+		if errors.Is(err, ErrRateRejected) {
+			slog.WarnContext(ctx, "rates guardrail left out quote", "provider", g.name,
+				"from", quote.From.Code, "to", quote.To.Code, "err", err)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		out = append(out, guarded)
+	}
+
+	served, err := NewMatrix(out...)
+	if err != nil {
+		return nil, err
+	}
+
+	fetchedAt, _ := quotes.FetchedAt()
+	g.record(ctx, fetchedAt, served)
+
+	return served, nil
+}
+
+// record hands the quotes served from the ones fetched at fetchedAt to the recorder as a table, if there is one.
+// Quotes of earlier tables served in place of rejected ones are recorded as served. A failure to record is logged and
+// doesn't fail the quotes.
+func (g *GuardedProvider) record(ctx context.Context, fetchedAt time.Time, quotes *Matrix) {
+	if g.recorder == nil || quotes.Len() == 0 {
+		return
+	}
+
+	if err := g.recorder.Record(context.WithoutCancel(ctx), quotesTable(g.name, fetchedAt, quotes)); err != nil {
+		slog.ErrorContext(ctx, "recording guarded quotes", "provider", g.name, "err", err)
+	}
+}
+
+// Since returns the first day the provider has rates for, zero when it has no history.
+func (g *GuardedProvider) Since() time.Time {
+	hp, ok := g.provider.(HistoricalProvider)
+	if !ok {
+		return time.Time{}
+	}
+
+	return hp.Since()
+}
+
+// RatesAt returns the rates of the provider as of date, failing with ErrRateRejected when any of them is not sane.
+func (g *GuardedProvider) RatesAt(ctx context.Context, date time.Time, c1, c2 *money.Currency, c ...*money.Currency) (*Matrix, error) {
+	hp, ok := g.provider.(HistoricalProvider)
+	if !ok {
+		return nil, ErrNoHistory
+	}
+
+	rates, err := hp.RatesAt(ctx, date, c1, c2, c...)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, rate := range rates.Rates() {
+		if reason := g.insane(rate); reason != "" {
+			g.report(ctx, GuardEvent{Kind: GuardRejected, Rate: rate.Rate, Reason: reason}, rate)
+			return nil, fmt.Errorf("rate for %q and %q on %s %s: %w",
+				rate.From.Code, rate.To.Code, Day(date).Format(time.DateOnly), reason, ErrRateRejected)
+		}
+	}
+
+	return rates, nil
+}
+
+// guard returns rate when it is accepted, or the last accepted rate of its pair otherwise.
+func (g *GuardedProvider) guard(ctx context.Context, rate ExchangeRate) (ExchangeRate, error) {
+	key := pair{from: rate.From.Code, to: rate.To.Code}
+	last, hasLast := g.accepted[key]
+
+	event := GuardEvent{Kind: GuardRejected, Rate: rate.Rate, Last: last.Rate}
+
+	if event.Reason = g.insane(rate); event.Reason == "" && hasLast {
+		threshold := g.threshold(rate.From.Code, rate.To.Code)
+
+		moved, err := movedBeyond(rate.Rate, last.Rate, threshold)
+		if err != nil {
+			return ExchangeRate{}, fmt.Errorf("guarding rate for %q and %q: %w", rate.From.Code, rate.To.Code, err)
+		}
+
+		if moved {
+			event.Reason = fmt.Sprintf("moved from %s by more than %s", last.Rate, threshold)
+
+			if g.action == GuardQuarantine {
+				held, confirmed, err := g.confirms(key, rate, threshold)
+				if err != nil {
+					return ExchangeRate{}, fmt.Errorf("guarding rate for %q and %q: %w", rate.From.Code, rate.To.Code, err)
+				}
+
+				if confirmed {
+					g.report(ctx, GuardEvent{Kind: GuardReleased, Rate: rate.Rate, Last: last.Rate,
+						Reason: fmt.Sprintf("confirmed move to %s", held.Rate)}, rate)
+					moved = false
+				} else {
+					event.Kind = GuardQuarantined
+					if !held.FetchedAt.After(rate.FetchedAt) {
+						g.quarantined[key] = rate
+					}
+				}
+			}
+		}
+
+		if !moved {
+			event.Reason = ""
+		}
+	}
+
+	if event.Reason == "" {
+		g.accepted[key] = rate
+		delete(g.quarantined, key)
+
+		return rate, nil
+	}
+
+	g.report(ctx, event, rate)
+
+	if !hasLast {
+		return ExchangeRate{}, fmt.Errorf("rate for %q and %q %s, no rate accepted before: %w",
+			rate.From.Code, rate.To.Code, event.Reason, ErrRateRejected)
+	}
+
+	last.Cached = true
+
+	return last, nil
+}
+
+// confirms reports whether rate confirms the move of the rate held in quarantine for key, being fetched after it and
+// within threshold of it. It returns the held rate, zero when there is none.
+func (g *GuardedProvider) confirms(key pair, rate ExchangeRate, threshold decimal.Decimal) (ExchangeRate, bool, error) {
+	held, ok := g.quarantined[key]
+	if !ok || !rate.FetchedAt.After(held.FetchedAt) {
+		return held, false, nil
+	}
+
+	moved, err := movedBeyond(rate.Rate, held.Rate, threshold)
+	if err != nil {
+		return held, false, err
+	}
+
+	return held, !moved, nil
+}
+
+// insane returns why rate is not sane, empty when it is.
+func (g *GuardedProvider) insane(rate ExchangeRate) string {
+	switch {
+	case rate.Rate.Sign() <= 0:
+		return fmt.Sprintf("%s is not positive", rate.Rate)
+	case rate.Rate.Cmp(g.minRate) < 0:
+		return fmt.Sprintf("%s is below %s", rate.Rate, g.minRate)
+	case rate.Rate.Cmp(g.maxRate) > 0:
+		return fmt.Sprintf("%s is above %s", rate.Rate, g.maxRate)
+	default:
+		return ""
+	}
+}
+
+// threshold returns how far the rate between the currencies with the codes may move, the wider of their thresholds.
+func (g *GuardedProvider) threshold(from, to string) decimal.Decimal {
+	out := g.maxMove
+	for _, code := range []string{from, to} {
+		t, ok := g.maxMoves[code]
+		if !ok {
+			continue
+		}
+
+		// A non-positive threshold lets the rate move any amount, which is wider than any other.
+		if t.Sign() <= 0 || out.Sign() > 0 && t.Cmp(out) > 0 {
+			out = t
+		}
+	}
+
+	return out
+}
+
+// report logs the event about rate and hands it to the listener, if there is one.
+func (g *GuardedProvider) report(ctx context.Context, event GuardEvent, rate ExchangeRate) {
+	event.Provider, event.From, event.To = g.name, rate.From.Code, rate.To.Code
+
+	slog.WarnContext(ctx, "rates guardrail intervened", "event", event.Kind, "provider", event.Provider,
+		"from", event.From, "to", event.To, "rate", event.Rate, "last", event.Last, "reason", event.Reason)
+
+	if g.listener != nil {
+		g.listener(ctx, event)
+	}
+}
+
+// movedBeyond reports whether rate moved from last by more than threshold relative to last.
+// A non-positive threshold lets it move any amount.
+func movedBeyond(rate, last, threshold decimal.Decimal) (bool, error) {
+	if threshold.Sign() <= 0 {
+		return false, nil
+	}
+
+	diff, err := rate.SubAbs(last)
+	if err != nil {
+		return false, err
+	}
+
+	allowed, err := last.Mul(threshold)
+	if err != nil {
+		return false, err
+	}
+
+	return diff.Cmp(allowed) > 0, nil
+}
@@ -0,0 +1,219 @@
+package rates
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/Rhymond/go-money"
+	"github.com/govalues/decimal"
+)
+
+func TestGuardedProvider(t *testing.T) {
+	usd, eur, btc := money.GetCurrency("USD"), money.GetCurrency("EUR"), money.GetCurrency("BTC")
+	start := time.Date(2025, time.July, 1, 12, 0, 0, 0, time.UTC)
+
+	// newGuarded guards table, collecting the kinds of the events it reports.
+	newGuarded := func(t *testing.T, table Provider, opts ...GuardOption) (*GuardedProvider, *[]GuardEventKind) {
+		var events []GuardEventKind
+		opts = append(opts, WithGuardListener(func(ctx context.Context, e GuardEvent) {
+			events = append(events, e.Kind)
+		}))
+
+		g, err := NewGuardedProvider("oxr", table, opts...)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		return g, &events
+	}
+
+	rateOf := func(t *testing.T, g *GuardedProvider, from, to *money.Currency) ExchangeRate {
+		t.Helper()
+
+		rates, err := g.Rates(t.Context(), from, to)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		rate, _ := rates.For(from, to)
+		return rate
+	}
+
+	t.Run("falls_back_to_last_accepted", func(t *testing.T) {
+		table := &usdTable{quotes: map[string]string{"EUR": "0.85"}, fetchedAt: start}
+		g, events := newGuarded(t, table)
+
+		if rate := rateOf(t, g, usd, eur); !rate.Rate.Equal(decimal.MustParse("0.85")) || rate.Cached {
+			t.Fatalf("Expected USD/EUR rate 0.85 accepted got %s", rate.Rate)
+		}
+
+		table.quotes["EUR"], table.fetchedAt = "0.0085", start.Add(time.Hour)
+
+		rate := rateOf(t, g, usd, eur)
+		if !rate.Rate.Equal(decimal.MustParse("0.85")) || !rate.Cached || !rate.FetchedAt.Equal(start) {
+			t.Fatalf("Expected the last accepted USD/EUR rate 0.85 served from cache got %s", rate.Rate)
+		}
+		if !slices.Equal(*events, []GuardEventKind{GuardRejected, GuardRejected}) {
+			t.Fatalf("Expected USD/EUR and EUR/USD rejected got %v", *events)
+		}
+	})
+
+	t.Run("quarantines_until_confirmed", func(t *testing.T) {
+		table := &usdTable{quotes: map[string]string{"EUR": "0.85"}, fetchedAt: start}
+		g, events := newGuarded(t, table, WithGuardAction(GuardQuarantine))
+
+		rateOf(t, g, usd, eur)
+
+		table.quotes["EUR"], table.fetchedAt = "0.95", start.Add(time.Hour)
+		if rate := rateOf(t, g, usd, eur); !rate.Rate.Equal(decimal.MustParse("0.85")) {
+			t.Fatalf("Expected the last accepted USD/EUR rate 0.85 while the move is quarantined got %s", rate.Rate)
+		}
+
+		// The same table again doesn't confirm the move, only one fetched after it does.
+		if rate := rateOf(t, g, usd, eur); !rate.Rate.Equal(decimal.MustParse("0.85")) {
+			t.Fatalf("Expected the move unconfirmed by the same table got %s", rate.Rate)
+		}
+
+		table.quotes["EUR"], table.fetchedAt = "0.951", start.Add(2*time.Hour)
+		if rate := rateOf(t, g, usd, eur); !rate.Rate.Equal(decimal.MustParse("0.951")) || rate.Cached {
+			t.Fatalf("Expected the confirmed USD/EUR rate 0.951 got %s", rate.Rate)
+		}
+
+		// Two pairs per request: USD/EUR and EUR/USD.
+		want := []GuardEventKind{GuardQuarantined, GuardQuarantined, GuardQuarantined, GuardQuarantined, GuardReleased, GuardReleased}
+		if !slices.Equal(*events, want) {
+			t.Fatalf("Expected events %v got %v", want, *events)
+		}
+	})
+
+	t.Run("currency_thresholds", func(t *testing.T) {
+		table := &usdTable{quotes: map[string]string{"EUR": "0.85", "BTC": "0.00001"}, fetchedAt: start}
+		g, _ := newGuarded(t, table, WithCurrencyMaxMove("BTC", decimal.MustParse("0.5")))
+
+		rateOf(t, g, usd, eur)
+		rateOf(t, g, usd, btc)
+
+		table.quotes["EUR"], table.quotes["BTC"] = "1.105", "0.000013"
+
+		if rate := rateOf(t, g, usd, btc); !rate.Rate.Equal(decimal.MustParse("0.000013")) {
+			t.Fatalf("Expected USD/BTC to move 30%% within its threshold got %s", rate.Rate)
+		}
+		if rate := rateOf(t, g, usd, eur); !rate.Rate.Equal(decimal.MustParse("0.85")) {
+			t.Fatalf("Expected USD/EUR moving 30%% rejected got %s", rate.Rate)
+		}
+	})
+
+	t.Run("quotes_leave_out_rejected", func(t *testing.T) {
+		table := &usdTable{quotes: make(map[string]string), fetchedAt: start}
+		for _, code := range []string{"AED", "AUD", "BRL", "CAD", "CHF", "CLP", "CNY", "CZK", "DKK", "EUR", "GBP", "HKD",
+			"HUF", "IDR", "ILS", "INR", "JPY", "KRW", "MXN", "NOK", "NZD", "PHP", "PLN", "SEK", "SGD", "THB", "TRY", "ZAR"} {
+			table.quotes[code] = "1.5"
+		}
+		table.quotes["JPY"] = "0"
+
+		g, events := newGuarded(t, table)
+
+		quotes, err := g.Quotes(t.Context())
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if quotes.Len() != len(table.quotes)-1 {
+			t.Fatalf("Expected %d quotes got %d", len(table.quotes)-1, quotes.Len())
+		}
+		if _, ok := quotes.For(usd, money.GetCurrency("JPY")); ok {
+			t.Fatalf("Expected the rejected USD/JPY quote left out")
+		}
+		if rate, ok := quotes.For(usd, eur); !ok || !rate.Rate.Equal(decimal.MustParse("1.5")) {
+			t.Fatalf("Expected USD/EUR quote 1.5 got %v", rate)
+		}
+		if !slices.Equal(*events, []GuardEventKind{GuardRejected}) {
+			t.Fatalf("Expected USD/JPY rejected got %v", *events)
+		}
+	})
+
+	t.Run("records_served_quotes", func(t *testing.T) {
+		var tables []Table
+		record := recorderFunc(func(ctx context.Context, table Table) error {
+			tables = append(tables, table)
+			return errors.New("disk full")
+		})
+
+		table := &usdTable{quotes: map[string]string{"EUR": "0.85", "GBP": "0.73"}, fetchedAt: start}
+		g, _ := newGuarded(t, table, WithGuardRecorder(record))
+
+		// A failing recorder doesn't fail the quotes.
+		if _, err := g.Quotes(t.Context()); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		table.quotes["EUR"], table.fetchedAt = "0.0085", start.Add(time.Hour)
+		if _, err := g.Quotes(t.Context()); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		// Historical rates are not recorded as the rates in force.
+		if _, err := g.RatesAt(t.Context(), start, usd, eur); !errors.Is(err, ErrNoHistory) {
+			t.Fatalf("Expected ErrNoHistory got %v", err)
+		}
+
+		if len(tables) != 2 {
+			t.Fatalf("Expected 2 recorded tables got %d", len(tables))
+		}
+
+		// The rejected EUR quote is recorded as the last accepted one it was served as.
+		served := tables[1]
+		if served.Provider != "oxr" || served.Base != "USD" || served.Quotation != QuoteIndirect || !served.FetchedAt.Equal(start.Add(time.Hour)) {
+			t.Fatalf("Unexpected table %+v", served)
+		}
+		if eur, gbp := served.Rates["EUR"], served.Rates["GBP"]; eur.String() != "0.85" || gbp.String() != "0.73" {
+			t.Fatalf("Expected the served EUR 0.85 and GBP 0.73 recorded got %s and %s", eur, gbp)
+		}
+	})
+
+	t.Run("rejects_insane_rates", func(t *testing.T) {
+		tests := map[string]decimal.Decimal{
+			"zero":     decimal.Zero,
+			"negative": decimal.MustParse("-0.85"),
+			"too_low":  decimal.MustParse("0.0000000000001"),
+			"too_high": decimal.MustParse("10000000000000"),
+		}
+
+		for name, rate := range tests {
+			t.Run(name, func(t *testing.T) {
+				g, events := newGuarded(t, quotedRates{{From: usd, To: eur, Rate: rate}})
+
+				if _, err := g.Rates(t.Context(), usd, eur); !errors.Is(err, ErrRateRejected) {
+					t.Fatalf("Expected ErrRateRejected got %v", err)
+				}
+				if !slices.Equal(*events, []GuardEventKind{GuardRejected}) {
+					t.Fatalf("Expected the rate rejected got %v", *events)
+				}
+			})
+		}
+	})
+
+	t.Run("invalid_config", func(t *testing.T) {
+		tests := map[string][]GuardOption{
+			"unknown_action":  {WithGuardAction("ignore")},
+			"zero_min_rate":   {WithRateBounds(decimal.Zero, decimal.One)},
+			"inverted_bounds": {WithRateBounds(decimal.One, decimal.MustParse("0.5"))},
+		}
+
+		for name, opts := range tests {
+			t.Run(name, func(t *testing.T) {
+				if _, err := NewGuardedProvider("oxr", quotedRates{}, opts...); err == nil {
+					t.Fatalf("Expected error")
+				}
+			})
+		}
+	})
+}
+
+type recorderFunc func(ctx context.Context, table Table) error
+
+func (f recorderFunc) Record(ctx context.Context, table Table) error {
+	return f(ctx, table)
+}
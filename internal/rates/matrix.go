@@ -0,0 +1,378 @@
+package rates
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Rhymond/go-money"
+	"github.com/govalues/decimal"
+)
+
+// Matrix holds the rates between currencies, at most one for every ordered pair of currencies, ordered by From and
+// then To. A nil *Matrix holds no rates. A Matrix is not changed once made, so it can be shared between goroutines.
+type Matrix struct {
+	rates []ExchangeRate
+	index map[pair]int
+}
+
+type pair struct {
+	from, to string
+}
+
+// NewMatrix returns a matrix of rates. It fails when a rate misses a currency, has the same currency on both sides
+// or when two rates are for the same pair.
+func NewMatrix(rates ...ExchangeRate) (*Matrix, error) {
+	m := &Matrix{
+		rates: slices.Clone(rates),
+		index: make(map[pair]int, len(rates)),
+	}
+
+	for _, r := range m.rates {
+		if r.From == nil || r.To == nil {
+			return nil, fmt.Errorf("rate %v: missing currency", r.Rate)
+		}
+		if r.From.Code == r.To.Code {
+			return nil, fmt.Errorf("rate %s: same currency on both sides", r)
+		}
+	}
+
+	slices.SortFunc(m.rates, func(a, b ExchangeRate) int {
+		if c := strings.Compare(a.From.Code, b.From.Code); c != 0 {
+			return c
+		}
+
+		return strings.Compare(a.To.Code, b.To.Code)
+	})
+
+	for i, r := range m.rates {
+		key := pair{from: r.From.Code, to: r.To.Code}
+		if _, ok := m.index[key]; ok {
+			return nil, fmt.Errorf("duplicate rate for %q and %q", r.From.Code, r.To.Code)
+		}
+
+		m.index[key] = i
+	}
+
+	return m, nil
+}
+
+// Len returns the number of rates in m.
+func (m *Matrix) Len() int {
+	if m == nil {
+		return 0
+	}
+
+	return len(m.rates)
+}
+
+// Rates returns the rates of m, ordered by From and then To.
+func (m *Matrix) Rates() []ExchangeRate {
+	if m == nil {
+		return nil
+	}
+
+	return slices.Clone(m.rates)
+}
+
+// For returns the rate from from to to.
+func (m *Matrix) For(from, to *money.Currency) (ExchangeRate, bool) {
+	if m == nil {
+		return ExchangeRate{}, false
+	}
+
+	i, ok := m.index[pair{from: from.Code, to: to.Code}]
+	if !ok {
+		return ExchangeRate{}, false
+	}
+
+	return m.rates[i], true
+}
+
+// Currencies returns the currencies m has rates for, ordered by code.
+func (m *Matrix) Currencies() []*money.Currency {
+	if m == nil {
+		return nil
+	}
+
+	uniq := make(map[string]*money.Currency)
+	for _, r := range m.rates {
+		uniq[r.From.Code] = r.From
+		uniq[r.To.Code] = r.To
+	}
+
+	out := make([]*money.Currency, 0, len(uniq))
+	for _, c := range uniq {
+		out = append(out, c)
+	}
+
+	slices.SortFunc(out, func(a, b *money.Currency) int {
+		return strings.Compare(a.Code, b.Code)
+	})
+
+	return out
+}
+
+// Subset returns the rates of m between currencies only.
+func (m *Matrix) Subset(currencies ...*money.Currency) *Matrix {
+	keep := make(map[string]struct{}, len(currencies))
+	for _, c := range currencies {
+		keep[c.Code] = struct{}{}
+	}
+
+	out := &Matrix{index: make(map[pair]int)}
+	for _, r := range m.Rates() {
+		_, from := keep[r.From.Code]
+		_, to := keep[r.To.Code]
+		if !from || !to {
+			continue
+		}
+
+		out.index[pair{from: r.From.Code, to: r.To.Code}] = len(out.rates)
+		out.rates = append(out.rates, r)
+	}
+
+	return out
+}
+
+// Invert returns the inverse of every rate of m, the rate from To to From.
+func (m *Matrix) Invert() (*Matrix, error) {
+	out := make([]ExchangeRate, 0, m.Len())
+	for _, r := range m.Rates() {
+		inverted, err := r.Invert()
+		if err != nil {
+			return nil, err
+		}
+
+		out = append(out, inverted)
+	}
+
+	return NewMatrix(out...)
+}
+
+// withSource returns m with the Source of the rates, and of the legs they were derived from, that have none set to name.
+func (m *Matrix) withSource(name string) *Matrix {
+	if m == nil {
+		return nil
+	}
+
+	out := &Matrix{rates: m.Rates(), index: m.index}
+	for i := range out.rates {
+		out.rates[i] = out.rates[i].withSource(name)
+	}
+
+	return out
+}
+
+// FetchedAt returns the fetch time of the oldest fetched rate in m.
+// It reports false when none of the rates were fetched from upstream.
+func (m *Matrix) FetchedAt() (time.Time, bool) {
+	return oldest(m.Rates(), fetchedAt)
+}
+
+// AsOf returns the as-of time of the oldest rate in m.
+// It reports false when upstream didn't tell for any of the rates.
+func (m *Matrix) AsOf() (time.Time, bool) {
+	return oldest(m.Rates(), asOf)
+}
+
+// Cached reports whether any of the rates in m was served from cache.
+func (m *Matrix) Cached() bool {
+	return anyCached(m.Rates())
+}
+
+// Sources returns the distinct, non-empty sources of m in order of the rates, those of the legs for derived rates.
+func (m *Matrix) Sources() []string {
+	return sources(m.Rates())
+}
+
+// jsonRate is the JSON form of an ExchangeRate, with currencies by code and the rate as a string to keep its digits.
+type jsonRate struct {
+	From      string          `json:"from"`
+	To        string          `json:"to"`
+	Rate      decimal.Decimal `json:"rate"`
+	Bid       decimal.Decimal `json:"bid,omitzero"`
+	Ask       decimal.Decimal `json:"ask,omitzero"`
+	AsOf      time.Time       `json:"as_of,omitzero"`
+	FetchedAt time.Time       `json:"fetched_at,omitzero"`
+	Cached    bool            `json:"cached,omitempty"`
+	Source    string          `json:"source,omitempty"`
+	Consensus *jsonConsensus  `json:"consensus,omitempty"`
+	Legs      []jsonRate      `json:"legs,omitempty"`
+}
+
+// jsonConsensus is the JSON form of a Consensus.
+type jsonConsensus struct {
+	Method     ConsensusMethod `json:"method"`
+	Sources    []string        `json:"sources"`
+	Rejected   []string        `json:"rejected,omitempty"`
+	Dispersion decimal.Decimal `json:"dispersion"`
+}
+
+func newJSONConsensus(c *Consensus) *jsonConsensus {
+	if c == nil {
+		return nil
+	}
+
+	return &jsonConsensus{Method: c.Method, Sources: c.Sources, Rejected: c.Rejected, Dispersion: c.Dispersion}
+}
+
+func (c *jsonConsensus) consensus() *Consensus {
+	if c == nil {
+		return nil
+	}
+
+	return &Consensus{Method: c.Method, Sources: c.Sources, Rejected: c.Rejected, Dispersion: c.Dispersion}
+}
+
+func newJSONRates(rates []ExchangeRate) []jsonRate {
+	if len(rates) == 0 {
+		return nil
+	}
+
+	out := make([]jsonRate, 0, len(rates))
+	for _, r := range rates {
+		out = append(out, jsonRate{
+			From:      r.From.Code,
+			To:        r.To.Code,
+			Rate:      r.Rate,
+			Bid:       r.Bid,
+			Ask:       r.Ask,
+			AsOf:      r.AsOf,
+			FetchedAt: r.FetchedAt,
+			Cached:    r.Cached,
+			Source:    r.Source,
+			Consensus: newJSONConsensus(r.Consensus),
+			Legs:      newJSONRates(r.Legs),
+		})
+	}
+
+	return out
+}
+
+func (r jsonRate) exchangeRate() (ExchangeRate, error) {
+	from, to := GetCurrency(r.From), GetCurrency(r.To)
+	if from == nil {
+		return ExchangeRate{}, fmt.Errorf("unknown currency %q: %w", r.From, ErrUnsupportedCurrency)
+	}
+	if to == nil {
+		return ExchangeRate{}, fmt.Errorf("unknown currency %q: %w", r.To, ErrUnsupportedCurrency)
+	}
+
+	out := ExchangeRate{
+		From:      from,
+		To:        to,
+		Rate:      r.Rate,
+		Bid:       r.Bid,
+		Ask:       r.Ask,
+		AsOf:      r.AsOf,
+		FetchedAt: r.FetchedAt,
+		Cached:    r.Cached,
+		Source:    r.Source,
+		Consensus: r.Consensus.consensus(),
+	}
+	for _, leg := range r.Legs {
+		l, err := leg.exchangeRate()
+		if err != nil {
+			return ExchangeRate{}, err
+		}
+
+		out.Legs = append(out.Legs, l)
+	}
+
+	return out, nil
+}
+
+// MarshalJSON encodes m as an array of rates with their provenance, e.g.
+//
+//	[{"from":"EUR","to":"USD","rate":"1.1781","fetched_at":"2025-01-02T15:04:05Z","source":"ecb"}]
+func (m *Matrix) MarshalJSON() ([]byte, error) {
+	rates := newJSONRates(m.Rates())
+	if rates == nil {
+		rates = []jsonRate{}
+	}
+
+	return json.Marshal(rates)
+}
+
+// UnmarshalJSON decodes m from an array of rates as encoded by MarshalJSON.
+func (m *Matrix) UnmarshalJSON(data []byte) error {
+	var rates []jsonRate
+	if err := json.Unmarshal(data, &rates); err != nil {
+		return err
+	}
+
+	out := make([]ExchangeRate, 0, len(rates))
+	for _, r := range rates {
+		rate, err := r.exchangeRate()
+		if err != nil {
+			return err
+		}
+
+		out = append(out, rate)
+	}
+
+	parsed, err := NewMatrix(out...)
+	if err != nil {
+		return err
+	}
+
+	*m = *parsed
+
+	return nil
+}
+
+// MarshalText encodes m as a line per rate, e.g. "EUR/USD 1.1781". Bid and ask rates and the provenance of the rates
+// are left out.
+func (m *Matrix) MarshalText() ([]byte, error) {
+	var buf bytes.Buffer
+	for _, r := range m.Rates() {
+		fmt.Fprintf(&buf, "%s/%s %s\n", r.From.Code, r.To.Code, r.Rate)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// UnmarshalText decodes m from a line per rate as encoded by MarshalText. Blank lines are skipped.
+func (m *Matrix) UnmarshalText(text []byte) error {
+	var out []ExchangeRate
+	for n, line := range strings.Split(string(text), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		codes, value, ok := strings.Cut(line, " ")
+		if !ok {
+			return fmt.Errorf("line %d: expected FROM/TO RATE got %q", n+1, line)
+		}
+		fromCode, toCode, ok := strings.Cut(codes, "/")
+		if !ok {
+			return fmt.Errorf("line %d: expected FROM/TO got %q", n+1, codes)
+		}
+
+		rate, err := decimal.Parse(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("line %d: parsing rate: %w", n+1, err)
+		}
+
+		r, err := jsonRate{From: fromCode, To: toCode, Rate: rate}.exchangeRate()
+		if err != nil {
+			return fmt.Errorf("line %d: %w", n+1, err)
+		}
+
+		out = append(out, r)
+	}
+
+	parsed, err := NewMatrix(out...)
+	if err != nil {
+		return err
+	}
+
+	*m = *parsed
+
+	return nil
+}
@@ -0,0 +1,171 @@
+package rates
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/Rhymond/go-money"
+	"github.com/govalues/decimal"
+)
+
+func TestMatrix(t *testing.T) {
+	eur, usd, pln := money.GetCurrency("EUR"), money.GetCurrency("USD"), money.GetCurrency("PLN")
+	fetchedAt := time.Date(2025, time.March, 3, 15, 0, 0, 0, time.UTC)
+	asOf := time.Date(2025, time.March, 3, 0, 0, 0, 0, time.UTC)
+
+	newMatrix := func(t *testing.T) *Matrix {
+		m, err := NewMatrix(
+			ExchangeRate{From: usd, To: pln, Rate: decimal.MustParse("3.4"), AsOf: asOf, FetchedAt: fetchedAt, Cached: true, Source: "ecb", Legs: []ExchangeRate{
+				{From: usd, To: eur, Rate: decimal.MustParse("0.8"), AsOf: asOf, FetchedAt: fetchedAt, Cached: true, Source: "ecb"},
+				{From: eur, To: pln, Rate: decimal.MustParse("4.25"), AsOf: asOf, FetchedAt: fetchedAt, Source: "ecb"},
+			}},
+			ExchangeRate{
+				From: eur, To: usd, Rate: decimal.MustParse("1.25"), Bid: decimal.MustParse("1.2"), Ask: decimal.MustParse("1.3"),
+				FetchedAt: fetchedAt, Source: "ecb",
+			},
+			ExchangeRate{From: eur, To: pln, Rate: decimal.MustParse("4.25"), FetchedAt: fetchedAt, Source: "consensus", Consensus: &Consensus{
+				Method: ConsensusMedian, Sources: []string{"ecb", "nbp"}, Rejected: []string{"oxr"}, Dispersion: decimal.MustParse("0.001"),
+			}},
+		)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		return m
+	}
+
+	pairs := func(m *Matrix) []string {
+		var out []string
+		for _, r := range m.Rates() {
+			out = append(out, r.From.Code+"/"+r.To.Code)
+		}
+
+		return out
+	}
+
+	t.Run("ordered", func(t *testing.T) {
+		m := newMatrix(t)
+
+		if got := pairs(m); !slices.Equal(got, []string{"EUR/PLN", "EUR/USD", "USD/PLN"}) {
+			t.Fatalf("Expected rates ordered by From and To got %v", got)
+		}
+
+		rate, ok := m.For(eur, usd)
+		if !ok || !rate.Rate.Equal(decimal.MustParse("1.25")) {
+			t.Fatalf("Expected EUR/USD rate 1.25 got %s", rate)
+		}
+		if _, ok := m.For(usd, eur); ok {
+			t.Fatalf("Expected no USD/EUR rate")
+		}
+
+		var codes []string
+		for _, c := range m.Currencies() {
+			codes = append(codes, c.Code)
+		}
+		if !slices.Equal(codes, []string{"EUR", "PLN", "USD"}) {
+			t.Fatalf("Expected currencies EUR, PLN, USD got %v", codes)
+		}
+	})
+
+	t.Run("duplicates", func(t *testing.T) {
+		_, err := NewMatrix(
+			ExchangeRate{From: eur, To: usd, Rate: decimal.MustParse("1.25")},
+			ExchangeRate{From: eur, To: usd, Rate: decimal.MustParse("1.25")},
+		)
+		if err == nil {
+			t.Fatalf("Expected an error for a duplicate pair")
+		}
+	})
+
+	t.Run("subset", func(t *testing.T) {
+		m := newMatrix(t).Subset(eur, usd)
+
+		if got := pairs(m); !slices.Equal(got, []string{"EUR/USD"}) {
+			t.Fatalf("Expected only EUR/USD got %v", got)
+		}
+		if _, ok := m.For(eur, pln); ok {
+			t.Fatalf("Expected no EUR/PLN rate in the subset")
+		}
+	})
+
+	t.Run("invert", func(t *testing.T) {
+		m, err := newMatrix(t).Invert()
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		if got := pairs(m); !slices.Equal(got, []string{"PLN/EUR", "PLN/USD", "USD/EUR"}) {
+			t.Fatalf("Expected inverted pairs got %v", got)
+		}
+
+		rate, _ := m.For(usd, eur)
+		if !rate.Rate.Equal(decimal.MustParse("0.8")) {
+			t.Fatalf("Expected USD/EUR rate 0.8 got %s", rate.Rate)
+		}
+
+		rate, _ = m.For(pln, usd)
+		if len(rate.Legs) != 2 || rate.Legs[0].From != pln || rate.Legs[0].To != eur || rate.Legs[1].From != eur || rate.Legs[1].To != usd {
+			t.Fatalf("Expected legs PLN/EUR and EUR/USD got %v", rate.Legs)
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		m := newMatrix(t)
+
+		buf, err := json.Marshal(m)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		var got Matrix
+		if err := json.Unmarshal(buf, &got); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		if !slices.EqualFunc(got.Rates(), m.Rates(), sameRate) {
+			t.Fatalf("Expected %v got %v", m.Rates(), got.Rates())
+		}
+	})
+
+	t.Run("text", func(t *testing.T) {
+		m := newMatrix(t)
+
+		buf, err := m.MarshalText()
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if want := "EUR/PLN 4.25\nEUR/USD 1.25\nUSD/PLN 3.4\n"; string(buf) != want {
+			t.Fatalf("Expected %q got %q", want, buf)
+		}
+
+		var got Matrix
+		if err := got.UnmarshalText(buf); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if !slices.Equal(pairs(&got), pairs(m)) {
+			t.Fatalf("Expected %v got %v", pairs(m), pairs(&got))
+		}
+
+		if err := got.UnmarshalText([]byte("EUR/ABC 1.25\n")); err == nil {
+			t.Fatalf("Expected an error for an unknown currency")
+		}
+	})
+}
+
+// sameRate reports whether a and b are the same rate with the same provenance.
+func sameRate(a, b ExchangeRate) bool {
+	return a.From.Code == b.From.Code && a.To.Code == b.To.Code && a.Rate.Equal(b.Rate) &&
+		a.Bid.Equal(b.Bid) && a.Ask.Equal(b.Ask) && a.AsOf.Equal(b.AsOf) && a.FetchedAt.Equal(b.FetchedAt) && a.Cached == b.Cached && a.Source == b.Source &&
+		sameConsensus(a.Consensus, b.Consensus) && slices.EqualFunc(a.Legs, b.Legs, sameRate)
+}
+
+func sameConsensus(a, b *Consensus) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	return a.Method == b.Method && slices.Equal(a.Sources, b.Sources) && slices.Equal(a.Rejected, b.Rejected) &&
+		a.Dispersion.Equal(b.Dispersion)
+}
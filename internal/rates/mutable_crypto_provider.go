@@ -0,0 +1,343 @@
+package rates
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Rhymond/go-money"
+	"github.com/govalues/decimal"
+)
+
+// CryptoAction is the kind of change that made a version of the crypto rates.
+type CryptoAction string
+
+const (
+	// CryptoLoaded replaced every token, e.g. with the ones of a (re)loaded config file.
+	CryptoLoaded CryptoAction = "load"
+
+	// CryptoPriceSet changed the price of a token.
+	CryptoPriceSet CryptoAction = "set_price"
+
+	// CryptoListed added a token.
+	CryptoListed CryptoAction = "list"
+
+	// CryptoDelisted removed a token.
+	CryptoDelisted CryptoAction = "delist"
+
+	// CryptoRolledBack restored the tokens of an earlier version.
+	CryptoRolledBack CryptoAction = "rollback"
+)
+
+// CryptoChange tells who changes the crypto rates and why.
+type CryptoChange struct {
+	Author string
+	Reason string
+}
+
+// CryptoVersion is a version of the crypto rates along with the change that made it.
+type CryptoVersion struct {
+	// Version numbers the versions from 1, in the order they were made.
+	Version int
+
+	Action CryptoAction
+
+	// Code is the token the change was made to, empty when it was made to every token.
+	Code string
+
+	Author string
+	Reason string
+	At     time.Time
+
+	// RolledBackTo is the version the tokens were restored from by a rollback.
+	RolledBackTo int
+
+	// Config holds the tokens of the version.
+	Config CryptoConfig
+}
+
+// MutableCryptoRatesProvider serves rates between tokens and USD like FixedCryptoRatesProvider, while letting the
+// tokens and their prices change at runtime. Every change makes a new version, kept along with who made it, when and
+// why, and the tokens can be rolled back to any earlier version.
+type MutableCryptoRatesProvider struct {
+	// recorder records the prices of every version as a table of the provider named name, when set.
+	recorder Recorder
+	name     string
+
+	// changing serializes changes, so each one builds on the version the previous one made.
+	changing sync.Mutex
+
+	// managedBy is the file the tokens are loaded from, when they are. They are then only ever replaced with its
+	// tokens, so no change made otherwise is lost on the next reload.
+	managedBy string
+
+	mu       sync.RWMutex
+	current  cryptoState
+	versions []CryptoVersion
+}
+
+// CryptoOption configures MutableCryptoRatesProvider.
+type CryptoOption func(*MutableCryptoRatesProvider)
+
+// WithCryptoRecorder records the prices of every version with r, as tables of the provider named name.
+func WithCryptoRecorder(name string, r Recorder) CryptoOption {
+	return func(p *MutableCryptoRatesProvider) {
+		p.name = name
+		p.recorder = r
+	}
+}
+
+// cryptoState is the currencies and prices of the current version.
+type cryptoState struct {
+	currencies []*money.Currency
+	prices     map[string]decimal.Decimal
+	// at is the time the version was made at, which its rates are as of.
+	at time.Time
+}
+
+// FixedCryptoConfig returns the tokens and prices served by FixedCryptoRatesProvider.
+func FixedCryptoConfig() CryptoConfig {
+	return CryptoConfig{Tokens: maps.Clone(fixedCryptoTokens)}
+}
+
+// NewMutableCryptoRatesProvider returns a provider serving the tokens of cfg as its first version, made by change.
+func NewMutableCryptoRatesProvider(cfg CryptoConfig, change CryptoChange, opts ...CryptoOption) (*MutableCryptoRatesProvider, error) {
+	p := &MutableCryptoRatesProvider{}
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	if _, err := p.Replace(cfg, change); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// Replace replaces every token with the ones of cfg.
+func (p *MutableCryptoRatesProvider) Replace(cfg CryptoConfig, change CryptoChange) (CryptoVersion, error) {
+	return p.apply(CryptoVersion{Action: CryptoLoaded}, change, func(CryptoConfig) (CryptoConfig, error) {
+		return cfg, nil
+	})
+}
+
+// SetPrice sets the USD price of the listed token with the code.
+func (p *MutableCryptoRatesProvider) SetPrice(code string, usd decimal.Decimal, change CryptoChange) (CryptoVersion, error) {
+	return p.apply(CryptoVersion{Action: CryptoPriceSet, Code: code}, change, func(cfg CryptoConfig) (CryptoConfig, error) {
+		token, ok := cfg.Tokens[code]
+		if !ok {
+			return CryptoConfig{}, fmt.Errorf("%q: %w", code, ErrTokenNotFound)
+		}
+
+		token.USD = usd
+		cfg.Tokens[code] = token
+
+		return cfg, nil
+	})
+}
+
+// List adds a token with the code.
+func (p *MutableCryptoRatesProvider) List(code string, token CryptoToken, change CryptoChange) (CryptoVersion, error) {
+	return p.apply(CryptoVersion{Action: CryptoListed, Code: code}, change, func(cfg CryptoConfig) (CryptoConfig, error) {
+		if _, ok := cfg.Tokens[code]; ok {
+			return CryptoConfig{}, fmt.Errorf("%q: %w", code, ErrTokenExists)
+		}
+
+		cfg.Tokens[code] = token
+
+		return cfg, nil
+	})
+}
+
+// Delist removes the listed token with the code. The last token can't be removed.
+func (p *MutableCryptoRatesProvider) Delist(code string, change CryptoChange) (CryptoVersion, error) {
+	return p.apply(CryptoVersion{Action: CryptoDelisted, Code: code}, change, func(cfg CryptoConfig) (CryptoConfig, error) {
+		if _, ok := cfg.Tokens[code]; !ok {
+			return CryptoConfig{}, fmt.Errorf("%q: %w", code, ErrTokenNotFound)
+		}
+
+		delete(cfg.Tokens, code)
+
+		return cfg, nil
+	})
+}
+
+// Rollback restores the tokens of the version, as a new version.
+func (p *MutableCryptoRatesProvider) Rollback(version int, change CryptoChange) (CryptoVersion, error) {
+	return p.apply(CryptoVersion{Action: CryptoRolledBack, RolledBackTo: version}, change, func(CryptoConfig) (CryptoConfig, error) {
+		v, ok := p.Version(version)
+		if !ok {
+			return CryptoConfig{}, fmt.Errorf("version %d: %w", version, ErrVersionNotFound)
+		}
+
+		return v.Config, nil
+	})
+}
+
+// apply makes a new version with the tokens edit returns for a copy of the current ones. The current version is kept
+// when edit fails or returns an invalid config.
+func (p *MutableCryptoRatesProvider) apply(
+	v CryptoVersion,
+	change CryptoChange,
+	edit func(CryptoConfig) (CryptoConfig, error),
+) (CryptoVersion, error) {
+	if strings.TrimSpace(change.Author) == "" || strings.TrimSpace(change.Reason) == "" {
+		return CryptoVersion{}, fmt.Errorf("%w: a change needs an author and a reason", ErrInvalidCryptoRates)
+	}
+
+	p.changing.Lock()
+	defer p.changing.Unlock()
+
+	if p.managedBy != "" && v.Action != CryptoLoaded {
+		return CryptoVersion{}, fmt.Errorf("%s: %w", p.managedBy, ErrCryptoRatesManaged)
+	}
+
+	previous := p.Current().Config
+	cfg, err := edit(previous.clone())
+	if err != nil {
+		return CryptoVersion{}, err
+	}
+	if err := cfg.validate(); err != nil {
+		return CryptoVersion{}, err
+	}
+
+	v.At = time.Now()
+
+	state := cryptoState{
+		currencies: []*money.Currency{GetCurrency(money.USD)},
+		prices:     make(map[string]decimal.Decimal, len(cfg.Tokens)),
+		at:         v.At,
+	}
+	for code, token := range cfg.Tokens {
+		symbol := token.Symbol
+		if symbol == "" {
+			symbol = code
+		}
+
+		state.currencies = append(state.currencies, register(code, symbol, token.Decimals))
+		state.prices[code] = token.USD
+	}
+
+	slices.SortFunc(state.currencies, func(a, b *money.Currency) int {
+		return strings.Compare(a.Code, b.Code)
+	})
+
+	// Delisted tokens are no longer known as currencies, or known as they were before they were listed.
+	for code := range previous.Tokens {
+		if _, ok := cfg.Tokens[code]; !ok {
+			unregisterCurrency(code)
+		}
+	}
+
+	v.Author = change.Author
+	v.Reason = change.Reason
+	v.Config = cfg.clone()
+
+	p.mu.Lock()
+	v.Version = len(p.versions) + 1
+	p.versions = append(p.versions, v)
+	p.current = state
+	p.mu.Unlock()
+
+	slog.Info("crypto rates changed", "version", v.Version, "action", v.Action, "code", v.Code,
+		"author", v.Author, "reason", v.Reason)
+
+	p.record(v.At, state.prices)
+
+	return v, nil
+}
+
+// register returns the currency of a token, registering it unless it is known with the same symbol and decimals.
+func register(code, symbol string, decimals int) *money.Currency {
+	if c := GetCurrency(code); c != nil && c.Grapheme == symbol && c.Fraction == decimals {
+		return c
+	}
+
+	return RegisterCurrency(code, symbol, decimals)
+}
+
+// Current returns the current version.
+func (p *MutableCryptoRatesProvider) Current() CryptoVersion {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if len(p.versions) == 0 {
+		return CryptoVersion{Config: CryptoConfig{Tokens: map[string]CryptoToken{}}}
+	}
+
+	return p.versions[len(p.versions)-1]
+}
+
+// Version returns the version with the number, if there is one.
+func (p *MutableCryptoRatesProvider) Version(version int) (CryptoVersion, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if version < 1 || version > len(p.versions) {
+		return CryptoVersion{}, false
+	}
+
+	return p.versions[version-1], true
+}
+
+// Versions returns every version, oldest first.
+func (p *MutableCryptoRatesProvider) Versions() []CryptoVersion {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return slices.Clone(p.versions)
+}
+
+func (p *MutableCryptoRatesProvider) state() cryptoState {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.current
+}
+
+// liveCurrencies marks the supported currencies as changing with every version.
+func (p *MutableCryptoRatesProvider) liveCurrencies() {}
+
+func (p *MutableCryptoRatesProvider) SupportedCurrencies(ctx context.Context) ([]*money.Currency, error) {
+	return slices.Clone(p.state().currencies), nil
+}
+
+// Rates returns the rates between currencies derived from the USD prices of the current version, as of the time it
+// was made.
+func (p *MutableCryptoRatesProvider) Rates(ctx context.Context, c1, c2 *money.Currency, c ...*money.Currency) (*Matrix, error) {
+	currencies := append([]*money.Currency{c1, c2}, c...)
+
+	state := p.state()
+
+	rates, err := valueCrossRates(money.USD, state.prices, currencyCodes(currencies), provenance{asOf: state.at})
+	if err != nil {
+		return nil, fmt.Errorf("getting crypto rates: %w", err)
+	}
+
+	return rates, nil
+}
+
+// record records prices as a table when a recorder is set. The prices are served whether recording them fails or not.
+func (p *MutableCryptoRatesProvider) record(at time.Time, prices map[string]decimal.Decimal) {
+	if p.recorder == nil {
+		return
+	}
+
+	table := Table{
+		Provider:  p.name,
+		FetchedAt: at,
+		Base:      money.USD,
+		Quotation: QuoteDirect,
+		Rates:     map[string]decimal.Decimal{money.USD: decimal.One},
+	}
+	maps.Copy(table.Rates, prices)
+
+	if err := p.recorder.Record(context.Background(), table); err != nil {
+		slog.Error("recording crypto rates", "err", err)
+	}
+}
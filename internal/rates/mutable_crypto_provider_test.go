@@ -0,0 +1,178 @@
+package rates
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/govalues/decimal"
+)
+
+func TestMutableCryptoRatesProvider(t *testing.T) {
+	change := CryptoChange{Author: "ops", Reason: "incident"}
+
+	newProvider := func(t *testing.T) *MutableCryptoRatesProvider {
+		p, err := NewMutableCryptoRatesProvider(FixedCryptoConfig(), CryptoChange{Author: "gorate", Reason: "startup"})
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		return p
+	}
+
+	usdRate := func(t *testing.T, p *MutableCryptoRatesProvider, code string) decimal.Decimal {
+		t.Helper()
+
+		from, usd := GetCurrency(code), GetCurrency("USD")
+
+		rates, err := p.Rates(t.Context(), from, usd)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		r, _ := rates.For(from, usd)
+
+		return r.Rate
+	}
+
+	t.Run("serves_fixed_prices", func(t *testing.T) {
+		p := newProvider(t)
+
+		if got := usdRate(t, p, "GATE"); !got.Equal(decimal.MustParse("6.87")) {
+			t.Fatalf("Expected GATE/USD rate 6.87 got %s", got)
+		}
+		if v := p.Current(); v.Version != 1 || v.Action != CryptoLoaded || v.Author != "gorate" {
+			t.Fatalf("Expected the first version loaded by gorate got %+v", v)
+		}
+	})
+
+	t.Run("changes", func(t *testing.T) {
+		p := newProvider(t)
+
+		v, err := p.SetPrice("GATE", decimal.MustParse("7.5"), change)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if v.Version != 2 || v.Action != CryptoPriceSet || v.Code != "GATE" || v.Author != "ops" || v.Reason != "incident" {
+			t.Fatalf("Expected version 2 setting the GATE price by ops got %+v", v)
+		}
+		if got := usdRate(t, p, "GATE"); !got.Equal(decimal.MustParse("7.5")) {
+			t.Fatalf("Expected GATE/USD rate 7.5 got %s", got)
+		}
+
+		if _, err := p.List("MUTX", CryptoToken{Decimals: 6, USD: decimal.MustParse("0.25")}, change); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if got := usdRate(t, p, "MUTX"); !got.Equal(decimal.MustParse("0.25")) {
+			t.Fatalf("Expected MUTX/USD rate 0.25 got %s", got)
+		}
+
+		if _, err := p.Delist("BEER", change); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if _, err := p.Rates(t.Context(), GetCurrency("BEER"), GetCurrency("USD")); !errors.Is(err, ErrUnsupportedCurrency) {
+			t.Fatalf("Expected the delisted BEER to be unsupported got %v", err)
+		}
+
+		if got := len(p.Versions()); got != 4 {
+			t.Fatalf("Expected 4 versions got %d", got)
+		}
+	})
+
+	t.Run("rollback", func(t *testing.T) {
+		p := newProvider(t)
+
+		if _, err := p.SetPrice("WBTC", decimal.MustParse("1"), change); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if _, err := p.Delist("USDT", change); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		v, err := p.Rollback(1, CryptoChange{Author: "ops", Reason: "bad price"})
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if v.Version != 4 || v.Action != CryptoRolledBack || v.RolledBackTo != 1 {
+			t.Fatalf("Expected version 4 rolling back to 1 got %+v", v)
+		}
+
+		if got := usdRate(t, p, "WBTC"); !got.Equal(decimal.MustParse("57037.22")) {
+			t.Fatalf("Expected WBTC/USD rate 57037.22 got %s", got)
+		}
+		if got := usdRate(t, p, "USDT"); !got.Equal(decimal.MustParse("0.999")) {
+			t.Fatalf("Expected USDT/USD rate 0.999 got %s", got)
+		}
+
+		if v, _ := p.Version(2); !v.Config.Tokens["WBTC"].USD.Equal(decimal.One) {
+			t.Fatalf("Expected version 2 to keep its WBTC price got %+v", v.Config.Tokens["WBTC"])
+		}
+	})
+
+	t.Run("rejected_changes", func(t *testing.T) {
+		single, err := NewMutableCryptoRatesProvider(CryptoConfig{Tokens: map[string]CryptoToken{
+			"WBTC": {Decimals: 8, USD: decimal.MustParse("57037.22")},
+		}}, change)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		tests := map[string]struct {
+			apply func(p *MutableCryptoRatesProvider) error
+			err   error
+		}{
+			"unknown_token": {
+				apply: func(p *MutableCryptoRatesProvider) error {
+					_, err := p.SetPrice("DOGE", decimal.One, change)
+					return err
+				},
+				err: ErrTokenNotFound,
+			},
+			"listed_twice": {
+				apply: func(p *MutableCryptoRatesProvider) error {
+					_, err := p.List("WBTC", CryptoToken{Decimals: 8, USD: decimal.One}, change)
+					return err
+				},
+				err: ErrTokenExists,
+			},
+			"non_positive_price": {
+				apply: func(p *MutableCryptoRatesProvider) error {
+					_, err := p.SetPrice("WBTC", decimal.Zero, change)
+					return err
+				},
+				err: ErrInvalidCryptoRates,
+			},
+			"no_reason": {
+				apply: func(p *MutableCryptoRatesProvider) error {
+					_, err := p.SetPrice("WBTC", decimal.One, CryptoChange{Author: "ops"})
+					return err
+				},
+				err: ErrInvalidCryptoRates,
+			},
+			"last_token": {
+				apply: func(p *MutableCryptoRatesProvider) error {
+					_, err := p.Delist("WBTC", change)
+					return err
+				},
+				err: ErrInvalidCryptoRates,
+			},
+			"unknown_version": {
+				apply: func(p *MutableCryptoRatesProvider) error {
+					_, err := p.Rollback(2, change)
+					return err
+				},
+				err: ErrVersionNotFound,
+			},
+		}
+
+		for name, tt := range tests {
+			t.Run(name, func(t *testing.T) {
+				if err := tt.apply(single); !errors.Is(err, tt.err) {
+					t.Fatalf("Expected %v got %v", tt.err, err)
+				}
+				if got := len(single.Versions()); got != 1 {
+					t.Fatalf("Expected no new version got %d versions", got)
+				}
+			})
+		}
+	})
+}
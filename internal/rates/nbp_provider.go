@@ -0,0 +1,338 @@
+package rates
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Rhymond/go-money"
+	"github.com/govalues/decimal"
+)
+
+// NBPBaseURL is the base URL of the Narodowy Bank Polski API.
+const NBPBaseURL = "https://api.nbp.pl/api"
+
+// Exchange rate tables published by Narodowy Bank Polski.
+const (
+	// NBPTableA holds mid rates of the most traded currencies, published every business day.
+	NBPTableA = "A"
+
+	// NBPTableB holds mid rates of the remaining currencies, published every Wednesday.
+	NBPTableB = "B"
+
+	// NBPTableC holds bid and ask rates of the most traded currencies, published every business day.
+	NBPTableC = "C"
+)
+
+// nbpName identifies the provider in errors.
+const nbpName = "nbp"
+
+// nbpCacheTTL is how long a downloaded table is served from memory, NBP publishes tables at most once a business day.
+const nbpCacheTTL = time.Hour
+
+// nbpLookback is how far back TableAt searches for the table in effect, long enough to cover
+// the weekly table B and the longest run of Polish bank holidays.
+const nbpLookback = 14 * 24 * time.Hour
+
+// nbpSince is the first day tables are available from the NBP API for.
+var nbpSince = time.Date(2002, time.January, 2, 0, 0, 0, 0, time.UTC)
+
+// NBPTable is an exchange rates table published by Narodowy Bank Polski. Rates are quoted in PLN.
+type NBPTable struct {
+	// Table is the kind of the table: NBPTableA, NBPTableB or NBPTableC.
+	Table string
+
+	// No is the number the table was published under, e.g. 125/A/NBP/2025.
+	No string
+
+	// TradingDate is the day the table C rates were set on, zero for tables A and B.
+	TradingDate time.Time
+
+	// EffectiveDate is the day the table was published and is in effect from.
+	EffectiveDate time.Time
+
+	Rates []NBPRate
+}
+
+// NBPRate is the price of a single unit of a currency in PLN.
+type NBPRate struct {
+	// Currency is the Polish name of the currency.
+	Currency string
+
+	Code string
+
+	// Mid is the mid rate. Table C doesn't publish one, so there it is the average of Bid and Ask.
+	Mid decimal.Decimal
+
+	// Bid is the buying rate, published in table C only.
+	Bid decimal.Decimal
+
+	// Ask is the selling rate, published in table C only.
+	Ask decimal.Decimal
+}
+
+// NBPProvider provides exchange rates published by Narodowy Bank Polski in one of its tables.
+type NBPProvider struct {
+	client  *http.Client
+	baseURL string
+	table   string
+	now     func() time.Time
+
+	mu        sync.Mutex
+	cached    *NBPTable
+	fetchedAt time.Time
+}
+
+// NewNBPProvider returns a provider of the rates in table, one of NBPTableA, NBPTableB or NBPTableC,
+// read from the NBP API at baseURL, e.g. NBPBaseURL.
+func NewNBPProvider(cli *http.Client, baseURL, table string) *NBPProvider {
+	return &NBPProvider{
+		client:  cli,
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		table:   strings.ToUpper(table),
+		now:     time.Now,
+	}
+}
+
+func (n *NBPProvider) SupportedCurrencies(ctx context.Context) ([]*money.Currency, error) {
+	table, _, err := n.latest(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("getting table %s: %w", n.table, err)
+	}
+
+	out := []*money.Currency{GetCurrency(money.PLN)}
+	for _, rate := range table.Rates {
+		if currency := GetCurrency(rate.Code); currency != nil {
+			out = append(out, currency)
+		}
+	}
+
+	slices.SortFunc(out, func(a, b *money.Currency) int {
+		return strings.Compare(a.Code, b.Code)
+	})
+
+	return out, nil
+}
+
+// Rates returns cross rates computed from the latest published table, two-sided for table C.
+func (n *NBPProvider) Rates(ctx context.Context, c1, c2 *money.Currency, c ...*money.Currency) (*Matrix, error) {
+	currencies := []*money.Currency{c1, c2}
+	currencies = append(currencies, c...)
+
+	table, prov, err := n.latest(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("getting table %s: %w", n.table, err)
+	}
+
+	rates, err := tableRates(money.PLN, table.prices(), currencyCodes(currencies), prov, true)
+	if err != nil {
+		return nil, fmt.Errorf("getting rates: %w", err)
+	}
+
+	return rates, nil
+}
+
+// Quotes returns the latest published table as values in PLN, two-sided for table C, downloaded rather than served
+// from cache.
+func (n *NBPProvider) Quotes(ctx context.Context) (*Matrix, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	table, prov, err := n.fetchLatest(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("getting table %s: %w", n.table, err)
+	}
+
+	return allQuotes(money.PLN, table.prices(), prov, true)
+}
+
+// Since returns the first day tables are available from the NBP API for.
+func (n *NBPProvider) Since() time.Time {
+	return nbpSince
+}
+
+// RatesAt returns cross rates computed from the table published on date, two-sided for table C.
+// It fails with ErrNoData for days no table was published on, e.g. weekends, holidays and, for table B, days other than Wednesday.
+func (n *NBPProvider) RatesAt(ctx context.Context, date time.Time, c1, c2 *money.Currency, c ...*money.Currency) (*Matrix, error) {
+	currencies := []*money.Currency{c1, c2}
+	currencies = append(currencies, c...)
+
+	if err := CheckDate(n, date, n.now()); err != nil {
+		return nil, err
+	}
+
+	day := Day(date).Format(time.DateOnly)
+
+	tables, err := n.fetch(ctx, "/exchangerates/tables/"+n.table+"/"+day+"/")
+	if err != nil {
+		return nil, fmt.Errorf("getting table %s published on %s: %w", n.table, day, err)
+	}
+
+	table := tables[len(tables)-1]
+	prov := provenance{asOf: table.EffectiveDate, fetchedAt: n.now()}
+
+	rates, err := tableRates(money.PLN, table.prices(), currencyCodes(currencies), prov, true)
+	if err != nil {
+		return nil, fmt.Errorf("getting rates: %w", err)
+	}
+
+	return rates, nil
+}
+
+// Table returns the latest published table, including bid and ask rates when the provider reads table C.
+func (n *NBPProvider) Table(ctx context.Context) (*NBPTable, error) {
+	table, _, err := n.latest(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("getting table %s: %w", n.table, err)
+	}
+
+	return table, nil
+}
+
+// TableAt returns the table in effect on date, which is the latest one published on or before it.
+func (n *NBPProvider) TableAt(ctx context.Context, date time.Time) (*NBPTable, error) {
+	end := date.Format(time.DateOnly)
+	start := date.Add(-nbpLookback).Format(time.DateOnly)
+
+	tables, err := n.fetch(ctx, "/exchangerates/tables/"+n.table+"/"+start+"/"+end+"/")
+	if err != nil {
+		return nil, fmt.Errorf("getting table %s in effect on %s: %w", n.table, end, err)
+	}
+
+	return &tables[len(tables)-1], nil
+}
+
+// latest returns the latest published table along with its provenance, downloading it when the cached copy is
+// missing or expired.
+func (n *NBPProvider) latest(ctx context.Context) (*NBPTable, provenance, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.cached != nil && n.now().Sub(n.fetchedAt) < nbpCacheTTL {
+		return n.cached, provenance{asOf: n.cached.EffectiveDate, fetchedAt: n.fetchedAt, cached: true}, nil
+	}
+
+	return n.fetchLatest(ctx)
+}
+
+// fetchLatest downloads the latest published table and caches it, returning it along with its provenance.
+// It is called with mu held.
+func (n *NBPProvider) fetchLatest(ctx context.Context) (*NBPTable, provenance, error) {
+	tables, err := n.fetch(ctx, "/exchangerates/tables/"+n.table+"/")
+	if err != nil {
+		return nil, provenance{}, err
+	}
+
+	n.cached = &tables[len(tables)-1]
+	n.fetchedAt = n.now()
+
+	return n.cached, provenance{asOf: n.cached.EffectiveDate, fetchedAt: n.fetchedAt}, nil
+}
+
+// fetch downloads the tables at path, ordered by effective date.
+func (n *NBPProvider) fetch(ctx context.Context, path string) ([]NBPTable, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, n.baseURL+path+"?format=json", nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("making request: %w: %w", ErrUnavailable, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		// NBP answers with a plain text status line, e.g. "404 NotFound - Not Found - Brak danych".
+		upErr := &UpstreamError{Provider: nbpName, StatusCode: resp.StatusCode}
+		switch {
+		case resp.StatusCode == http.StatusNotFound:
+			upErr.Err = ErrNoData
+		case resp.StatusCode >= http.StatusInternalServerError:
+			upErr.Err = ErrUnavailable
+		}
+		return nil, upErr
+	}
+
+	var raw []struct {
+		Table         string `json:"table"`
+		No            string `json:"no"`
+		TradingDate   string `json:"tradingDate"`
+		EffectiveDate string `json:"effectiveDate"`
+		Rates         []struct {
+			Currency string          `json:"currency"`
+			Code     string          `json:"code"`
+			Mid      decimal.Decimal `json:"mid"`
+			Bid      decimal.Decimal `json:"bid"`
+			Ask      decimal.Decimal `json:"ask"`
+		} `json:"rates"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+		return nil, fmt.Errorf("decoding data: %w", err)
+	}
+
+	if len(raw) == 0 {
+		return nil, fmt.Errorf("no tables found")
+	}
+
+	tables := make([]NBPTable, 0, len(raw))
+	for _, r := range raw {
+		table := NBPTable{
+			Table: r.Table,
+			No:    r.No,
+			Rates: make([]NBPRate, 0, len(r.Rates)),
+		}
+
+		if table.EffectiveDate, err = time.Parse(time.DateOnly, r.EffectiveDate); err != nil {
+			return nil, fmt.Errorf("parsing effective date of table %q: %w", r.No, err)
+		}
+		if r.TradingDate != "" {
+			if table.TradingDate, err = time.Parse(time.DateOnly, r.TradingDate); err != nil {
+				return nil, fmt.Errorf("parsing trading date of table %q: %w", r.No, err)
+			}
+		}
+
+		for _, rate := range r.Rates {
+			mid := rate.Mid
+			if mid.IsZero() && !rate.Bid.IsZero() && !rate.Ask.IsZero() {
+				if mid, err = decimal.Mean(rate.Bid, rate.Ask); err != nil {
+					return nil, fmt.Errorf("computing mid rate of %q: %w", rate.Code, err)
+				}
+			}
+
+			table.Rates = append(table.Rates, NBPRate{
+				Currency: rate.Currency,
+				Code:     rate.Code,
+				Mid:      mid,
+				Bid:      rate.Bid,
+				Ask:      rate.Ask,
+			})
+		}
+
+		tables = append(tables, table)
+	}
+
+	slices.SortFunc(tables, func(a, b NBPTable) int {
+		return a.EffectiveDate.Compare(b.EffectiveDate)
+	})
+
+	return tables, nil
+}
+
+// prices returns the prices of the table by currency code, including PLN itself. Bid and ask are set for table C only.
+func (t *NBPTable) prices() map[string]price {
+	out := make(map[string]price, len(t.Rates)+1)
+	for _, rate := range t.Rates {
+		out[rate.Code] = price{mid: rate.Mid, bid: rate.Bid, ask: rate.Ask}
+	}
+	out[money.PLN] = price{mid: decimal.One}
+
+	return out
+}
@@ -0,0 +1,156 @@
+package rates
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Rhymond/go-money"
+	"github.com/govalues/decimal"
+)
+
+// nbpFixtures serves testdata/nbp the way the NBP API serves tables,
+// e.g. /api/exchangerates/tables/A/2025-06-22/2025-07-06/ from A_2025-06-22_2025-07-06.json.
+func nbpFixtures(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/exchangerates/tables/"), "/")
+		buf, err := os.ReadFile("testdata/nbp/" + strings.ReplaceAll(name, "/", "_") + ".json")
+		if err != nil {
+			http.Error(w, "404 NotFound - Not Found - Brak danych", http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write(buf)
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func TestNBPProvider(t *testing.T) {
+	srv := nbpFixtures(t)
+
+	pln, usd, eur, aed := money.GetCurrency("PLN"), money.GetCurrency("USD"), money.GetCurrency("EUR"), money.GetCurrency("AED")
+
+	tests := []struct {
+		table    string
+		from, to *money.Currency
+		want     string
+	}{
+		{table: NBPTableA, from: usd, to: pln, want: "3.6016"},
+		{table: NBPTableA, from: eur, to: usd, want: "1.177865393158596179"},
+		{table: NBPTableB, from: aed, to: pln, want: "0.9806"},
+		{table: NBPTableC, from: usd, to: pln, want: "3.6019"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.table+"/"+tc.from.Code+tc.to.Code, func(t *testing.T) {
+			prov := NewNBPProvider(srv.Client(), srv.URL+"/api", tc.table)
+
+			rates, err := prov.Rates(t.Context(), tc.from, tc.to)
+			if err != nil {
+				t.Fatalf("err: %v", err)
+			}
+
+			rate, ok := rates.For(tc.from, tc.to)
+			if !ok {
+				t.Fatalf("Expected rate for %s/%s in %v", tc.from.Code, tc.to.Code, rates)
+			}
+			if !rate.Rate.Equal(decimal.MustParse(tc.want)) {
+				t.Fatalf("Expected rate %s got %s", tc.want, rate.Rate)
+			}
+		})
+	}
+
+	t.Run("table_c_bid_ask", func(t *testing.T) {
+		prov := NewNBPProvider(srv.Client(), srv.URL+"/api", NBPTableC)
+
+		table, err := prov.Table(t.Context())
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		if want := time.Date(2025, 6, 30, 0, 0, 0, 0, time.UTC); !table.TradingDate.Equal(want) {
+			t.Fatalf("Expected trading date %v got %v", want, table.TradingDate)
+		}
+
+		usdRate := table.Rates[0]
+		if usdRate.Code != "USD" || !usdRate.Bid.Equal(decimal.MustParse("3.5659")) || !usdRate.Ask.Equal(decimal.MustParse("3.6379")) {
+			t.Fatalf("Expected USD bid 3.5659 and ask 3.6379 got %+v", usdRate)
+		}
+
+		rates, err := prov.Rates(t.Context(), usd, pln)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		rate, _ := rates.For(usd, pln)
+		if !rate.Bid.Equal(decimal.MustParse("3.5659")) || !rate.Ask.Equal(decimal.MustParse("3.6379")) {
+			t.Fatalf("Expected USD/PLN bid 3.5659 and ask 3.6379 got %s %s", rate.Bid, rate.Ask)
+		}
+
+		inverted, _ := rates.For(pln, usd)
+		if !inverted.Bid.Equal(decimal.MustParse("0.2748838615684873141")) || !inverted.Ask.Equal(decimal.MustParse("0.2804341120053843350")) {
+			t.Fatalf("Expected PLN/USD bid 1/3.6379 and ask 1/3.5659 got %s %s", inverted.Bid, inverted.Ask)
+		}
+	})
+
+	t.Run("quotes", func(t *testing.T) {
+		prov := NewNBPProvider(srv.Client(), srv.URL+"/api", NBPTableC)
+
+		quotes, err := prov.Quotes(t.Context())
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		// Table C values the currencies in PLN, two-sided.
+		rate, ok := quotes.For(usd, pln)
+		if !ok || !rate.Bid.Equal(decimal.MustParse("3.5659")) || !rate.Ask.Equal(decimal.MustParse("3.6379")) {
+			t.Fatalf("Expected USD/PLN quote with bid 3.5659 and ask 3.6379 got %v", rate)
+		}
+		if _, ok := quotes.For(usd, eur); ok {
+			t.Fatalf("Expected only quotes in PLN got %v", quotes.Rates())
+		}
+	})
+
+	t.Run("table_in_effect", func(t *testing.T) {
+		prov := NewNBPProvider(srv.Client(), srv.URL+"/api", NBPTableA)
+
+		// Sunday, the table published on the Friday before is in effect.
+		table, err := prov.TableAt(t.Context(), time.Date(2025, 7, 6, 0, 0, 0, 0, time.UTC))
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		if table.No != "128/A/NBP/2025" {
+			t.Fatalf("Expected table 128/A/NBP/2025 got %s", table.No)
+		}
+
+		if _, err := prov.TableAt(t.Context(), time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)); err == nil {
+			t.Fatalf("Expected error for a date without tables")
+		}
+	})
+	t.Run("rates_at", func(t *testing.T) {
+		prov := NewNBPProvider(srv.Client(), srv.URL+"/api", NBPTableA)
+
+		rates, err := prov.RatesAt(t.Context(), time.Date(2025, 7, 3, 0, 0, 0, 0, time.UTC), usd, pln)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		rate, ok := rates.For(usd, pln)
+		if !ok || !rate.Rate.Equal(decimal.MustParse("3.6052")) {
+			t.Fatalf("Expected USD/PLN rate 3.6052 got %v", rates)
+		}
+
+		// Saturday, no table was published.
+		if _, err := prov.RatesAt(t.Context(), time.Date(2025, 7, 5, 0, 0, 0, 0, time.UTC), usd, pln); !errors.Is(err, ErrNoData) {
+			t.Fatalf("Expected %v got %v", ErrNoData, err)
+		}
+	})
+}
@@ -4,31 +4,79 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
 	"net/url"
 	"slices"
-	"sort"
 	"strings"
+	"time"
 
 	"github.com/Rhymond/go-money"
 	"github.com/govalues/decimal"
+	"golang.org/x/sync/singleflight"
 )
 
+// DefaultOpenExchangeRatesBaseURL is the base URL of the Open Exchange Rates API.
+const DefaultOpenExchangeRatesBaseURL = "https://openexchangerates.org/api"
+
+// openExchangeRatesName identifies the provider in errors.
+const openExchangeRatesName = "openexchangerates"
+
+// openExchangeRatesSince is the first day Open Exchange Rates has historical rates for.
+var openExchangeRatesSince = time.Date(1999, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// fetchTimeout bounds a shared upstream fetch, which runs detached from the callers waiting for it.
+const fetchTimeout = 30 * time.Second
+
+// latestKey identifies the fetch of the latest table in the singleflight group.
+const latestKey = "latest"
+
 type OpenExchangeRatesProvider struct {
-	client *http.Client
-	appID  string
+	client  *http.Client
+	appID   string
+	baseURL string
 
-	// TODO: implement caching.
+	now func() time.Time
+
+	// group coalesces concurrent upstream fetches into a single request.
+	group singleflight.Group
+}
+
+// oxrTable holds the full USD based table as returned by openexchangerates.
+type oxrTable struct {
+	base  string
+	rates map[string]decimal.Decimal
+	// asOf is the time the rates were published at, as told by the timestamp of the table, zero when it has none.
+	asOf      time.Time
+	fetchedAt time.Time
 }
 
-func NewOpenExchangeRatesProvider(cli *http.Client, appID string) *OpenExchangeRatesProvider {
-	return &OpenExchangeRatesProvider{
-		client: cli,
-		appID:  appID,
+// OpenExchangeRatesOption configures OpenExchangeRatesProvider.
+type OpenExchangeRatesOption func(*OpenExchangeRatesProvider)
+
+// WithBaseURL sets the base URL of the Open Exchange Rates API, e.g. to point it at a fake server.
+func WithBaseURL(baseURL string) OpenExchangeRatesOption {
+	return func(o *OpenExchangeRatesProvider) {
+		o.baseURL = strings.TrimSuffix(baseURL, "/")
 	}
 }
 
+func NewOpenExchangeRatesProvider(cli *http.Client, appID string, opts ...OpenExchangeRatesOption) *OpenExchangeRatesProvider {
+	o := &OpenExchangeRatesProvider{
+		client:  cli,
+		appID:   appID,
+		baseURL: DefaultOpenExchangeRatesBaseURL,
+		now:     time.Now,
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
 func (o *OpenExchangeRatesProvider) SupportedCurrencies(ctx context.Context) ([]*money.Currency, error) {
 	currencies, err := o.getCurrencies(ctx)
 	if err != nil {
@@ -38,62 +86,99 @@ func (o *OpenExchangeRatesProvider) SupportedCurrencies(ctx context.Context) ([]
 	return currencies, nil
 }
 
-func (o *OpenExchangeRatesProvider) Rates(ctx context.Context, c1, c2 *money.Currency, c ...*money.Currency) (ExchangeRates, error) {
+func (o *OpenExchangeRatesProvider) Rates(ctx context.Context, c1, c2 *money.Currency, c ...*money.Currency) (*Matrix, error) {
 	currencies := []*money.Currency{c1, c2}
 	currencies = append(currencies, c...)
 
-	rates, err := o.getRates(ctx, currencies)
+	table, err := o.fetch(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("getting rates: %w", err)
 	}
 
-	out := make([]ExchangeRate, 0, len(rates))
+	return o.ratesFrom(table, currencies)
+}
 
-	for _, rate := range rates {
-		expectedFrom := slices.ContainsFunc(currencies, func(currency *money.Currency) bool {
-			return currency.Code == rate.From.Code
-		})
+// Quotes returns the quotes of the latest USD based table.
+func (o *OpenExchangeRatesProvider) Quotes(ctx context.Context) (*Matrix, error) {
+	table, err := o.fetch(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("getting rates: %w", err)
+	}
 
-		expectedTo := slices.ContainsFunc(currencies, func(currency *money.Currency) bool {
-			return currency.Code == rate.To.Code
-		})
+	return allQuotes(money.USD, midPrices(table.rates), provenance{asOf: table.asOf, fetchedAt: table.fetchedAt}, false)
+}
 
-		if !expectedFrom || !expectedTo {
-			continue
-		}
+// Since returns the first day Open Exchange Rates has historical rates for.
+func (o *OpenExchangeRatesProvider) Since() time.Time {
+	return openExchangeRatesSince
+}
 
-		out = append(out, rate)
+// RatesAt returns rates as of the end of the given UTC day.
+func (o *OpenExchangeRatesProvider) RatesAt(ctx context.Context, date time.Time, c1, c2 *money.Currency, c ...*money.Currency) (*Matrix, error) {
+	if err := CheckDate(o, date, o.now()); err != nil {
+		return nil, err
 	}
 
-	return out, nil
+	currencies := []*money.Currency{c1, c2}
+	currencies = append(currencies, c...)
 
+	table, err := o.fetchTable(ctx, "/historical/"+Day(date).Format(time.DateOnly)+".json")
+	if err != nil {
+		return nil, fmt.Errorf("getting rates at %s: %w", Day(date).Format(time.DateOnly), err)
+	}
+
+	return o.ratesFrom(table, currencies)
 }
 
-// getRates retrieves exchange rates for the provided currencies using the Open Exchange Rates API.
-// It ensures there are at least two distinct currencies and computes cross-rates for all currency pairs.
-// Returns a list of ExchangeRate containing rate information or an error if the retrieval or processing fails.
-func (o *OpenExchangeRatesProvider) getRates(ctx context.Context, currencies []*money.Currency) ([]ExchangeRate, error) {
-	uniq := map[string]struct{}{}
-	for _, s := range currencies {
-		uniq[s.Code] = struct{}{}
+// ratesFrom returns the rates between currencies computed from table.
+func (o *OpenExchangeRatesProvider) ratesFrom(table *oxrTable, currencies []*money.Currency) (*Matrix, error) {
+	for _, c := range currencies {
+		if _, ok := table.rates[c.Code]; !ok {
+			return nil, fmt.Errorf("openexchangerates missing rate for %q: %w", c.Code, ErrUnsupportedCurrency)
+		}
 	}
-	if len(uniq) < 2 {
-		return nil, fmt.Errorf("at least 2 distinct currencies required")
+
+	prov := provenance{asOf: table.asOf, fetchedAt: table.fetchedAt}
+
+	rates, err := crossRates(money.USD, table.rates, currencyCodes(currencies), prov)
+	if err != nil {
+		return nil, fmt.Errorf("getting rates: %w", err)
 	}
 
-	reqSymbols := make([]string, 0, len(uniq))
-	for c := range uniq {
-		if c == money.USD {
-			continue
+	return rates, nil
+}
+
+// fetch downloads the latest table, sharing a single upstream request between concurrent callers.
+// The request is detached from ctx, so a caller giving up does not abort it for the others still waiting.
+func (o *OpenExchangeRatesProvider) fetch(ctx context.Context) (*oxrTable, error) {
+	ch := o.group.DoChan(latestKey, func() (any, error) {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), fetchTimeout)
+		defer cancel()
+
+		table, err := o.fetchTable(ctx, "/latest.json")
+		if err != nil {
+			return nil, err
 		}
-		reqSymbols = append(reqSymbols, c)
+
+		return table, nil
+	})
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case res := <-ch:
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		return res.Val.(*oxrTable), nil
 	}
-	sort.Strings(reqSymbols)
+}
 
+// fetchTable downloads the full USD based table at path from the Open Exchange Rates API.
+func (o *OpenExchangeRatesProvider) fetchTable(ctx context.Context, path string) (*oxrTable, error) {
 	params := url.Values{}
 	params.Add("app_id", o.appID)
-	params.Add("symbols", strings.Join(reqSymbols, ","))
-	url := "https://openexchangerates.org/api/latest.json?" + params.Encode()
+	url := o.baseURL + path + "?" + params.Encode()
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
@@ -102,12 +187,18 @@ func (o *OpenExchangeRatesProvider) getRates(ctx context.Context, currencies []*
 
 	resp, err := o.client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("making request: %w", err)
+		return nil, fmt.Errorf("making request: %w: %w", ErrUnavailable, err)
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode != http.StatusOK {
+		return nil, openExchangeRatesError(resp)
+	}
+
 	var raw struct {
-		Rates map[string]decimal.Decimal `json:"rates"`
+		Timestamp int64                      `json:"timestamp"`
+		Base      string                     `json:"base"`
+		Rates     map[string]decimal.Decimal `json:"rates"`
 	}
 
 	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
@@ -120,79 +211,38 @@ func (o *OpenExchangeRatesProvider) getRates(ctx context.Context, currencies []*
 
 	raw.Rates[money.USD] = decimal.One
 
-	for c := range uniq {
-		if _, ok := raw.Rates[c]; !ok {
-			return nil, fmt.Errorf("openexchangerates missing rate for %q", c)
-		}
+	if raw.Base == "" {
+		raw.Base = money.USD
 	}
 
-	currList := make([]string, 0, len(uniq))
-	for c := range uniq {
-		currList = append(currList, c)
+	table := &oxrTable{
+		base:      raw.Base,
+		rates:     raw.Rates,
+		fetchedAt: o.now(),
 	}
-	sort.Strings(currList)
-
-	usd := money.GetCurrency(money.USD)
-	out := make([]ExchangeRate, 0, len(currList)*len(currList))
-
-	for _, from := range currList {
-		curFrom := money.GetCurrency(from)
-		if curFrom == nil {
-			return nil, fmt.Errorf("unknown currency: %q", from)
-		}
-		rateFrom := raw.Rates[from]
-
-		for _, to := range currList {
-			if from == to {
-				continue
-			}
-			curTo := money.GetCurrency(to)
-			if curTo == nil {
-				return nil, fmt.Errorf("unknown currency: %q", to)
-			}
-			rateTo := raw.Rates[to]
-
-			cross, err := rateTo.Quo(rateFrom)
-			if err != nil {
-				return nil, fmt.Errorf("making cross rate: %w", err)
-			}
-
-			out = append(out, ExchangeRate{
-				From: curFrom,
-				To:   curTo,
-				Rate: cross,
-			})
-		}
-
-		if from != money.USD {
-			r, _ := decimal.One.Quo(rateFrom)
-
-			out = append(out,
-				ExchangeRate{From: curFrom, To: usd, Rate: r},
-				ExchangeRate{From: usd, To: curFrom, Rate: rateFrom},
-			)
-		}
+	if raw.Timestamp > 0 {
+		table.asOf = time.Unix(raw.Timestamp, 0).UTC()
 	}
 
-	slices.SortFunc(out, func(a, b ExchangeRate) int {
-		return strings.Compare(a.To.Code, b.To.Code)
-	})
-
-	return out, nil
+	return table, nil
 }
 
 func (o *OpenExchangeRatesProvider) getCurrencies(ctx context.Context) ([]*money.Currency, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://openexchangerates.org/api/currencies.json", nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, o.baseURL+"/currencies.json", nil)
 	if err != nil {
 		return nil, fmt.Errorf("creating request: %w", err)
 	}
 
 	resp, err := o.client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("making request: %w", err)
+		return nil, fmt.Errorf("making request: %w: %w", ErrUnavailable, err)
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode != http.StatusOK {
+		return nil, openExchangeRatesError(resp)
+	}
+
 	currencies := make(map[string]string)
 	if err := json.NewDecoder(resp.Body).Decode(&currencies); err != nil {
 		return nil, fmt.Errorf("decoding data: %w", err)
@@ -200,7 +250,7 @@ func (o *OpenExchangeRatesProvider) getCurrencies(ctx context.Context) ([]*money
 
 	out := make([]*money.Currency, 0, len(currencies))
 	for code := range currencies {
-		currency := money.GetCurrency(code)
+		currency := GetCurrency(code)
 		if currency == nil {
 			slog.ErrorContext(ctx, "currency not found", "currency", code)
 			continue
@@ -215,3 +265,43 @@ func (o *OpenExchangeRatesProvider) getCurrencies(ctx context.Context) ([]*money
 
 	return out, nil
 }
+
+// openExchangeRatesError decodes the error body of a failed Open Exchange Rates response, e.g.
+// {"error":true,"status":401,"message":"invalid_app_id","description":"Invalid App ID provided..."},
+// and classifies it using the sentinel errors of this package.
+func openExchangeRatesError(resp *http.Response) error {
+	upErr := &UpstreamError{
+		Provider:   openExchangeRatesName,
+		StatusCode: resp.StatusCode,
+	}
+
+	var body struct {
+		Message     string `json:"message"`
+		Description string `json:"description"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<16)).Decode(&body); err == nil {
+		upErr.Message = body.Message
+		upErr.Description = body.Description
+	}
+
+	switch {
+	case upErr.Message == "missing_app_id":
+		upErr.Err = ErrMissingAppID
+	case upErr.Message == "invalid_app_id":
+		upErr.Err = ErrInvalidAppID
+	case upErr.Message == "invalid_base":
+		upErr.Err = ErrInvalidBase
+	case upErr.Message == "not_available":
+		upErr.Err = ErrNoData
+	case upErr.Message == "not_allowed":
+		upErr.Err = ErrNotAllowed
+	case upErr.Message == "access_restricted" && resp.StatusCode != http.StatusTooManyRequests:
+		upErr.Err = ErrNotAllowed
+	case upErr.Message == "access_restricted", resp.StatusCode == http.StatusTooManyRequests:
+		upErr.Err = ErrQuotaExceeded
+	case resp.StatusCode >= http.StatusInternalServerError:
+		upErr.Err = ErrUnavailable
+	}
+
+	return upErr
+}
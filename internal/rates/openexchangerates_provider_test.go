@@ -1,17 +1,28 @@
 package rates
 
 import (
+	"context"
+	"errors"
 	"net/http"
-	"os"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/IAmRadek/gorate/internal/rates/oxrfake"
 	"github.com/Rhymond/go-money"
+	"github.com/govalues/decimal"
 )
 
 func TestOpenExchangeRatesProvider(t *testing.T) {
+	srv := httptest.NewServer(oxrfake.New())
+	defer srv.Close()
+
 	prov := NewOpenExchangeRatesProvider(
-		http.DefaultClient,
-		os.Getenv("OPEN_EXCHANGE_RATES_PROVIDER_APP_ID"),
+		srv.Client(),
+		oxrfake.AppID,
+		WithBaseURL(srv.URL+oxrfake.BasePath),
 	)
 
 	rates, err := prov.Rates(t.Context(),
@@ -24,38 +35,251 @@ func TestOpenExchangeRatesProvider(t *testing.T) {
 		t.Fatalf("err: %v", err)
 	}
 
+	// Every pair once, ordered by From and then To.
 	expectedCombinations := [][2]string{
-		{"USD", "BTC"},
-		{"EUR", "BTC"},
-		{"USD", "BTC"},
-		{"GBP", "BTC"},
-		{"USD", "EUR"},
 		{"BTC", "EUR"},
-		{"USD", "EUR"},
-		{"GBP", "EUR"},
-		{"USD", "GBP"},
 		{"BTC", "GBP"},
-		{"USD", "GBP"},
+		{"BTC", "USD"},
+		{"EUR", "BTC"},
 		{"EUR", "GBP"},
 		{"EUR", "USD"},
+		{"GBP", "BTC"},
+		{"GBP", "EUR"},
 		{"GBP", "USD"},
-		{"GBP", "USD"},
-		{"EUR", "USD"},
-		{"BTC", "USD"},
-		{"BTC", "USD"},
+		{"USD", "BTC"},
+		{"USD", "EUR"},
+		{"USD", "GBP"},
 	}
 
-	if len(rates) != len(expectedCombinations) {
-		t.Fatalf("Expected %d rates go %d", len(expectedCombinations), len(rates))
+	if rates.Len() != len(expectedCombinations) {
+		t.Fatalf("Expected %d rates go %d", len(expectedCombinations), rates.Len())
 	}
 
 	for i, ec := range expectedCombinations {
-		if ec[0] != rates[i].From.Code {
-			t.Fatalf("Expected %d From currency to match %q got %q", i, ec[0], rates[i].From.Code)
+		rate := rates.Rates()[i]
+		if ec[0] != rate.From.Code {
+			t.Fatalf("Expected %d From currency to match %q got %q", i, ec[0], rate.From.Code)
+		}
+
+		if ec[1] != rate.To.Code {
+			t.Fatalf("Expected %d To currency to match %q got %q", i, ec[0], rate.From.Code)
+		}
+	}
+}
+
+func TestOpenExchangeRatesProviderQuotes(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		_, _ = w.Write([]byte(`{"timestamp":1751371200,"base":"USD","rates":{"USD":1,"EUR":0.848818,"GBP":0.731209}}`))
+	}))
+	defer srv.Close()
+
+	prov := NewOpenExchangeRatesProvider(srv.Client(), "app-id", WithBaseURL(srv.URL))
+
+	for want := int32(1); want <= 2; want++ {
+		quotes, err := prov.Quotes(t.Context())
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if got := calls.Load(); got != want {
+			t.Fatalf("Expected quotes fetched from upstream every time, %d upstream calls got %d", want, got)
+		}
+
+		// Only the quotes of the table against USD are returned, not the rates between every pair.
+		if quotes.Len() != 2 || quotes.Cached() {
+			t.Fatalf("Expected 2 fetched quotes got %v", quotes.Rates())
+		}
+		if r, ok := quotes.For(money.GetCurrency("USD"), money.GetCurrency("EUR")); !ok || !r.Rate.Equal(decimal.MustParse("0.848818")) {
+			t.Fatalf("Expected USD/EUR quote 0.848818 got %v", r)
+		}
+	}
+
+}
+
+func TestOpenExchangeRatesProviderCoalescesFetches(t *testing.T) {
+	const callers = 50
+
+	var calls atomic.Int32
+	started := make(chan struct{})
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			close(started)
+		}
+		<-release
+		_, _ = w.Write([]byte(`{"base":"USD","rates":{"USD":1,"EUR":0.848818,"GBP":0.731209}}`))
+	}))
+	defer srv.Close()
+
+	prov := NewOpenExchangeRatesProvider(srv.Client(), "app-id", WithBaseURL(srv.URL))
+
+	usd, eur, gbp := money.GetCurrency("USD"), money.GetCurrency("EUR"), money.GetCurrency("GBP")
+
+	var wg sync.WaitGroup
+	errs := make(chan error, callers)
+	for i := range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			// Every caller asks for a different subset of the same table.
+			var err error
+			if i%2 == 0 {
+				_, err = prov.Rates(t.Context(), usd, eur)
+			} else {
+				_, err = prov.Rates(t.Context(), eur, gbp, usd)
+			}
+			errs <- err
+		}()
+	}
+
+	<-started
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("err: %v", err)
 		}
+	}
+
+	if got := calls.Load(); got != 1 {
+		t.Fatalf("Expected 1 upstream call for %d concurrent callers got %d", callers, got)
+	}
+}
 
-		if ec[1] != rates[i].To.Code {
-			t.Fatalf("Expected %d To currency to match %q got %q", i, ec[0], rates[i].From.Code)
+func TestOpenExchangeRatesProviderCancelledCallerDoesNotAbortFetch(t *testing.T) {
+	var calls atomic.Int32
+	started := make(chan struct{})
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			close(started)
 		}
+		<-release
+		_, _ = w.Write([]byte(`{"base":"USD","rates":{"USD":1,"EUR":0.848818}}`))
+	}))
+	defer srv.Close()
+
+	prov := NewOpenExchangeRatesProvider(srv.Client(), "app-id", WithBaseURL(srv.URL))
+
+	usd, eur := money.GetCurrency("USD"), money.GetCurrency("EUR")
+
+	cancelledCtx, cancel := context.WithCancel(t.Context())
+	cancelledErr := make(chan error, 1)
+	go func() {
+		_, err := prov.Rates(cancelledCtx, usd, eur)
+		cancelledErr <- err
+	}()
+
+	<-started
+
+	waitingErr := make(chan error, 1)
+	go func() {
+		_, err := prov.Rates(t.Context(), eur, usd)
+		waitingErr <- err
+	}()
+
+	cancel()
+	if err := <-cancelledErr; !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected cancelled caller to get %v got %v", context.Canceled, err)
+	}
+
+	close(release)
+	if err := <-waitingErr; err != nil {
+		t.Fatalf("Expected waiting caller to get rates got: %v", err)
+	}
+
+	if got := calls.Load(); got != 1 {
+		t.Fatalf("Expected 1 upstream call got %d", got)
+	}
+}
+
+func TestOpenExchangeRatesProviderErrors(t *testing.T) {
+	srv := httptest.NewServer(oxrfake.New())
+	defer srv.Close()
+
+	tests := []struct {
+		name      string
+		appID     string
+		baseURL   string
+		want      error
+		status    int
+		transient bool
+	}{
+		{name: "missing_app_id", appID: "", want: ErrMissingAppID, status: http.StatusUnauthorized},
+		{name: "invalid_app_id", appID: "wrong", want: ErrInvalidAppID, status: http.StatusUnauthorized},
+		{name: "not_allowed", appID: oxrfake.AppIDNotAllowed, want: ErrNotAllowed, status: http.StatusForbidden},
+		{name: "quota_exceeded", appID: oxrfake.AppIDQuotaExceeded, want: ErrQuotaExceeded, status: http.StatusTooManyRequests},
+		{name: "unavailable", appID: oxrfake.AppIDUnavailable, want: ErrUnavailable, status: http.StatusServiceUnavailable, transient: true},
+		{name: "unreachable", appID: oxrfake.AppID, baseURL: "http://127.0.0.1:1", want: ErrUnavailable, transient: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			baseURL := srv.URL + oxrfake.BasePath
+			if tc.baseURL != "" {
+				baseURL = tc.baseURL
+			}
+
+			prov := NewOpenExchangeRatesProvider(srv.Client(), tc.appID, WithBaseURL(baseURL))
+
+			_, err := prov.Rates(t.Context(), money.GetCurrency("USD"), money.GetCurrency("EUR"))
+			if !errors.Is(err, tc.want) {
+				t.Fatalf("Expected %v got %v", tc.want, err)
+			}
+
+			if IsTransient(err) != tc.transient {
+				t.Fatalf("Expected transient to be %v for %v", tc.transient, err)
+			}
+
+			var upErr *UpstreamError
+			if tc.status != 0 && (!errors.As(err, &upErr) || upErr.StatusCode != tc.status) {
+				t.Fatalf("Expected upstream error with status %d got %v", tc.status, err)
+			}
+		})
+	}
+
+	prov := NewOpenExchangeRatesProvider(srv.Client(), oxrfake.AppID, WithBaseURL(srv.URL+oxrfake.BasePath))
+
+	_, err := prov.Rates(t.Context(), money.GetCurrency("USD"), money.GetCurrency("XPT"))
+	if !errors.Is(err, ErrUnsupportedCurrency) {
+		t.Fatalf("Expected %v got %v", ErrUnsupportedCurrency, err)
+	}
+}
+
+func TestOpenExchangeRatesProviderRatesAt(t *testing.T) {
+	srv := httptest.NewServer(oxrfake.New())
+	defer srv.Close()
+
+	prov := NewOpenExchangeRatesProvider(srv.Client(), oxrfake.AppID, WithBaseURL(srv.URL+oxrfake.BasePath))
+	prov.now = func() time.Time { return time.Date(2025, 7, 1, 12, 0, 0, 0, time.UTC) }
+
+	usd, eur := money.GetCurrency("USD"), money.GetCurrency("EUR")
+
+	rates, err := prov.RatesAt(t.Context(), time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC), usd, eur)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	rate, ok := rates.For(usd, eur)
+	if !ok || !rate.Rate.Equal(decimal.MustParse("0.924131")) {
+		t.Fatalf("Expected USD/EUR rate 0.924131 on 2025-03-10 got %v", rate)
+	}
+
+	if _, err := prov.RatesAt(t.Context(), time.Date(2025, 7, 2, 0, 0, 0, 0, time.UTC), usd, eur); !errors.Is(err, ErrFutureDate) {
+		t.Fatalf("Expected %v got %v", ErrFutureDate, err)
+	}
+
+	if _, err := prov.RatesAt(t.Context(), time.Date(1998, 12, 31, 0, 0, 0, 0, time.UTC), usd, eur); !errors.Is(err, ErrDateOutOfRange) {
+		t.Fatalf("Expected %v got %v", ErrDateOutOfRange, err)
+	}
+
+	// The fake has no fixture for that day, the same as openexchangerates.org for days it has no data for.
+	if _, err := prov.RatesAt(t.Context(), time.Date(2025, 3, 9, 0, 0, 0, 0, time.UTC), usd, eur); !errors.Is(err, ErrNoData) {
+		t.Fatalf("Expected %v got %v", ErrNoData, err)
 	}
 }
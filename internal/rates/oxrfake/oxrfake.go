@@ -0,0 +1,199 @@
+// Package oxrfake implements a fake openexchangerates.org API serving recorded fixture data,
+// so code depending on the Open Exchange Rates API can be run and tested offline.
+package oxrfake
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	// AppID is the app id accepted by the fake server.
+	AppID = "oxrfake"
+
+	// AppIDNotAllowed makes every request fail with the not_allowed error.
+	AppIDNotAllowed = "oxrfake-not-allowed"
+
+	// AppIDQuotaExceeded makes every request fail with the access_restricted error returned once the quota is used up.
+	AppIDQuotaExceeded = "oxrfake-quota-exceeded"
+
+	// AppIDUnavailable makes every request fail with 503 Service Unavailable.
+	AppIDUnavailable = "oxrfake-unavailable"
+
+	// BasePath is the path prefix the API is served under, the same as on openexchangerates.org.
+	BasePath = "/api"
+)
+
+//go:embed fixtures
+var fixtures embed.FS
+
+var historicalFile = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}\.json$`)
+
+// Server is a fake openexchangerates.org API.
+type Server struct {
+	fixtures fs.FS
+	mux      *http.ServeMux
+}
+
+// New returns a fake server serving the bundled fixtures.
+func New() *Server {
+	sub, err := fs.Sub(fixtures, "fixtures")
+	if err != nil {
+		panic(fmt.Sprintf("oxrfake: opening fixtures: %v", err))
+	}
+
+	return NewFromFS(sub)
+}
+
+// NewFromFS returns a fake server serving fixtures from fsys.
+// It expects latest.json, currencies.json, historical/YYYY-MM-DD.json and errors/<message>.json files.
+func NewFromFS(fsys fs.FS) *Server {
+	s := &Server{
+		fixtures: fsys,
+		mux:      http.NewServeMux(),
+	}
+
+	s.mux.HandleFunc("GET "+BasePath+"/latest.json", s.handleRates("latest.json"))
+	s.mux.HandleFunc("GET "+BasePath+"/currencies.json", s.handleCurrencies)
+	s.mux.HandleFunc("GET "+BasePath+"/historical/{file}", s.handleHistorical)
+	s.mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		s.writeError(w, "not_found")
+	})
+
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *Server) handleHistorical(w http.ResponseWriter, r *http.Request) {
+	file := r.PathValue("file")
+	if !historicalFile.MatchString(file) {
+		s.writeError(w, "not_found")
+		return
+	}
+
+	if _, err := time.Parse(time.DateOnly, strings.TrimSuffix(file, ".json")); err != nil {
+		s.writeError(w, "not_available")
+		return
+	}
+
+	s.handleRates("historical/"+file)(w, r)
+}
+
+func (s *Server) handleRates(fixture string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !s.authorize(w, r) {
+			return
+		}
+
+		if base := r.URL.Query().Get("base"); base != "" && base != "USD" {
+			s.writeError(w, "invalid_base")
+			return
+		}
+
+		buf, err := fs.ReadFile(s.fixtures, fixture)
+		if errors.Is(err, fs.ErrNotExist) {
+			s.writeError(w, "not_available")
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		var body map[string]any
+		if err := unmarshal(buf, &body); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if symbols := r.URL.Query().Get("symbols"); symbols != "" {
+			all, _ := body["rates"].(map[string]any)
+			filtered := make(map[string]any, len(all))
+			for _, code := range strings.Split(symbols, ",") {
+				code = strings.ToUpper(strings.TrimSpace(code))
+				if rate, ok := all[code]; ok {
+					filtered[code] = rate
+				}
+			}
+			body["rates"] = filtered
+		}
+
+		writeJSON(w, http.StatusOK, body)
+	}
+}
+
+func (s *Server) handleCurrencies(w http.ResponseWriter, r *http.Request) {
+	buf, err := fs.ReadFile(s.fixtures, "currencies.json")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	_, _ = w.Write(buf)
+}
+
+// authorize checks the app_id the same way openexchangerates.org does and writes the error response if it fails.
+func (s *Server) authorize(w http.ResponseWriter, r *http.Request) bool {
+	switch r.URL.Query().Get("app_id") {
+	case AppID:
+		return true
+	case "":
+		s.writeError(w, "missing_app_id")
+	case AppIDNotAllowed:
+		s.writeError(w, "not_allowed")
+	case AppIDQuotaExceeded:
+		s.writeError(w, "access_restricted")
+	case AppIDUnavailable:
+		http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+	default:
+		s.writeError(w, "invalid_app_id")
+	}
+
+	return false
+}
+
+// writeError writes the documented error body for message with its status code.
+func (s *Server) writeError(w http.ResponseWriter, message string) {
+	buf, err := fs.ReadFile(s.fixtures, "errors/"+message+".json")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	var body struct {
+		Status int `json:"status"`
+	}
+	if err := json.Unmarshal(buf, &body); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(body.Status)
+	_, _ = w.Write(buf)
+}
+
+// unmarshal decodes buf keeping numbers as json.Number so fixture rates are served verbatim.
+func unmarshal(buf []byte, v any) error {
+	dec := json.NewDecoder(bytes.NewReader(buf))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -2,6 +2,8 @@ package rates
 
 import (
 	"context"
+	"fmt"
+	"time"
 
 	"github.com/Rhymond/go-money"
 )
@@ -12,5 +14,42 @@ type Provider interface {
 	SupportedCurrencies(ctx context.Context) ([]*money.Currency, error)
 
 	// Rates returns current rates for a given set of currencies at least two is required.
-	Rates(ctx context.Context, c1, c2 *money.Currency, c ...*money.Currency) (ExchangeRates, error)
+	Rates(ctx context.Context, c1, c2 *money.Currency, c ...*money.Currency) (*Matrix, error)
+}
+
+// HistoricalProvider encapsulates providers that can also return rates as they were on a past day.
+type HistoricalProvider interface {
+	// Since returns the first day the provider has rates for.
+	Since() time.Time
+
+	// RatesAt returns rates as of a given day for a given set of currencies at least two is required.
+	RatesAt(ctx context.Context, date time.Time, c1, c2 *money.Currency, c ...*money.Currency) (*Matrix, error)
+}
+
+// QuoteProvider encapsulates providers deriving their rates from a table of quotes against a single base currency.
+type QuoteProvider interface {
+	// Quotes returns the quotes between every currency of the latest table and the base currency, fetched from
+	// upstream rather than served from cache.
+	Quotes(ctx context.Context) (*Matrix, error)
+}
+
+// Day returns the UTC day t falls on.
+func Day(t time.Time) time.Time {
+	y, m, d := t.UTC().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// CheckDate returns an error when p cannot have rates for date, because it is after the day of now or before p covers.
+func CheckDate(p HistoricalProvider, date, now time.Time) error {
+	date = Day(date)
+
+	if date.After(Day(now)) {
+		return fmt.Errorf("%s: %w", date.Format(time.DateOnly), ErrFutureDate)
+	}
+
+	if since := Day(p.Since()); date.Before(since) {
+		return fmt.Errorf("%s is before %s: %w", date.Format(time.DateOnly), since.Format(time.DateOnly), ErrDateOutOfRange)
+	}
+
+	return nil
 }
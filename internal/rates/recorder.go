@@ -0,0 +1,62 @@
+package rates
+
+import (
+	"context"
+	"time"
+
+	"github.com/govalues/decimal"
+)
+
+// Quotation tells how the rates of a Table are quoted against its base currency.
+type Quotation string
+
+const (
+	// QuoteIndirect rates are the amount of a currency worth one unit of the base currency, e.g. EUR 0.85 per USD.
+	QuoteIndirect Quotation = "indirect"
+
+	// QuoteDirect rates are the price of one unit of a currency in the base currency, e.g. PLN 4.25 per EUR.
+	QuoteDirect Quotation = "direct"
+)
+
+// Table is a full rates table of a provider as its rates were served from, with rates kept exactly as published
+// unless a guardrail served an earlier one in place of a rate.
+type Table struct {
+	Provider  string
+	FetchedAt time.Time
+	Base      string
+	Quotation Quotation
+	Rates     map[string]decimal.Decimal
+}
+
+// Recorder keeps the tables providers serve their rates from, so the rates served from them can be accounted for later.
+type Recorder interface {
+	Record(ctx context.Context, table Table) error
+}
+
+// quotesTable returns quotes between currencies and a single base currency as the table of provider fetched at
+// fetchedAt. Quotes of the base currency are indirect, quotes in it direct.
+func quotesTable(provider string, fetchedAt time.Time, quotes *Matrix) Table {
+	all := quotes.Rates()
+
+	base, quotation := all[0].From.Code, QuoteIndirect
+	if len(all) > 1 && all[1].From.Code != base {
+		base, quotation = all[0].To.Code, QuoteDirect
+	}
+
+	table := Table{
+		Provider:  provider,
+		FetchedAt: fetchedAt,
+		Base:      base,
+		Quotation: quotation,
+		Rates:     map[string]decimal.Decimal{base: decimal.One},
+	}
+	for _, q := range all {
+		if quotation == QuoteIndirect {
+			table.Rates[q.To.Code] = q.Rate
+		} else {
+			table.Rates[q.From.Code] = q.Rate
+		}
+	}
+
+	return table
+}
@@ -15,32 +15,32 @@ func NewStaticRatesProvider() StaticTestRatesProvider {
 
 func (s StaticTestRatesProvider) SupportedCurrencies(ctx context.Context) ([]*money.Currency, error) {
 	return []*money.Currency{
-		money.GetCurrency("USD"),
-		money.GetCurrency("GBP"),
-		money.GetCurrency("EUR"),
-		money.GetCurrency("BTC"),
+		GetCurrency("USD"),
+		GetCurrency("GBP"),
+		GetCurrency("EUR"),
+		GetCurrency("BTC"),
 	}, nil
 }
 
-func (s StaticTestRatesProvider) Rates(ctx context.Context, c1, c2 *money.Currency, c ...*money.Currency) (ExchangeRates, error) {
-	return []ExchangeRate{
-		{money.GetCurrency("USD"), money.GetCurrency("BTC"), decimal.MustParse("0.000009104837")},
-		{money.GetCurrency("EUR"), money.GetCurrency("BTC"), decimal.MustParse("0.0000106959819745101")},
-		{money.GetCurrency("USD"), money.GetCurrency("BTC"), decimal.MustParse("0.000009104837")},
-		{money.GetCurrency("GBP"), money.GetCurrency("BTC"), decimal.MustParse("0.0000124249434010156")},
-		{money.GetCurrency("USD"), money.GetCurrency("EUR"), decimal.MustParse("0.851239")},
-		{money.GetCurrency("BTC"), money.GetCurrency("EUR"), decimal.MustParse("93493.05209966965911")},
-		{money.GetCurrency("USD"), money.GetCurrency("EUR"), decimal.MustParse("0.851239")},
-		{money.GetCurrency("GBP"), money.GetCurrency("EUR"), decimal.MustParse("1.161645880726595859")},
-		{money.GetCurrency("USD"), money.GetCurrency("GBP"), decimal.MustParse("0.732787")},
-		{money.GetCurrency("BTC"), money.GetCurrency("GBP"), decimal.MustParse("80483.26400571476458")},
-		{money.GetCurrency("USD"), money.GetCurrency("GBP"), decimal.MustParse("0.732787")},
-		{money.GetCurrency("EUR"), money.GetCurrency("GBP"), decimal.MustParse("0.860847541054862383")},
-		{money.GetCurrency("EUR"), money.GetCurrency("USD"), decimal.MustParse("1.174758205392375114")},
-		{money.GetCurrency("GBP"), money.GetCurrency("USD"), decimal.MustParse("1.364653030143820783")},
-		{money.GetCurrency("GBP"), money.GetCurrency("USD"), decimal.MustParse("1.364653030143820783")},
-		{money.GetCurrency("EUR"), money.GetCurrency("USD"), decimal.MustParse("1.174758205392375114")},
-		{money.GetCurrency("BTC"), money.GetCurrency("USD"), decimal.MustParse("109831.7301012637568")},
-		{money.GetCurrency("BTC"), money.GetCurrency("USD"), decimal.MustParse("109831.7301012637568")},
-	}, nil
+// Rates returns the fixed rates between currencies.
+func (s StaticTestRatesProvider) Rates(ctx context.Context, c1, c2 *money.Currency, c ...*money.Currency) (*Matrix, error) {
+	all, err := NewMatrix(
+		ExchangeRate{From: GetCurrency("BTC"), To: GetCurrency("EUR"), Rate: decimal.MustParse("93493.05209966965911")},
+		ExchangeRate{From: GetCurrency("BTC"), To: GetCurrency("GBP"), Rate: decimal.MustParse("80483.26400571476458")},
+		ExchangeRate{From: GetCurrency("BTC"), To: GetCurrency("USD"), Rate: decimal.MustParse("109831.7301012637568")},
+		ExchangeRate{From: GetCurrency("EUR"), To: GetCurrency("BTC"), Rate: decimal.MustParse("0.0000106959819745101")},
+		ExchangeRate{From: GetCurrency("EUR"), To: GetCurrency("GBP"), Rate: decimal.MustParse("0.860847541054862383")},
+		ExchangeRate{From: GetCurrency("EUR"), To: GetCurrency("USD"), Rate: decimal.MustParse("1.174758205392375114")},
+		ExchangeRate{From: GetCurrency("GBP"), To: GetCurrency("BTC"), Rate: decimal.MustParse("0.0000124249434010156")},
+		ExchangeRate{From: GetCurrency("GBP"), To: GetCurrency("EUR"), Rate: decimal.MustParse("1.161645880726595859")},
+		ExchangeRate{From: GetCurrency("GBP"), To: GetCurrency("USD"), Rate: decimal.MustParse("1.364653030143820783")},
+		ExchangeRate{From: GetCurrency("USD"), To: GetCurrency("BTC"), Rate: decimal.MustParse("0.000009104837")},
+		ExchangeRate{From: GetCurrency("USD"), To: GetCurrency("EUR"), Rate: decimal.MustParse("0.851239")},
+		ExchangeRate{From: GetCurrency("USD"), To: GetCurrency("GBP"), Rate: decimal.MustParse("0.732787")},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return all.Subset(append([]*money.Currency{c1, c2}, c...)...), nil
 }
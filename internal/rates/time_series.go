@@ -0,0 +1,177 @@
+package rates
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Rhymond/go-money"
+	"golang.org/x/sync/errgroup"
+)
+
+// timeSeriesConcurrency bounds how many days TimeSeries requests from the provider at once.
+const timeSeriesConcurrency = 8
+
+// timeSeriesCacheSize bounds how many days TimeSeries remembers, counting each set of currencies separately.
+const timeSeriesCacheSize = 10_000
+
+// Series holds the rates published on each day of a date range.
+type Series struct {
+	Start time.Time
+	End   time.Time
+
+	// Days holds the rates of every day in the range the provider published them for, ordered by date.
+	Days []SeriesDay
+
+	// Gaps lists the days in the range the provider published no rates for, e.g. weekends and holidays, ordered by date.
+	Gaps []time.Time
+}
+
+// SeriesDay holds the rates published on a single day.
+type SeriesDay struct {
+	Date  time.Time
+	Rates *Matrix
+}
+
+// TimeSeries returns rates of a historical provider over date ranges.
+// Past days never change, so it remembers them and overlapping ranges are fetched from the provider once.
+type TimeSeries struct {
+	provider HistoricalProvider
+	maxDays  int
+	now      func() time.Time
+
+	mu    sync.Mutex
+	cache map[seriesKey]seriesEntry
+	// order holds the cached keys from the oldest inserted, to evict them first.
+	order []seriesKey
+}
+
+type seriesKey struct {
+	date  time.Time
+	codes string
+}
+
+type seriesEntry struct {
+	rates *Matrix
+	gap   bool
+}
+
+// NewTimeSeries returns a TimeSeries reading rates from provider over ranges of at most maxDays days.
+func NewTimeSeries(provider HistoricalProvider, maxDays int) *TimeSeries {
+	return &TimeSeries{
+		provider: provider,
+		maxDays:  maxDays,
+		now:      time.Now,
+		cache:    make(map[seriesKey]seriesEntry),
+	}
+}
+
+// Series returns the rates published on every day from start to end, inclusive.
+// Days the provider published no rates for are reported in Gaps, any other failure fails the whole range.
+func (s *TimeSeries) Series(ctx context.Context, start, end time.Time, c1, c2 *money.Currency, c ...*money.Currency) (*Series, error) {
+	start, end = Day(start), Day(end)
+
+	if end.Before(start) {
+		return nil, fmt.Errorf("%s to %s: %w", start.Format(time.DateOnly), end.Format(time.DateOnly), ErrInvalidRange)
+	}
+
+	days := int(end.Sub(start)/(24*time.Hour)) + 1
+	if days > s.maxDays {
+		return nil, fmt.Errorf("%d days, at most %d allowed: %w", days, s.maxDays, ErrRangeTooLong)
+	}
+
+	now := s.now()
+	if err := CheckDate(s.provider, start, now); err != nil {
+		return nil, err
+	}
+	if err := CheckDate(s.provider, end, now); err != nil {
+		return nil, err
+	}
+
+	currencies := []*money.Currency{c1, c2}
+	currencies = append(currencies, c...)
+
+	codes := slices.Sorted(slices.Values(currencyCodes(currencies)))
+	codes = slices.Compact(codes)
+
+	entries := make([]seriesEntry, days)
+
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(timeSeriesConcurrency)
+
+	for i := range entries {
+		date := start.AddDate(0, 0, i)
+		key := seriesKey{date: date, codes: strings.Join(codes, ",")}
+
+		if entry, ok := s.cached(key); ok {
+			entries[i] = entry
+			continue
+		}
+
+		g.Go(func() error {
+			rates, err := s.provider.RatesAt(ctx, date, c1, c2, c...)
+			switch {
+			// A failover chain may report no data from one provider while another one was unreachable.
+			case errors.Is(err, ErrNoData) && !IsTransient(err):
+				entries[i] = seriesEntry{gap: true}
+			case err != nil:
+				return fmt.Errorf("getting rates on %s: %w", date.Format(time.DateOnly), err)
+			default:
+				entries[i] = seriesEntry{rates: rates}
+			}
+
+			// Rates of the current day may still be published or revised.
+			if date.Before(Day(now)) {
+				s.store(key, entries[i])
+			}
+
+			return nil
+		})
+	}
+
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	series := &Series{Start: start, End: end}
+	for i, entry := range entries {
+		date := start.AddDate(0, 0, i)
+		if entry.gap {
+			series.Gaps = append(series.Gaps, date)
+			continue
+		}
+
+		series.Days = append(series.Days, SeriesDay{Date: date, Rates: entry.rates})
+	}
+
+	return series, nil
+}
+
+func (s *TimeSeries) cached(key seriesKey) (seriesEntry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.cache[key]
+	return entry, ok
+}
+
+func (s *TimeSeries) store(key seriesKey, entry seriesEntry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.cache[key]; ok {
+		return
+	}
+
+	if len(s.order) >= timeSeriesCacheSize {
+		delete(s.cache, s.order[0])
+		s.order = s.order[1:]
+	}
+
+	s.cache[key] = entry
+	s.order = append(s.order, key)
+}
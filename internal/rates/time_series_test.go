@@ -0,0 +1,87 @@
+package rates
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Rhymond/go-money"
+)
+
+type countingHistoricalProvider struct {
+	*ECBProvider
+
+	calls atomic.Int32
+}
+
+func (p *countingHistoricalProvider) RatesAt(ctx context.Context, date time.Time, c1, c2 *money.Currency, c ...*money.Currency) (*Matrix, error) {
+	p.calls.Add(1)
+	return p.ECBProvider.RatesAt(ctx, date, c1, c2, c...)
+}
+
+func TestTimeSeries(t *testing.T) {
+	srv := httptest.NewServer(http.FileServer(http.Dir("testdata/ecb")))
+	defer srv.Close()
+
+	eur, usd := money.GetCurrency("EUR"), money.GetCurrency("USD")
+	date := func(day int) time.Time {
+		return time.Date(2025, 6, day, 0, 0, 0, 0, time.UTC)
+	}
+
+	prov := &countingHistoricalProvider{ECBProvider: NewECBProvider(srv.Client(), srv.URL+"/eurofxref-hist-90d.xml")}
+	ts := NewTimeSeries(prov, 7)
+	ts.now = func() time.Time { return time.Date(2025, 7, 2, 12, 0, 0, 0, time.UTC) }
+
+	series, err := ts.Series(t.Context(), date(25), date(30), eur, usd)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	if len(series.Days) != 4 {
+		t.Fatalf("Expected 4 days with rates got %d", len(series.Days))
+	}
+	for i, want := range []time.Time{date(25), date(26), date(27), date(30)} {
+		if !series.Days[i].Date.Equal(want) {
+			t.Fatalf("Expected day %d to be %v got %v", i, want, series.Days[i].Date)
+		}
+	}
+
+	if len(series.Gaps) != 2 || !series.Gaps[0].Equal(date(28)) || !series.Gaps[1].Equal(date(29)) {
+		t.Fatalf("Expected weekend gaps got %v", series.Gaps)
+	}
+
+	if calls := prov.calls.Load(); calls != 6 {
+		t.Fatalf("Expected 6 provider calls got %d", calls)
+	}
+
+	// Overlapping range only fetches the day not seen before, the order of currencies doesn't matter.
+	if _, err := ts.Series(t.Context(), date(26), time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC), usd, eur); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if calls := prov.calls.Load(); calls != 7 {
+		t.Fatalf("Expected 7 provider calls got %d", calls)
+	}
+
+	tests := []struct {
+		name       string
+		start, end time.Time
+		want       error
+	}{
+		{name: "ends_before_start", start: date(27), end: date(26), want: ErrInvalidRange},
+		{name: "too_long", start: date(20), end: date(30), want: ErrRangeTooLong},
+		{name: "in_future", start: time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC), end: time.Date(2025, 7, 3, 0, 0, 0, 0, time.UTC), want: ErrFutureDate},
+		{name: "before_document", start: date(1), end: date(2), want: ErrDateOutOfRange},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := ts.Series(t.Context(), tc.start, tc.end, eur, usd); !errors.Is(err, tc.want) {
+				t.Fatalf("Expected %v got %v", tc.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package snapshots
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/IAmRadek/gorate/internal/jsonlines"
+	"github.com/IAmRadek/gorate/internal/rates"
+)
+
+// FileStore appends snapshots to a JSON lines file, one snapshot per line.
+// Only an index is kept in memory, snapshots are read back from the file when requested.
+type FileStore struct {
+	mu    sync.RWMutex
+	file  *jsonlines.File[Snapshot]
+	index index
+}
+
+// OpenFileStore opens the snapshots file at path, creating it when it doesn't exist.
+// A partially written last line, left by a crash during a write, is truncated.
+func OpenFileStore(path string) (*FileStore, error) {
+	f := &FileStore{index: newIndex()}
+
+	file, err := jsonlines.Open(path, func(s *Snapshot) string { return s.ID }, f.index.add)
+	if err != nil {
+		return nil, fmt.Errorf("opening snapshots file: %w", err)
+	}
+	f.file = file
+
+	return f, nil
+}
+
+func (f *FileStore) Save(ctx context.Context, table rates.Table) (*Snapshot, error) {
+	s := newSnapshot(table)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.file.Append(s); err != nil {
+		return nil, fmt.Errorf("snapshot %q: %w", s.ID, err)
+	}
+
+	f.index.add(s)
+
+	return s, nil
+}
+
+func (f *FileStore) Get(ctx context.Context, id string) (*Snapshot, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	return f.read(id)
+}
+
+func (f *FileStore) At(ctx context.Context, provider string, t time.Time) (*Snapshot, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	id, ok := f.index.at(provider, t)
+	if !ok {
+		return nil, fmt.Errorf("%s at %s: %w", provider, t.Format(time.RFC3339), ErrNotFound)
+	}
+
+	return f.read(id)
+}
+
+// Close closes the snapshots file.
+func (f *FileStore) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.file.Close()
+}
+
+func (f *FileStore) read(id string) (*Snapshot, error) {
+	s, ok, err := f.file.Read(id)
+	if err != nil {
+		return nil, fmt.Errorf("snapshot %q: %w", id, err)
+	}
+	if !ok {
+		return nil, fmt.Errorf("%q: %w", id, ErrNotFound)
+	}
+
+	return s, nil
+}
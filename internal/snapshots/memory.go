@@ -0,0 +1,60 @@
+package snapshots
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/IAmRadek/gorate/internal/rates"
+)
+
+// MemoryStore keeps snapshots in memory, they are lost when the process exits.
+type MemoryStore struct {
+	mu        sync.RWMutex
+	snapshots map[string]*Snapshot
+	index     index
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		snapshots: make(map[string]*Snapshot),
+		index:     newIndex(),
+	}
+}
+
+func (m *MemoryStore) Save(ctx context.Context, table rates.Table) (*Snapshot, error) {
+	s := newSnapshot(table)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.snapshots[s.ID] = s
+	m.index.add(s)
+
+	return s, nil
+}
+
+func (m *MemoryStore) Get(ctx context.Context, id string) (*Snapshot, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	s, ok := m.snapshots[id]
+	if !ok {
+		return nil, fmt.Errorf("%q: %w", id, ErrNotFound)
+	}
+
+	return s, nil
+}
+
+func (m *MemoryStore) At(ctx context.Context, provider string, t time.Time) (*Snapshot, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	id, ok := m.index.at(provider, t)
+	if !ok {
+		return nil, fmt.Errorf("%s at %s: %w", provider, t.Format(time.RFC3339), ErrNotFound)
+	}
+
+	return m.snapshots[id], nil
+}
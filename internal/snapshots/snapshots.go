@@ -0,0 +1,106 @@
+// Package snapshots persists the rate tables providers serve their rates from,
+// so the rates served to customers can be accounted for afterwards.
+package snapshots
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"maps"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/IAmRadek/gorate/internal/rates"
+	"github.com/govalues/decimal"
+)
+
+// ErrNotFound is returned when no snapshot matches a lookup.
+var ErrNotFound = errors.New("snapshot not found")
+
+// Snapshot is a rates table recorded under a unique ID.
+type Snapshot struct {
+	ID        string                     `json:"id"`
+	Provider  string                     `json:"provider"`
+	FetchedAt time.Time                  `json:"fetched_at"`
+	Base      string                     `json:"base"`
+	Quotation rates.Quotation            `json:"quotation"`
+	Rates     map[string]decimal.Decimal `json:"rates"`
+}
+
+// Store keeps snapshots.
+type Store interface {
+	// Save records table as a new snapshot.
+	Save(ctx context.Context, table rates.Table) (*Snapshot, error)
+
+	// Get returns the snapshot with the given ID.
+	Get(ctx context.Context, id string) (*Snapshot, error)
+
+	// At returns the latest snapshot of provider fetched at or before t, which is the table its rates were served from at
+	// t: as the provider fetched it, with any rate the guardrails didn't accept replaced by the one served instead.
+	At(ctx context.Context, provider string, t time.Time) (*Snapshot, error)
+}
+
+// Recorder returns a rates.Recorder saving tables to store.
+func Recorder(store Store) rates.Recorder {
+	return recorder{store: store}
+}
+
+type recorder struct {
+	store Store
+}
+
+func (r recorder) Record(ctx context.Context, table rates.Table) error {
+	_, err := r.store.Save(ctx, table)
+	return err
+}
+
+func newSnapshot(table rates.Table) *Snapshot {
+	return &Snapshot{
+		ID:        rand.Text(),
+		Provider:  table.Provider,
+		FetchedAt: table.FetchedAt.UTC(),
+		Base:      table.Base,
+		Quotation: table.Quotation,
+		Rates:     maps.Clone(table.Rates),
+	}
+}
+
+// index finds snapshots by provider and time, it is shared by the backends.
+type index struct {
+	// byProvider holds the IDs and fetch times of every provider's snapshots, ordered by fetch time.
+	byProvider map[string][]indexEntry
+}
+
+type indexEntry struct {
+	id        string
+	fetchedAt time.Time
+}
+
+func newIndex() index {
+	return index{byProvider: make(map[string][]indexEntry)}
+}
+
+func (x index) add(s *Snapshot) {
+	entries := x.byProvider[s.Provider]
+
+	i := sort.Search(len(entries), func(i int) bool {
+		return entries[i].fetchedAt.After(s.FetchedAt)
+	})
+
+	x.byProvider[s.Provider] = slices.Insert(entries, i, indexEntry{id: s.ID, fetchedAt: s.FetchedAt})
+}
+
+// at returns the ID of the latest snapshot of provider fetched at or before t.
+func (x index) at(provider string, t time.Time) (string, bool) {
+	entries := x.byProvider[provider]
+
+	i := sort.Search(len(entries), func(i int) bool {
+		return entries[i].fetchedAt.After(t)
+	})
+	if i == 0 {
+		return "", false
+	}
+
+	return entries[i-1].id, true
+}
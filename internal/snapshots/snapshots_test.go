@@ -0,0 +1,138 @@
+package snapshots
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/IAmRadek/gorate/internal/rates"
+	"github.com/govalues/decimal"
+)
+
+func table(provider string, fetchedAt time.Time, eur string) rates.Table {
+	return rates.Table{
+		Provider:  provider,
+		FetchedAt: fetchedAt,
+		Base:      "USD",
+		Quotation: rates.QuoteIndirect,
+		Rates: map[string]decimal.Decimal{
+			"USD": decimal.One,
+			"EUR": decimal.MustParse(eur),
+		},
+	}
+}
+
+func testStore(t *testing.T, store Store) {
+	t.Helper()
+
+	t0 := time.Date(2025, 7, 1, 12, 0, 0, 0, time.UTC)
+
+	first, err := store.Save(t.Context(), table("oxr", t0, "0.848818"))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	// Saved out of order, e.g. a historical table fetched late.
+	second, err := store.Save(t.Context(), table("oxr", t0.Add(2*time.Hour), "0.848900"))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	middle, err := store.Save(t.Context(), table("oxr", t0.Add(time.Hour), "0.848850"))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if _, err := store.Save(t.Context(), table("ecb", t0.Add(time.Hour), "0.848")); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	got, err := store.Get(t.Context(), first.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if got.Provider != "oxr" || !got.FetchedAt.Equal(t0) || got.Base != "USD" || got.Quotation != rates.QuoteIndirect {
+		t.Fatalf("Unexpected snapshot %+v", got)
+	}
+	// Rates are kept exactly, trailing zeros included.
+	if eur := got.Rates["EUR"]; eur.String() != "0.848818" {
+		t.Fatalf("Expected EUR rate 0.848818 got %s", eur)
+	}
+
+	tests := []struct {
+		at   time.Time
+		want string
+	}{
+		{at: t0, want: first.ID},
+		{at: t0.Add(30 * time.Minute), want: first.ID},
+		{at: t0.Add(time.Hour), want: middle.ID},
+		{at: t0.Add(24 * time.Hour), want: second.ID},
+	}
+
+	for _, tc := range tests {
+		got, err := store.At(t.Context(), "oxr", tc.at)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if got.ID != tc.want {
+			t.Fatalf("At %v: expected snapshot %s got %s", tc.at, tc.want, got.ID)
+		}
+	}
+
+	if _, err := store.At(t.Context(), "oxr", t0.Add(-time.Second)); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Expected %v got %v", ErrNotFound, err)
+	}
+	if _, err := store.Get(t.Context(), "missing"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Expected %v got %v", ErrNotFound, err)
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	testStore(t, NewMemoryStore())
+}
+
+func TestFileStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snapshots.jsonl")
+
+	store, err := OpenFileStore(path)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	testStore(t, store)
+
+	saved, err := store.Save(t.Context(), table("oxr", time.Date(2025, 7, 2, 0, 0, 0, 0, time.UTC), "0.85"))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Simulate a crash in the middle of writing a snapshot.
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	_, _ = f.WriteString(`{"id":"partial","provider":"oxr"`)
+	_ = f.Close()
+
+	reopened, err := OpenFileStore(path)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer reopened.Close()
+
+	got, err := reopened.At(t.Context(), "oxr", time.Date(2025, 7, 3, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if got.ID != saved.ID {
+		t.Fatalf("Expected snapshot %s got %s", saved.ID, got.ID)
+	}
+
+	if _, err := reopened.Save(t.Context(), table("oxr", time.Date(2025, 7, 3, 0, 0, 0, 0, time.UTC), "0.86")); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if _, err := reopened.Get(t.Context(), saved.ID); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+}